@@ -1,14 +1,26 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/auth"
 	"github.com/budhilaw/gotapo-api/internal/config"
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/budhilaw/gotapo-api/internal/metrics"
 	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
 	"github.com/budhilaw/gotapo-api/internal/router"
+	"github.com/budhilaw/gotapo-api/internal/vault"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -18,52 +30,182 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Structured logging at LOG_LEVEL in LOG_FORMAT
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Cancelled on SIGINT/SIGTERM to abort in-flight camera calls
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		AppName:      "Tapo Camera API",
-		ErrorHandler: customErrorHandler,
+		AppName:               "Tapo Camera API",
+		ErrorHandler:          response.ErrorHandler,
+		DisableStartupMessage: true,
 	})
 
 	// Middleware
-	app.Use(recover.New())
+	app.Use(middleware.RequestContext(ctx))
+	app.Use(middleware.RequestID())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: middleware.LogPanic,
+	}))
 	app.Use(cors.New())
 	app.Use(middleware.Logger())
 
+	clientOpts := []tapo.Option{
+		tapo.WithPort(cfg.CameraPort),
+		tapo.WithTimeout(cfg.CameraTimeout),
+		tapo.WithKeepAlive(cfg.CameraKeepAlive),
+		tapo.WithMaxIdleConns(cfg.CameraMaxIdleConns),
+		tapo.WithIdleConnTimeout(cfg.CameraIdleConnTimeout),
+		tapo.WithTLSSessionCache(cfg.CameraTLSSessionCache),
+		tapo.WithLogger(logger),
+	}
+
+	// Trust-on-first-use certificate pinning
+	var pins tapo.PinStore
+	if cfg.CameraPinFile != "" {
+		store, err := tapo.NewFilePinStore(cfg.CameraPinFile)
+		if err != nil {
+			fatal("Failed to open pin file", err)
+		}
+		pins = store
+		clientOpts = append(clientOpts, tapo.WithPinStore(store))
+	}
+
+	// Prometheus metrics of requests and camera traffic
+	var apiMetrics *metrics.Metrics
+	if cfg.Metrics {
+		apiMetrics = metrics.New()
+		clientOpts = append(clientOpts, tapo.WithObserver(apiMetrics))
+	}
+
+	// Camera session pool shared by all handlers
+	pool := tapo.NewPool(cfg.SessionIdleTimeout, clientOpts...)
+	defer pool.Close()
+	if apiMetrics != nil {
+		apiMetrics.ObservePool(pool)
+	}
+
+	// Outbound proxies for cameras on remote sites
+	proxies := map[string]*url.URL{}
+	rawProxies := []string{cfg.CameraProxy}
+	for _, raw := range cfg.CameraProxies {
+		rawProxies = append(rawProxies, raw)
+	}
+	for _, raw := range rawProxies {
+		if raw == "" || raw == config.DirectProxy {
+			continue
+		}
+		proxyURL, err := tapo.ParseProxyURL(raw)
+		if err != nil {
+			fatal("Invalid camera proxy", err)
+		}
+		proxies[raw] = proxyURL
+	}
+	// Vault encrypting the camera passwords in the registry
+	var registryOpts []registry.Option
+	credentialVault, err := vault.Load(cfg.VaultKey, cfg.VaultKeyFile)
+	if err != nil {
+		fatal("Failed to load vault key", err)
+	}
+	if credentialVault != nil {
+		registryOpts = append(registryOpts, registry.WithVault(credentialVault))
+	} else {
+		slog.Warn("VAULT_KEY not set: camera passwords cannot be stored in the registry")
+	}
+
+	// Registered cameras addressable by ID
+	cameras, err := registry.Open(cfg.CameraRegistryFile, registryOpts...)
+	if err != nil {
+		fatal("Failed to open camera registry", err)
+	}
+
+	// Sessions of registered cameras use their scheme and base path, and
+	// every camera its outbound proxy
+	pool.SetHostOptions(func(host string) []tapo.Option {
+		var opts []tapo.Option
+		if cam, ok := cameras.Resolve(host); ok {
+			opts = cam.EndpointOptions()
+		}
+		if proxyURL, ok := proxies[cfg.ProxyFor(host)]; ok {
+			opts = append(opts, tapo.WithProxy(proxyURL))
+		}
+		return opts
+	})
+
+	// API keys and bearer tokens
+	var authService *auth.Service
+	if cfg.APIAuth {
+		keys, err := auth.OpenKeyStore(cfg.APIKeysFile)
+		if err != nil {
+			fatal("Failed to open API key file", err)
+		}
+
+		secret := []byte(cfg.JWTSecret)
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				fatal("Failed to generate JWT secret", err)
+			}
+			slog.Warn("JWT_SECRET not set: bearer tokens are invalidated on restart")
+		}
+
+		authService = auth.NewService(keys, secret, cfg.TokenTTL, cfg.APIAdminKey)
+	} else {
+		slog.Warn("API_AUTH disabled: anyone reaching the server can use stored camera credentials; admin and registry changes are closed")
+	}
+
+	// Append-only trail of the requests changing cameras or server state
+	trail, err := audit.Open(cfg.AuditFile, audit.WithRetention(cfg.AuditRetention))
+	if err != nil {
+		fatal("Failed to open audit file", err)
+	}
+	defer trail.Close()
+
 	// Setup routes
-	router.Setup(app)
+	router.Setup(app, router.Dependencies{
+		Pool:            pool,
+		Pins:            pins,
+		Cameras:         cameras,
+		Auth:            authService,
+		Audit:           trail,
+		Metrics:         apiMetrics,
+		DefaultUsername: cfg.DefaultUsername,
+		DefaultPassword: cfg.DefaultPassword,
+		TraceCameras:    cfg.CameraTrace,
+	})
 
 	// Graceful shutdown
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+		<-ctx.Done()
 
-		log.Println("Shutting down server...")
+		slog.Info("Shutting down server")
 		if err := app.Shutdown(); err != nil {
-			log.Printf("Server shutdown error: %v", err)
+			slog.Error("Server shutdown failed", "error", err)
 		}
 	}()
 
 	// Start server
-	log.Printf("🚀 Tapo Camera API starting on %s", cfg.GetServerAddress())
-	log.Printf("📖 API Documentation: http://%s/api", cfg.GetServerAddress())
+	slog.Info("Tapo Camera API starting",
+		"address", cfg.GetServerAddress(),
+		"docs", "http://"+cfg.GetServerAddress()+"/api",
+		"log_level", cfg.LogLevel)
 
 	if err := app.Listen(cfg.GetServerAddress()); err != nil {
-		log.Fatalf("Server error: %v", err)
+		fatal("Server error", err)
 	}
 }
 
-func customErrorHandler(c *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
-	message := "Internal Server Error"
-
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
-	}
-
-	return c.Status(code).JSON(fiber.Map{
-		"error":   true,
-		"message": message,
-	})
+// fatal logs msg with err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
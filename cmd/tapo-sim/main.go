@@ -0,0 +1,78 @@
+// Command tapo-sim serves an emulated Tapo camera over HTTPS so the API
+// server and other clients can be exercised without hardware.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+)
+
+func main() {
+	addr := flag.String("addr", ":8443", "address to listen on")
+	username := flag.String("username", emulator.DefaultUsername, "accepted username")
+	password := flag.String("password", emulator.DefaultPassword, "accepted password")
+	legacy := flag.Bool("legacy", false, "use the legacy MD5 login instead of encrypt_type 3")
+	delay := flag.Duration("delay", 0, "delay before answering each request")
+	flag.Parse()
+
+	opts := []emulator.Option{
+		emulator.WithCredentials(*username, *password),
+		emulator.WithDelay(*delay),
+	}
+	if *legacy {
+		opts = append(opts, emulator.WithLegacyAuth())
+	}
+
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		log.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           emulator.New(opts...),
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("📷 Emulated Tapo camera listening on https://%s (user %q)", *addr, *username)
+
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// selfSignedCertificate creates a certificate like the one cameras ship with
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "tapo-sim"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,570 @@
+// Package audit keeps an append-only trail of the requests that change
+// cameras or server state: who sent them, to which camera, with what body,
+// and how the camera responded.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of an audited request
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeFailed  = "failed"
+)
+
+// Redacted replaces the value of secret fields in recorded bodies
+const Redacted = "[REDACTED]"
+
+// MaxBodySize is the largest request or response body recorded verbatim;
+// larger bodies are replaced by a note of their size
+const MaxBodySize = 16 << 10
+
+// MemoryEntries is the number of latest entries kept by a trail without a
+// file
+const MemoryEntries = 10000
+
+// pruneInterval is how often expired entries are dropped while recording
+const pruneInterval = time.Hour
+
+// secretFields are the body fields whose values are never recorded
+var secretFields = map[string]bool{
+	"password":   true,
+	"secret":     true,
+	"token":      true,
+	"api_key":    true,
+	"jwt_secret": true,
+}
+
+// Actor identifies the client that sent a request
+type Actor struct {
+	KeyID  string `json:"key_id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Method string `json:"method,omitempty"`
+	IP     string `json:"ip,omitempty"`
+}
+
+// Entry is one audited request
+type Entry struct {
+	// Seq numbers the entries of a trail in the order they were recorded
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+
+	Actor Actor `json:"actor"`
+
+	// Camera is the registry ID of the camera, Address where it was reached
+	Camera  string `json:"camera,omitempty"`
+	Address string `json:"address,omitempty"`
+
+	// Action names the operation, e.g. SetPrivacy or DeletePreset
+	Action string `json:"action"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// Operations lists the camera methods run by a batch
+	Operations []string `json:"operations,omitempty"`
+
+	// RequestID is the X-Request-ID of the request and its response
+	RequestID string `json:"request_id,omitempty"`
+
+	Request json.RawMessage `json:"request,omitempty"`
+
+	Status   int             `json:"status"`
+	Outcome  string          `json:"outcome"`
+	Error    string          `json:"error,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// OutcomeFor returns the outcome of a request answered with status
+func OutcomeFor(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return OutcomeDenied
+	case status >= 400:
+		return OutcomeFailed
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// Body returns data as recorded in an entry: JSON with secret fields
+// redacted, other content as a JSON string, and nothing for an empty body
+func Body(data []byte) json.RawMessage {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) > MaxBodySize {
+		note, _ := json.Marshal(map[string]int{"truncated_bytes": len(data)})
+		return note
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		s, _ := json.Marshal(string(data))
+		return s
+	}
+
+	out, err := json.Marshal(redact(v))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// redact replaces the values of secret fields in v
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if secretFields[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = redact(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return v
+}
+
+// hasAction reports whether action, compared case-insensitively, is the
+// action of e or one of its operations
+func (e Entry) hasAction(action string) bool {
+	if strings.EqualFold(action, e.Action) {
+		return true
+	}
+	return slices.ContainsFunc(e.Operations, func(op string) bool {
+		return strings.EqualFold(action, op)
+	})
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	// Camera matches the registry ID or the address of the camera
+	Camera string
+
+	// Actor matches the API key ID or name
+	Actor string
+
+	// Action matches the action or one of the operations of a batch
+	Action string
+
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+
+	// Limit caps the number of entries returned; 0 returns all
+	Limit int
+}
+
+// Match reports whether e is selected by f
+func (f Filter) Match(e Entry) bool {
+	if f.Camera != "" && f.Camera != e.Camera && f.Camera != e.Address {
+		return false
+	}
+	if f.Actor != "" && f.Actor != e.Actor.KeyID && f.Actor != e.Actor.Name {
+		return false
+	}
+	if f.Action != "" && !e.hasAction(f.Action) {
+		return false
+	}
+	if f.Outcome != "" && f.Outcome != e.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Option configures a Log
+type Option func(*Log)
+
+// WithRetention drops entries older than d. Zero keeps entries forever.
+func WithRetention(d time.Duration) Option {
+	return func(l *Log) {
+		l.retention = d
+	}
+}
+
+// Log is an audit trail persisted as a JSON Lines file. Entries are only
+// ever appended; the file is rewritten only to drop entries past the
+// retention period. Queries read the file, so memory use does not grow
+// with the trail.
+type Log struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	retention time.Duration
+	seq       uint64
+	lastPrune time.Time
+	now       func() time.Time
+
+	// entries holds the latest MemoryEntries of a trail without a file
+	entries []Entry
+
+	// oldest is the time of the oldest entry in the file
+	oldest time.Time
+}
+
+// Open loads the trail at path, creating it on first write, and drops the
+// entries past the retention period. An empty path keeps the latest
+// MemoryEntries entries in memory only.
+func Open(path string, opts ...Option) (*Log, error) {
+	l := &Log{
+		path: path,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if path != "" {
+		if err := l.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.prune(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// load checks the entries of the trail file and reads the last sequence
+// number. A torn last line, left by a crash mid-write, is cut so the next
+// entry starts on a line of its own.
+func (l *Log) load() error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit file: %w", err)
+	}
+	defer file.Close()
+
+	var size int64
+	r := bufio.NewReader(file)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Entries are written with their newline, so an unterminated
+			// last line is torn
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit file: %w", err)
+		}
+		size += int64(len(line))
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("failed to parse audit file line %d: %w", n, err)
+		}
+		l.seq = max(l.seq, e.Seq)
+		if l.oldest.IsZero() || e.Time.Before(l.oldest) {
+			l.oldest = e.Time
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read audit file: %w", err)
+	}
+	if info.Size() > size {
+		if err := os.Truncate(l.path, size); err != nil {
+			return fmt.Errorf("failed to repair audit file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Record appends e to the trail, numbering and timestamping it
+func (l *Log) Record(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= pruneInterval {
+		if err := l.prune(); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	e.Seq = l.seq + 1
+	if e.Time.IsZero() {
+		e.Time = now
+	}
+	e.Time = e.Time.UTC()
+
+	if err := l.append(e); err != nil {
+		return Entry{}, err
+	}
+
+	l.seq = e.Seq
+	switch {
+	case l.path != "":
+		if l.oldest.IsZero() {
+			l.oldest = e.Time
+		}
+	case len(l.entries) == MemoryEntries:
+		l.entries = append(l.entries[1:], e)
+	default:
+		l.entries = append(l.entries, e)
+	}
+	return e, nil
+}
+
+// Query returns the entries selected by f, newest first
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	var entries []Entry
+	err := l.scan(func(e Entry) error {
+		switch {
+		case !f.Match(e):
+		case f.Limit > 0 && len(entries) == f.Limit:
+			entries = append(entries[1:], e)
+		default:
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// Export writes the entries selected by f to w as JSON Lines, oldest first
+func (l *Log) Export(w io.Writer, f Filter) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	if f.Limit > 0 {
+		entries, err := l.Query(f)
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			if err := enc.Encode(entries[i]); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}
+
+	err := l.scan(func(e Entry) error {
+		if !f.Match(e) {
+			return nil
+		}
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// scan calls fn with every entry of the trail, oldest first, until fn
+// returns an error. The file is only ever appended to or replaced, so it
+// is read without holding l.mu; a line still being written is skipped.
+func (l *Log) scan(fn func(Entry) error) error {
+	if l.path == "" {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		for _, e := range l.entries {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit file: %w", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit file: %w", err)
+		}
+
+		var e Entry
+		if json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Retention returns how long entries are kept; zero keeps them forever
+func (l *Log) Retention() time.Duration {
+	return l.retention
+}
+
+// Close closes the trail file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// append writes e to the end of the trail file; the caller must hold l.mu
+func (l *Log) append(e Entry) error {
+	if l.path == "" {
+		return nil
+	}
+
+	if l.file == nil {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+			return fmt.Errorf("failed to create audit directory: %w", err)
+		}
+		file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit file: %w", err)
+		}
+		l.file = file
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit file: %w", err)
+	}
+	return nil
+}
+
+// prune drops the entries past the retention period, rewriting the trail
+// file without them; the caller must hold l.mu
+func (l *Log) prune() error {
+	now := l.now()
+	l.lastPrune = now
+
+	if l.retention <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-l.retention)
+
+	if l.path == "" {
+		kept := l.entries[:0]
+		for _, e := range l.entries {
+			if !e.Time.Before(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		l.entries = kept
+		return nil
+	}
+
+	if l.oldest.IsZero() || !l.oldest.Before(cutoff) {
+		return nil
+	}
+
+	oldest, err := l.rewrite(cutoff)
+	if err != nil {
+		return err
+	}
+	l.oldest = oldest
+
+	// Reopen on the next write, as the handle points at the old file
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	return nil
+}
+
+// rewrite atomically replaces the trail file with its entries recorded
+// at or after cutoff, and returns the time of the oldest one; the caller
+// must hold l.mu
+func (l *Log) rewrite(cutoff time.Time) (time.Time, error) {
+	tmp := l.path + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to write audit file: %w", err)
+	}
+	defer os.Remove(tmp)
+	defer dst.Close()
+
+	var oldest time.Time
+	bw := bufio.NewWriter(dst)
+	err = l.scan(func(e Entry) error {
+		if e.Time.Before(cutoff) {
+			return nil
+		}
+		if oldest.IsZero() || e.Time.Before(oldest) {
+			oldest = e.Time
+		}
+
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = bw.Write(append(line, '\n'))
+		return err
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = dst.Close()
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to write audit file: %w", err)
+	}
+
+	if err := os.Rename(tmp, l.path); err != nil {
+		return time.Time{}, fmt.Errorf("failed to replace audit file: %w", err)
+	}
+	return oldest, nil
+}
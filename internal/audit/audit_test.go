@@ -0,0 +1,261 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog_RecordAndQuery(t *testing.T) {
+	l, _ := Open("")
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: base, Actor: Actor{KeyID: "k1", Name: "night-shift"}, Camera: "front-door", Address: "192.168.1.100", Action: "SetPrivacy", Status: 200, Outcome: OutcomeSuccess},
+		{Time: base.Add(time.Minute), Actor: Actor{KeyID: "k2", Name: "ops"}, Address: "192.168.1.101", Action: "Reboot", Status: 403, Outcome: OutcomeDenied},
+		{Time: base.Add(2 * time.Minute), Actor: Actor{KeyID: "k1", Name: "night-shift"}, Camera: "front-door", Address: "192.168.1.100", Action: "DeletePreset", Status: 404, Outcome: OutcomeFailed},
+	}
+	for i, e := range entries {
+		got, err := l.Record(e)
+		if err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		if got.Seq != uint64(i+1) {
+			t.Errorf("Expected seq %d, got %d", i+1, got.Seq)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all newest first", Filter{}, []string{"DeletePreset", "Reboot", "SetPrivacy"}},
+		{"camera id", Filter{Camera: "front-door"}, []string{"DeletePreset", "SetPrivacy"}},
+		{"camera address", Filter{Camera: "192.168.1.101"}, []string{"Reboot"}},
+		{"actor name", Filter{Actor: "night-shift"}, []string{"DeletePreset", "SetPrivacy"}},
+		{"actor key", Filter{Actor: "k2"}, []string{"Reboot"}},
+		{"action", Filter{Action: "setprivacy"}, []string{"SetPrivacy"}},
+		{"outcome", Filter{Outcome: OutcomeDenied}, []string{"Reboot"}},
+		{"since", Filter{Since: base.Add(time.Minute)}, []string{"DeletePreset", "Reboot"}},
+		{"until", Filter{Until: base.Add(time.Minute)}, []string{"SetPrivacy"}},
+		{"limit", Filter{Limit: 1}, []string{"DeletePreset"}},
+	}
+
+	for _, tt := range tests {
+		entries, err := l.Query(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: Query failed: %v", tt.name, err)
+		}
+
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Action)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLog_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	l.Record(Entry{Action: "SetAlarm", Request: Body([]byte(`{"enabled":true}`))})
+	l.Record(Entry{Action: "Reboot"})
+	l.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected audit file: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// A crash mid-write leaves a torn last line
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"seq":3,"act`)
+	f.Close()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer reopened.Close()
+
+	entries, _ := reopened.Query(Filter{})
+	if len(entries) != 2 || entries[1].Action != "SetAlarm" || string(entries[1].Request) != `{"enabled":true}` {
+		t.Fatalf("Expected the recorded entries, got %+v", entries)
+	}
+
+	e, _ := reopened.Record(Entry{Action: "FormatStorage"})
+	if e.Seq != 3 {
+		t.Errorf("Expected seq to continue at 3, got %d", e.Seq)
+	}
+	reopened.Close()
+
+	// The torn line was cut, so the new entry is readable
+	again, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open after repair failed: %v", err)
+	}
+	if entries, _ := again.Query(Filter{}); len(entries) != 3 || entries[0].Action != "FormatStorage" {
+		t.Errorf("Expected 3 entries ending with FormatStorage, got %+v", entries)
+	}
+}
+
+func TestLog_MemoryLimit(t *testing.T) {
+	l, _ := Open("")
+	for i := 0; i < MemoryEntries+5; i++ {
+		l.Record(Entry{Action: "SetLED"})
+	}
+
+	entries, _ := l.Query(Filter{})
+	if len(entries) != MemoryEntries {
+		t.Fatalf("Expected %d entries kept in memory, got %d", MemoryEntries, len(entries))
+	}
+	if entries[0].Seq != MemoryEntries+5 || entries[len(entries)-1].Seq != 6 {
+		t.Errorf("Expected the latest entries, got seq %d to %d", entries[len(entries)-1].Seq, entries[0].Seq)
+	}
+}
+
+func TestLog_FileQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, _ := Open(path)
+	defer l.Close()
+
+	for _, action := range []string{"SetLED", "Reboot", "SetFlip", "Reboot"} {
+		l.Record(Entry{Action: action})
+	}
+	if len(l.entries) != 0 {
+		t.Errorf("Expected a file-backed trail to keep no entries in memory, got %d", len(l.entries))
+	}
+
+	entries, err := l.Query(Filter{Action: "reboot", Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Seq != 4 {
+		t.Errorf("Expected the latest reboot, got %+v", entries)
+	}
+
+	var buf bytes.Buffer
+	l.Export(&buf, Filter{Action: "Reboot"})
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 exported entries, got %s", buf.String())
+	}
+}
+
+func TestLog_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	os.WriteFile(path, []byte("not json\n{\"seq\":2}\n"), 0o600)
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected a corrupt audit file to be rejected")
+	}
+}
+
+func TestLog_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	now := time.Now()
+
+	l, _ := Open(path, WithRetention(24*time.Hour))
+	l.Record(Entry{Time: now.Add(-48 * time.Hour), Action: "Old"})
+	l.Record(Entry{Time: now.Add(-time.Hour), Action: "Recent"})
+	l.Close()
+
+	// Reopening prunes the expired entries from the file
+	reopened, err := Open(path, WithRetention(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if entries, _ := reopened.Query(Filter{}); len(entries) != 1 || entries[0].Action != "Recent" {
+		t.Errorf("Expected only the recent entry, got %+v", entries)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "Old") {
+		t.Errorf("Expected the expired entry to be removed from the file, got %s", data)
+	}
+
+	// Recording prunes hourly, and appends to the rewritten file
+	reopened.now = func() time.Time { return now.Add(24 * time.Hour) }
+	reopened.Record(Entry{Action: "Next"})
+	reopened.Close()
+
+	data, _ = os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), "Next") {
+		t.Errorf("Expected only the new entry, got %s", data)
+	}
+}
+
+func TestLog_Export(t *testing.T) {
+	l, _ := Open("")
+	l.Record(Entry{Action: "SetLED", Camera: "garage"})
+	l.Record(Entry{Action: "Reboot", Camera: "front-door"})
+	l.Record(Entry{Action: "SetFlip", Camera: "garage"})
+
+	var buf bytes.Buffer
+	if err := l.Export(&buf, Filter{Camera: "garage"}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var actions []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		actions = append(actions, e.Action)
+	}
+	if strings.Join(actions, ",") != "SetLED,SetFlip" {
+		t.Errorf("Expected SetLED,SetFlip oldest first, got %v", actions)
+	}
+}
+
+func TestBody(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{`{"enabled":true}`, `{"enabled":true}`},
+		{`{"name":"Garage","password":"hunter2","nested":[{"Secret":"x"}]}`, `{"name":"Garage","nested":[{"Secret":"[REDACTED]"}],"password":"[REDACTED]"}`},
+		{`{"success":true,"secret":"gtk_abc","result":{"token":"eyJ"}}`, `{"result":{"token":"[REDACTED]"},"secret":"[REDACTED]","success":true}`},
+		{"enabled=true", `"enabled=true"`},
+		{`{"blob":"` + strings.Repeat("x", MaxBodySize) + `"}`, `{"truncated_bytes":16395}`},
+	}
+
+	for _, tt := range tests {
+		if got := string(Body([]byte(tt.in))); got != tt.want {
+			t.Errorf("Body(%.40q): expected %s, got %s", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestOutcomeFor(t *testing.T) {
+	tests := map[int]string{
+		200: OutcomeSuccess,
+		201: OutcomeSuccess,
+		401: OutcomeDenied,
+		403: OutcomeDenied,
+		404: OutcomeFailed,
+		502: OutcomeFailed,
+	}
+
+	for status, want := range tests {
+		if got := OutcomeFor(status); got != want {
+			t.Errorf("OutcomeFor(%d): expected %s, got %s", status, want, got)
+		}
+	}
+}
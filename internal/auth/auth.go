@@ -0,0 +1,183 @@
+// Package auth authenticates clients of the API with API keys and signed
+// JWT bearer tokens issued to those keys.
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+)
+
+// Errors returned by Authenticate
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrTokenExpired    = errors.New("token expired")
+)
+
+// BootstrapKeyID identifies the admin key configured in the environment
+const BootstrapKeyID = "bootstrap"
+
+// Principal is an authenticated API client
+type Principal struct {
+	KeyID string `json:"key_id"`
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+
+	// Grants list the camera actions of a non-admin key
+	Grants []rbac.Grant `json:"grants,omitempty"`
+
+	// Method is "api_key" or "jwt"
+	Method string `json:"method"`
+
+	// ExpiresAt is when the credential expires; zero if it does not
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// Can reports whether the principal may perform p on cam. Admin keys may
+// perform every action.
+func (p Principal) Can(perm rbac.Permission, cam rbac.Camera) bool {
+	return p.Admin || rbac.Allows(p.Grants, perm, cam)
+}
+
+// Service authenticates API keys and issues and verifies tokens
+type Service struct {
+	keys         *KeyStore
+	secret       []byte
+	maxTokenTTL  time.Duration
+	bootstrapKey string
+}
+
+// NewService creates an authenticator for keys. Tokens are signed with
+// secret and live at most maxTokenTTL. A non-empty bootstrapKey is accepted
+// as an admin key, to create the first keys.
+func NewService(keys *KeyStore, secret []byte, maxTokenTTL time.Duration, bootstrapKey string) *Service {
+	return &Service{
+		keys:         keys,
+		secret:       secret,
+		maxTokenTTL:  maxTokenTTL,
+		bootstrapKey: bootstrapKey,
+	}
+}
+
+// Keys returns the API key store
+func (s *Service) Keys() *KeyStore {
+	return s.keys
+}
+
+// Authenticate verifies an API key or a JWT
+func (s *Service) Authenticate(credential string) (Principal, error) {
+	now := time.Now()
+
+	if credential == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	if strings.Count(credential, ".") == 2 {
+		claims, err := verifyToken(s.secret, credential, now)
+		if err != nil {
+			if errors.Is(err, ErrTokenExpired) {
+				return Principal{}, err
+			}
+			return Principal{}, ErrUnauthenticated
+		}
+
+		// Tokens die with the key they were issued to
+		p, err := s.principalForKey(claims.Subject, now)
+		if err != nil {
+			return Principal{}, err
+		}
+		p.Method = "jwt"
+		p.ExpiresAt = time.Unix(claims.ExpiresAt, 0).UTC()
+		return p, nil
+	}
+
+	if s.bootstrapKey != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(s.bootstrapKey)) == 1 {
+		return Principal{KeyID: BootstrapKeyID, Name: BootstrapKeyID, Admin: true, Method: "api_key"}, nil
+	}
+
+	key, ok := s.keys.Lookup(credential)
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	if key.Expired(now) {
+		return Principal{}, ErrTokenExpired
+	}
+
+	return principal(key, "api_key"), nil
+}
+
+// IssueToken returns a JWT for the key with keyID valid for ttl, capped by
+// the maximum token lifetime and the key's own expiry
+func (s *Service) IssueToken(keyID string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+
+	p, err := s.principalForKey(keyID, now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if ttl <= 0 || ttl > s.maxTokenTTL {
+		ttl = s.maxTokenTTL
+	}
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	if !p.ExpiresAt.IsZero() && p.ExpiresAt.Before(expiresAt) {
+		expiresAt = p.ExpiresAt
+	}
+
+	jti, err := randomString(12)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, err := signToken(s.secret, Claims{
+		Subject:   p.KeyID,
+		Name:      p.Name,
+		Admin:     p.Admin,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        jti,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt.UTC(), nil
+}
+
+// principalForKey returns the principal of a live key
+func (s *Service) principalForKey(keyID string, now time.Time) (Principal, error) {
+	if keyID == BootstrapKeyID {
+		if s.bootstrapKey == "" {
+			return Principal{}, ErrUnauthenticated
+		}
+		return Principal{KeyID: BootstrapKeyID, Name: BootstrapKeyID, Admin: true}, nil
+	}
+
+	key, ok := s.keys.Get(keyID)
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	if key.Expired(now) {
+		return Principal{}, ErrTokenExpired
+	}
+
+	return principal(key, ""), nil
+}
+
+// principal returns the principal authenticated by key
+func principal(key APIKey, method string) Principal {
+	p := Principal{
+		KeyID:  key.ID,
+		Name:   key.Name,
+		Admin:  key.Admin,
+		Grants: key.Grants,
+		Method: method,
+	}
+	if key.ExpiresAt != nil {
+		p.ExpiresAt = *key.ExpiresAt
+	}
+	return p
+}
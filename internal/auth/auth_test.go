@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	keys, err := OpenKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("OpenKeyStore failed: %v", err)
+	}
+	return NewService(keys, []byte("test-secret-test-secret-test-sec"), time.Hour, "gtk_bootstrap")
+}
+
+func TestService_APIKeys(t *testing.T) {
+	svc := newTestService(t)
+
+	key, secret, err := svc.Keys().Create("dashboard", false, nil, nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, KeyPrefix) {
+		t.Errorf("Expected secret with prefix %s, got %s", KeyPrefix, secret)
+	}
+
+	p, err := svc.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if p.KeyID != key.ID || p.Admin || p.Method != "api_key" {
+		t.Errorf("Unexpected principal: %+v", p)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	_, expiredSecret, _ := svc.Keys().Create("old", false, nil, &past)
+
+	tests := []struct {
+		name       string
+		credential string
+		err        error
+	}{
+		{"empty", "", ErrUnauthenticated},
+		{"unknown key", KeyPrefix + "unknown", ErrUnauthenticated},
+		{"expired key", expiredSecret, ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		if _, err := svc.Authenticate(tt.credential); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	if p, err := svc.Authenticate("gtk_bootstrap"); err != nil || !p.Admin {
+		t.Errorf("Expected bootstrap key to authenticate as admin, got %+v %v", p, err)
+	}
+}
+
+func TestKeyStore_StoresOnlyHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	keys, _ := OpenKeyStore(path)
+
+	_, secret, err := keys.Create("dashboard", true, nil, nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Key file not written: %v", err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Error("Key file contains the plaintext secret")
+	}
+
+	reopened, _ := OpenKeyStore(path)
+	if key, ok := reopened.Lookup(secret); !ok || !key.Admin {
+		t.Errorf("Expected key to survive reopening, got %+v %v", key, ok)
+	}
+}
+
+func TestService_Tokens(t *testing.T) {
+	svc := newTestService(t)
+	key, _, _ := svc.Keys().Create("dashboard", true, nil, nil)
+
+	token, expiresAt, err := svc.IssueToken(key.ID, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	if d := time.Until(expiresAt); d > time.Hour || d < 59*time.Minute {
+		t.Errorf("Expected TTL capped at 1h, got %v", d)
+	}
+
+	p, err := svc.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if p.KeyID != key.ID || !p.Admin || p.Method != "jwt" {
+		t.Errorf("Unexpected principal: %+v", p)
+	}
+
+	parts := strings.Split(token, ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	forged, _ := signToken([]byte("other-secret"), Claims{Subject: key.ID, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	expired, _ := signToken(svc.secret, Claims{Subject: key.ID, ExpiresAt: time.Now().Add(-time.Second).Unix()})
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x","exp":9999999999}`)) + "." + parts[2], ErrUnauthenticated},
+		{"alg none", none + "." + parts[1] + ".", ErrUnauthenticated},
+		{"other secret", forged, ErrUnauthenticated},
+		{"expired", expired, ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		if _, err := svc.Authenticate(tt.token); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	// Revoking the key revokes its tokens
+	if err := svc.Keys().Delete(key.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := svc.Authenticate(token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected token of a deleted key to be rejected, got %v", err)
+	}
+}
+
+func TestService_TokenCappedByKeyExpiry(t *testing.T) {
+	svc := newTestService(t)
+
+	expires := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	key, _, _ := svc.Keys().Create("temporary", false, nil, &expires)
+
+	_, expiresAt, err := svc.IssueToken(key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	if !expiresAt.Equal(expires) {
+		t.Errorf("Expected token to expire with its key at %v, got %v", expires, expiresAt)
+	}
+}
+
+func TestService_TokensFollowKeyGrants(t *testing.T) {
+	svc := newTestService(t)
+	key, _, _ := svc.Keys().Create("night-shift", false, []rbac.Grant{{Role: "viewer"}}, nil)
+
+	token, _, _ := svc.IssueToken(key.ID, time.Hour)
+	cam := rbac.Camera{ID: "front-door"}
+
+	p, _ := svc.Authenticate(token)
+	if p.Can(rbac.PTZControl, cam) {
+		t.Error("Expected a viewer not to control PTZ")
+	}
+
+	if _, err := svc.Keys().SetGrants(key.ID, []rbac.Grant{{Role: "operator"}}); err != nil {
+		t.Fatalf("SetGrants failed: %v", err)
+	}
+
+	p, _ = svc.Authenticate(token)
+	if !p.Can(rbac.PTZControl, cam) {
+		t.Error("Expected the token to follow the new grants")
+	}
+
+	if _, err := svc.Keys().SetGrants(key.ID, []rbac.Grant{{Role: "superuser"}}); err == nil {
+		t.Error("Expected an unknown role to be rejected")
+	}
+}
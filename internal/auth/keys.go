@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+)
+
+// KeyPrefix starts every API key so keys are recognisable in configs and
+// distinguishable from JWTs
+const KeyPrefix = "gtk_"
+
+// ErrKeyNotFound is returned for an unknown API key ID
+var ErrKeyNotFound = errors.New("api key not found")
+
+// APIKey is an issued API key. Only the SHA-256 hash of the secret is
+// stored.
+type APIKey struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Admin     bool         `json:"admin"`
+	Grants    []rbac.Grant `json:"grants,omitempty"`
+	Hash      string       `json:"hash"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the key has expired at now
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// KeyStore is an API key store persisted as a JSON file
+type KeyStore struct {
+	mu   sync.Mutex
+	path string
+	keys map[string]APIKey
+}
+
+// OpenKeyStore loads the key file at path, creating it on first write. An
+// empty path keeps keys in memory only.
+func OpenKeyStore(path string) (*KeyStore, error) {
+	s := &KeyStore{
+		path: path,
+		keys: make(map[string]APIKey),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key file: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse api key file: %w", err)
+	}
+	for _, key := range keys {
+		s.keys[key.ID] = key
+	}
+
+	return s, nil
+}
+
+// Create issues a new key and returns it with its secret. Admin keys
+// manage the server and may perform every camera action; other keys may
+// perform the actions of their grants. The secret is not stored and
+// cannot be retrieved later.
+func (s *KeyStore) Create(name string, admin bool, grants []rbac.Grant, expiresAt *time.Time) (APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APIKey{}, "", errors.New("key name is required")
+	}
+	if err := validateGrants(grants); err != nil {
+		return APIKey{}, "", err
+	}
+
+	id, err := randomString(9)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	secret = KeyPrefix + secret
+
+	key := APIKey{
+		ID:        id,
+		Name:      name,
+		Admin:     admin,
+		Grants:    grants,
+		Hash:      hashSecret(secret),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		return APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// Get returns the key with id
+func (s *KeyStore) Get(id string) (APIKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+// Lookup returns the key whose secret is secret
+func (s *KeyStore) Lookup(secret string) (APIKey, bool) {
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, true
+		}
+	}
+	return APIKey{}, false
+}
+
+// SetGrants replaces the grants of the key with id
+func (s *KeyStore) SetGrants(id string, grants []rbac.Grant) (APIKey, error) {
+	if err := validateGrants(grants); err != nil {
+		return APIKey{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, ErrKeyNotFound
+	}
+
+	old := key
+	key.Grants = grants
+	s.keys[id] = key
+	if err := s.save(); err != nil {
+		s.keys[id] = old
+		return APIKey{}, err
+	}
+	return key, nil
+}
+
+// List returns all keys sorted by creation time
+func (s *KeyStore) List() []APIKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted()
+}
+
+// Delete revokes the key with id, and with it the tokens issued to it
+func (s *KeyStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	delete(s.keys, id)
+	if err := s.save(); err != nil {
+		s.keys[id] = key
+		return err
+	}
+	return nil
+}
+
+// sorted returns the keys ordered by creation time; the caller must hold s.mu
+func (s *KeyStore) sorted() []APIKey {
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// save atomically writes the keys to disk; the caller must hold s.mu
+func (s *KeyStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create api key directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write api key file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// validateGrants checks every grant
+func validateGrants(grants []rbac.Grant) error {
+	for i, g := range grants {
+		if err := g.Validate(); err != nil {
+			return fmt.Errorf("grant %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// hashSecret returns the hex SHA-256 hash under which a secret is stored
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as unpadded base64url
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"net"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -15,22 +19,97 @@ type Config struct {
 	DefaultUsername string
 	DefaultPassword string
 
+	// Camera session settings
+	SessionIdleTimeout time.Duration
+
+	// Camera connection settings
+	CameraPort            int
+	CameraTimeout         time.Duration
+	CameraKeepAlive       bool
+	CameraMaxIdleConns    int
+	CameraIdleConnTimeout time.Duration
+	CameraTLSSessionCache int
+
+	// File storing trusted camera certificates; empty disables pinning
+	CameraPinFile string
+
+	// File storing the camera registry; empty keeps it in memory
+	CameraRegistryFile string
+
+	// Master keys encrypting stored camera passwords, inline or in a file;
+	// the first key is active
+	VaultKey     string
+	VaultKeyFile string
+
+	// Cameras whose protocol exchanges are traced; "*" traces all
+	CameraTrace []string
+
+	// Proxy used to reach all cameras, and per-camera overrides keyed by
+	// camera address or CIDR range
+	CameraProxy   string
+	CameraProxies map[string]string
+
 	// API settings
 	APIPrefix string
 
+	// API authentication; the admin key bootstraps the first API keys
+	APIAuth     bool
+	APIAdminKey string
+	APIKeysFile string
+	JWTSecret   string
+	TokenTTL    time.Duration
+
+	// Audit trail of the requests changing cameras or server state; an
+	// empty file keeps it in memory. Entries older than the retention are
+	// dropped; zero keeps them forever.
+	AuditFile      string
+	AuditRetention time.Duration
+
+	// Prometheus metrics served at /metrics
+	Metrics bool
+
 	// Logging
-	LogLevel string
+	LogLevel  string
+	LogFormat string
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		ServerPort:      getEnv("SERVER_PORT", "3000"),
-		ServerHost:      getEnv("SERVER_HOST", "0.0.0.0"),
-		DefaultUsername: getEnv("TAPO_DEFAULT_USERNAME", ""),
-		DefaultPassword: getEnv("TAPO_DEFAULT_PASSWORD", ""),
-		APIPrefix:       getEnv("API_PREFIX", "/api"),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		ServerPort:         getEnv("SERVER_PORT", "3000"),
+		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0"),
+		DefaultUsername:    getEnv("TAPO_DEFAULT_USERNAME", ""),
+		DefaultPassword:    getEnv("TAPO_DEFAULT_PASSWORD", ""),
+		SessionIdleTimeout: getEnvDuration("SESSION_IDLE_TIMEOUT", 5*time.Minute),
+
+		CameraPort:            getEnvInt("TAPO_PORT", 443),
+		CameraTimeout:         getEnvDuration("TAPO_TIMEOUT", 10*time.Second),
+		CameraKeepAlive:       getEnvBool("TAPO_KEEP_ALIVE", true),
+		CameraMaxIdleConns:    getEnvInt("TAPO_MAX_IDLE_CONNS", 2),
+		CameraIdleConnTimeout: getEnvDuration("TAPO_IDLE_CONN_TIMEOUT", 30*time.Second),
+		CameraTLSSessionCache: getEnvInt("TAPO_TLS_SESSION_CACHE", 8),
+		CameraPinFile:         getEnv("TAPO_PIN_FILE", ""),
+		CameraRegistryFile:    getEnv("TAPO_REGISTRY_FILE", "data/cameras.json"),
+		VaultKey:              getEnv("VAULT_KEY", ""),
+		VaultKeyFile:          getEnv("VAULT_KEY_FILE", ""),
+		CameraTrace:           getEnvList("TAPO_TRACE", nil),
+		CameraProxy:           getEnv("TAPO_PROXY", ""),
+		CameraProxies:         getEnvMap("TAPO_CAMERA_PROXIES"),
+
+		APIPrefix:   getEnv("API_PREFIX", "/api"),
+		APIAuth:     getEnvBool("API_AUTH", false),
+		APIAdminKey: getEnv("API_ADMIN_KEY", ""),
+		APIKeysFile: getEnv("API_KEYS_FILE", "data/api_keys.json"),
+		JWTSecret:   getEnv("JWT_SECRET", ""),
+		TokenTTL:    getEnvDuration("TOKEN_TTL", time.Hour),
+
+		AuditFile:      getEnv("AUDIT_FILE", "data/audit.jsonl"),
+		AuditRetention: getEnvDuration("AUDIT_RETENTION", 90*24*time.Hour),
+
+		Metrics: getEnvBool("METRICS", true),
+
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "json"),
 	}
 }
 
@@ -39,6 +118,42 @@ func (c *Config) GetServerAddress() string {
 	return c.ServerHost + ":" + c.ServerPort
 }
 
+// DirectProxy in CameraProxies connects to a camera without the default proxy
+const DirectProxy = "direct"
+
+// ProxyFor returns the proxy URL for the camera at host, or "" to connect
+// directly. An entry for the exact address wins over one for the address
+// without its port, which wins over the narrowest matching CIDR range.
+func (c *Config) ProxyFor(host string) string {
+	proxy, ok := c.CameraProxies[host]
+
+	addr := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		addr = h
+	}
+	if !ok {
+		proxy, ok = c.CameraProxies[addr]
+	}
+
+	if ip, err := netip.ParseAddr(addr); !ok && err == nil {
+		bits := -1
+		for key, value := range c.CameraProxies {
+			prefix, err := netip.ParsePrefix(key)
+			if err == nil && prefix.Contains(ip) && prefix.Bits() > bits {
+				proxy, ok, bits = value, true, prefix.Bits()
+			}
+		}
+	}
+
+	if !ok {
+		proxy = c.CameraProxy
+	}
+	if proxy == DirectProxy {
+		return ""
+	}
+	return proxy
+}
+
 // getEnv gets an environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -47,6 +162,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList gets a comma-separated environment variable with a default value
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap gets a comma-separated list of key=value pairs
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key, nil) {
+		if k, v, ok := strings.Cut(item, "="); ok {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
 // getEnvInt gets an integer environment variable with a default value
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -66,3 +208,13 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvDuration gets a duration environment variable with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
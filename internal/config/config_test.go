@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+func TestConfig_ProxyFor(t *testing.T) {
+	cfg := &Config{
+		CameraProxy: "socks5://jump.hq:1080",
+		CameraProxies: map[string]string{
+			"10.1.0.0/16":     "socks5://jump.site-a:1080",
+			"10.1.2.0/24":     "http://jump.site-a-annex:3128",
+			"10.1.2.50":       "http://jump.site-a-gate:3128",
+			"10.1.2.60:8443":  "socks5://jump.site-a-lab:1080",
+			"192.168.1.100":   DirectProxy,
+			"camera.site-b":   "http://jump.site-b:3128",
+			"fd00:1::/64":     "socks5://jump.site-c:1080",
+			"not-a-cidr/1234": "http://ignored:1",
+		},
+	}
+
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"10.1.9.9", "socks5://jump.site-a:1080"},
+		{"10.1.2.9", "http://jump.site-a-annex:3128"},
+		{"10.1.2.50", "http://jump.site-a-gate:3128"},
+		{"10.1.2.50:8443", "http://jump.site-a-gate:3128"},
+		{"10.1.2.60:8443", "socks5://jump.site-a-lab:1080"},
+		{"10.1.2.60", "http://jump.site-a-annex:3128"},
+		{"192.168.1.100", ""},
+		{"camera.site-b", "http://jump.site-b:3128"},
+		{"fd00:1::20", "socks5://jump.site-c:1080"},
+		{"[fd00:1::20]:443", "socks5://jump.site-c:1080"},
+		{"172.16.0.1", "socks5://jump.hq:1080"},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.ProxyFor(tt.host); got != tt.expected {
+			t.Errorf("ProxyFor(%q): expected %q, got %q", tt.host, tt.expected, got)
+		}
+	}
+}
+
+func TestGetEnvMap(t *testing.T) {
+	t.Setenv("TEST_MAP", "10.1.0.0/16=socks5://jump:1080, 192.168.1.100 = direct,invalid")
+
+	m := getEnvMap("TEST_MAP")
+
+	if len(m) != 2 {
+		t.Errorf("Expected 2 entries, got %v", m)
+	}
+	if m["10.1.0.0/16"] != "socks5://jump:1080" || m["192.168.1.100"] != "direct" {
+		t.Errorf("Unexpected entries: %v", m)
+	}
+}
@@ -1,19 +1,20 @@
 package handlers
 
 import (
-	"strconv"
-
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // AlarmHandler handles alarm operations
-type AlarmHandler struct{}
+type AlarmHandler struct {
+	pool *tapo.Pool
+}
 
 // NewAlarmHandler creates a new alarm handler
-func NewAlarmHandler() *AlarmHandler {
-	return &AlarmHandler{}
+func NewAlarmHandler(pool *tapo.Pool) *AlarmHandler {
+	return &AlarmHandler{pool: pool}
 }
 
 // SetAlarmRequest represents an alarm configuration request
@@ -27,41 +28,28 @@ type SetAlarmRequest struct {
 // GetAlarm gets alarm status
 // GET /api/cameras/:ip/alarm
 func (h *AlarmHandler) GetAlarm(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getLastAlarmInfo", map[string]interface{}{
-		"msg_alarm": map[string]interface{}{
-			"name": []string{"chn1_msg_alarm_info"},
-		},
-	})
+	result, err := client.GetAlarmInfo(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetAlarm sets alarm configuration
 // PUT /api/cameras/:ip/alarm
 func (h *AlarmHandler) SetAlarm(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetAlarmRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	enabled := "off"
@@ -69,112 +57,60 @@ func (h *AlarmHandler) SetAlarm(c *fiber.Ctx) error {
 		enabled = "on"
 	}
 
-	alarmConfig := map[string]interface{}{
-		"enabled": enabled,
+	settings := tapo.Chn1AlarmSettings{
+		Enabled:   enabled,
+		AlarmType: "0",
+		LightType: "0",
+		AlarmMode: []string{"sound", "light"},
 	}
 
 	if req.AlarmType != "" {
-		alarmConfig["alarm_type"] = req.AlarmType
-	} else {
-		alarmConfig["alarm_type"] = "0"
+		settings.AlarmType = req.AlarmType
 	}
 
 	if req.LightType != "" {
-		alarmConfig["light_type"] = req.LightType
-	} else {
-		alarmConfig["light_type"] = "0"
+		settings.LightType = req.LightType
 	}
 
 	if len(req.AlarmMode) > 0 {
-		alarmConfig["alarm_mode"] = req.AlarmMode
-	} else {
-		alarmConfig["alarm_mode"] = []string{"sound", "light"}
+		settings.AlarmMode = req.AlarmMode
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "set",
-		"msg_alarm": map[string]interface{}{
-			"chn1_msg_alarm_info": alarmConfig,
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.SetAlarm(c.UserContext(), settings); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // TriggerAlarm starts manual alarm
 // POST /api/cameras/:ip/alarm/trigger
 func (h *AlarmHandler) TriggerAlarm(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "do",
-		"msg_alarm": map[string]interface{}{
-			"manual_msg_alarm": map[string]string{
-				"action": "start",
-			},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.StartManualAlarm(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // StopAlarm stops manual alarm
 // DELETE /api/cameras/:ip/alarm/trigger
 func (h *AlarmHandler) StopAlarm(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "do",
-		"msg_alarm": map[string]interface{}{
-			"manual_msg_alarm": map[string]string{
-				"action": "stop",
-			},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.StopManualAlarm(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
-
-// Helper to use strconv package
-var _ = strconv.Itoa
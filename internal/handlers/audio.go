@@ -4,16 +4,19 @@ import (
 	"strconv"
 
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // AudioHandler handles audio operations
-type AudioHandler struct{}
+type AudioHandler struct {
+	pool *tapo.Pool
+}
 
 // NewAudioHandler creates a new audio handler
-func NewAudioHandler() *AudioHandler {
-	return &AudioHandler{}
+func NewAudioHandler(pool *tapo.Pool) *AudioHandler {
+	return &AudioHandler{pool: pool}
 }
 
 // SetSpeakerRequest represents a speaker volume request
@@ -30,130 +33,74 @@ type SetMicrophoneRequest struct {
 // GetConfig gets audio configuration
 // GET /api/cameras/:ip/audio
 func (h *AudioHandler) GetConfig(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "get",
-		"audio_config": map[string]interface{}{
-			"name": []string{"microphone", "speaker"},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
+	result, err := client.GetAudioConfig(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetSpeaker sets speaker volume
 // PUT /api/cameras/:ip/audio/speaker
 func (h *AudioHandler) SetSpeaker(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetSpeakerRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	// Validate volume range
 	if req.Volume < 0 || req.Volume > 100 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_volume",
-			"message": "Volume must be between 0 and 100",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_volume", "Volume must be between 0 and 100")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "set",
-		"audio_config": map[string]interface{}{
-			"speaker": map[string]string{
-				"volume": strconv.Itoa(req.Volume),
-			},
-		},
-	}
-
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.SetSpeakerVolume(c.UserContext(), req.Volume); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // SetMicrophone sets microphone configuration
 // PUT /api/cameras/:ip/audio/microphone
 func (h *AudioHandler) SetMicrophone(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetMicrophoneRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
-	micConfig := make(map[string]string)
-
-	if req.Volume > 0 {
-		if req.Volume > 100 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "invalid_volume",
-				"message": "Volume must be between 0 and 100",
-			})
-		}
-		micConfig["volume"] = strconv.Itoa(req.Volume)
+	if req.Volume > 100 {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_volume", "Volume must be between 0 and 100")
 	}
 
 	muteValue := "off"
 	if req.Mute {
 		muteValue = "on"
 	}
-	micConfig["mute"] = muteValue
-
-	client := tapo.NewClient(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "set",
-		"audio_config": map[string]interface{}{
-			"microphone": micConfig,
-		},
+	settings := tapo.MicrophoneSettings{Mute: muteValue}
+	if req.Volume > 0 {
+		settings.Volume = strconv.Itoa(req.Volume)
 	}
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	client := h.pool.Get(cameraIP, username, password)
+
+	if err := client.SetMicrophone(c.UserContext(), settings); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bufio"
+	"strconv"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Audit query limits
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditHandler serves the audit trail
+type AuditHandler struct {
+	trail *audit.Log
+}
+
+// NewAuditHandler creates a new audit trail handler
+func NewAuditHandler(trail *audit.Log) *AuditHandler {
+	return &AuditHandler{trail: trail}
+}
+
+// List returns the latest audit entries, newest first, filtered by the
+// camera, actor, action, outcome, since and until query parameters
+// GET /api/audit
+func (h *AuditHandler) List(c *fiber.Ctx) error {
+	f, err := auditFilter(c)
+	if err != nil {
+		return invalidAuditQuery(c, err)
+	}
+
+	f.Limit = DefaultAuditLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxAuditLimit {
+			return invalidAuditQuery(c, fiber.NewError(fiber.StatusBadRequest,
+				"limit must be between 1 and "+strconv.Itoa(MaxAuditLimit)))
+		}
+		f.Limit = limit
+	}
+
+	entries, err := h.trail.Query(f)
+	if err != nil {
+		return response.Fail(c, fiber.StatusInternalServerError, "read_failed", err.Error())
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	return response.OK(c, entries)
+}
+
+// Export streams the audit entries matching the same filters as List as
+// JSON Lines, oldest first
+// GET /api/audit/export
+func (h *AuditHandler) Export(c *fiber.Ctx) error {
+	f, err := auditFilter(c)
+	if err != nil {
+		return invalidAuditQuery(c, err)
+	}
+
+	name := "audit-" + time.Now().UTC().Format("20060102T150405Z") + ".jsonl"
+	c.Attachment(name)
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		h.trail.Export(w, f)
+	})
+	return nil
+}
+
+// auditFilter reads the audit filters from the query string
+func auditFilter(c *fiber.Ctx) (audit.Filter, error) {
+	f := audit.Filter{
+		Camera:  c.Query("camera"),
+		Actor:   c.Query("actor"),
+		Action:  c.Query("action"),
+		Outcome: c.Query("outcome"),
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, p.name+" must be an RFC 3339 time")
+		}
+		*p.dst = t
+	}
+
+	return f, nil
+}
+
+// invalidAuditQuery writes the response for invalid audit filters
+func invalidAuditQuery(c *fiber.Ctx, err error) error {
+	return response.Fail(c, fiber.StatusBadRequest, "invalid_query", err.Error())
+}
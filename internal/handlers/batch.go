@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchRequests limits how many methods a single batch may contain
+const maxBatchRequests = 20
+
+// BatchHandler handles batched camera calls
+type BatchHandler struct {
+	pool *tapo.Pool
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(pool *tapo.Pool) *BatchHandler {
+	return &BatchHandler{pool: pool}
+}
+
+// BatchRequest represents a batch of camera methods sent in one multipleRequest
+type BatchRequest struct {
+	Requests []tapo.SingleRequest `json:"requests"`
+}
+
+// BatchCallResult is the outcome of one method of a batch
+type BatchCallResult struct {
+	Method    string          `json:"method"`
+	Success   bool            `json:"success"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	ErrorCode int             `json:"error_code,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// Execute sends several methods to the camera in a single multipleRequest
+// POST /api/cameras/:ip/batch
+func (h *BatchHandler) Execute(c *fiber.Ctx) error {
+	cameraIP := middleware.GetCameraHost(c)
+	username, password := middleware.GetTapoCredentials(c)
+
+	var req BatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	if len(req.Requests) == 0 || len(req.Requests) > maxBatchRequests {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_batch", fmt.Sprintf("Batch must contain 1-%d requests", maxBatchRequests))
+	}
+
+	methods := make([]string, len(req.Requests))
+	for i, single := range req.Requests {
+		if single.Method == "" {
+			return response.Fail(c, fiber.StatusBadRequest, "invalid_batch", "Every request needs a method")
+		}
+		methods[i] = single.Method
+	}
+	middleware.SetAuditOperations(c, methods)
+
+	client := h.pool.Get(cameraIP, username, password)
+
+	results, err := client.ExecuteBatchContext(c.UserContext(), req.Requests)
+	if err != nil {
+		return executionError(c, err)
+	}
+
+	return response.OK(c, batchCallResults(results))
+}
+
+// batchCallResults converts client batch results to their REST form
+func batchCallResults(results []tapo.BatchResult) []BatchCallResult {
+	out := make([]BatchCallResult, len(results))
+	for i, result := range results {
+		out[i] = BatchCallResult{
+			Method:  result.Method,
+			Success: result.Err == nil,
+			Result:  result.Result,
+		}
+
+		if result.Err != nil {
+			out[i].Message = result.Err.Error()
+
+			var tapoErr *tapo.TapoError
+			if errors.As(result.Err, &tapoErr) {
+				out[i].ErrorCode = tapoErr.Code
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CamerasHandler manages the camera registry
+type CamerasHandler struct {
+	registry *registry.Registry
+	pool     *tapo.Pool
+}
+
+// NewCamerasHandler creates a new camera registry handler
+func NewCamerasHandler(registry *registry.Registry, pool *tapo.Pool) *CamerasHandler {
+	return &CamerasHandler{registry: registry, pool: pool}
+}
+
+// CameraRequest represents a create or update camera request. On update,
+// omitted fields are left unchanged.
+type CameraRequest struct {
+	ID       string    `json:"id,omitempty"`
+	Name     *string   `json:"name,omitempty"`
+	Address  *string   `json:"address,omitempty"`
+	Username *string   `json:"username,omitempty"`
+	Password *string   `json:"password,omitempty"`
+	Scheme   *string   `json:"scheme,omitempty"`
+	BasePath *string   `json:"base_path,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+}
+
+// CameraResponse is a registered camera as returned by the API. Passwords
+// are never returned.
+type CameraResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	Username    string    `json:"username,omitempty"`
+	HasPassword bool      `json:"has_password"`
+	Scheme      string    `json:"scheme,omitempty"`
+	BasePath    string    `json:"base_path,omitempty"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// newCameraResponse converts a registered camera for the API
+func newCameraResponse(cam registry.Camera) CameraResponse {
+	tags := cam.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return CameraResponse{
+		ID:          cam.ID,
+		Name:        cam.Name,
+		Address:     cam.Address,
+		Username:    cam.Username,
+		HasPassword: cam.Password != "",
+		Scheme:      cam.Scheme,
+		BasePath:    cam.BasePath,
+		Tags:        tags,
+		CreatedAt:   cam.CreatedAt,
+		UpdatedAt:   cam.UpdatedAt,
+	}
+}
+
+// apply copies the fields present in req onto cam
+func (req CameraRequest) apply(cam *registry.Camera) {
+	if req.Name != nil {
+		cam.Name = *req.Name
+	}
+	if req.Address != nil {
+		cam.Address = *req.Address
+	}
+	if req.Username != nil {
+		cam.Username = *req.Username
+	}
+	if req.Password != nil {
+		cam.Password = *req.Password
+	}
+	if req.Scheme != nil {
+		cam.Scheme = *req.Scheme
+	}
+	if req.BasePath != nil {
+		cam.BasePath = *req.BasePath
+	}
+	if req.Tags != nil {
+		cam.Tags = *req.Tags
+	}
+}
+
+// visible reports whether the API client may view cam
+func visible(c *fiber.Ctx, cam registry.Camera) bool {
+	return middleware.Allowed(c, rbac.CameraView, rbac.Camera{ID: cam.ID, Address: cam.Address, Tags: cam.Tags})
+}
+
+// List returns the registered cameras the client may view, optionally
+// filtered by tag
+// GET /api/cameras?tag=outdoor
+func (h *CamerasHandler) List(c *fiber.Ctx) error {
+	tag := c.Query("tag")
+
+	cameras := []CameraResponse{}
+	for _, cam := range h.registry.List() {
+		if (tag == "" || cam.HasTag(tag)) && visible(c, cam) {
+			cameras = append(cameras, newCameraResponse(cam))
+		}
+	}
+
+	return response.OK(c, cameras)
+}
+
+// Get returns a registered camera by ID or address
+// GET /api/cameras/:id
+func (h *CamerasHandler) Get(c *fiber.Ctx) error {
+	cam, ok := h.registry.Resolve(c.Params("id"))
+	if !ok || !visible(c, cam) {
+		return registryError(c, registry.ErrNotFound)
+	}
+
+	return response.OK(c, newCameraResponse(cam))
+}
+
+// Create registers a camera
+// POST /api/cameras
+func (h *CamerasHandler) Create(c *fiber.Ctx) error {
+	var req CameraRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	cam := registry.Camera{ID: req.ID}
+	req.apply(&cam)
+
+	cam, err := h.registry.Create(cam)
+	if err != nil {
+		return registryError(c, err)
+	}
+
+	// Drop sessions opened with other credentials before registration
+	h.pool.EvictHost(cam.Address)
+
+	return response.Created(c, newCameraResponse(cam))
+}
+
+// Update changes a registered camera by ID or address
+// PUT /api/cameras/:id
+func (h *CamerasHandler) Update(c *fiber.Ctx) error {
+	cam, ok := h.registry.Resolve(c.Params("id"))
+	if !ok {
+		return registryError(c, registry.ErrNotFound)
+	}
+
+	var req CameraRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+	if req.ID != "" && req.ID != cam.ID {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_camera", "Camera ID cannot be changed")
+	}
+
+	oldAddress := cam.Address
+	req.apply(&cam)
+
+	cam, err := h.registry.Update(cam)
+	if err != nil {
+		return registryError(c, err)
+	}
+
+	h.pool.EvictHost(oldAddress)
+	h.pool.EvictHost(cam.Address)
+
+	return response.OK(c, newCameraResponse(cam))
+}
+
+// Delete removes a camera from the registry by ID or address
+// DELETE /api/cameras/:id
+func (h *CamerasHandler) Delete(c *fiber.Ctx) error {
+	cam, ok := h.registry.Resolve(c.Params("id"))
+	if !ok {
+		return registryError(c, registry.ErrNotFound)
+	}
+
+	cam, err := h.registry.Delete(cam.ID)
+	if err != nil {
+		return registryError(c, err)
+	}
+
+	h.pool.EvictHost(cam.Address)
+
+	return response.Message(c, "Camera removed")
+}
+
+// registryError writes the response for a failed registry operation
+func registryError(c *fiber.Ctx, err error) error {
+	var verr *registry.ValidationError
+
+	switch {
+	case errors.As(err, &verr):
+		return response.FailWith(c, fiber.StatusBadRequest, response.Error{
+			Code:    "invalid_camera",
+			Message: err.Error(),
+			Field:   verr.Field,
+		})
+	case errors.Is(err, registry.ErrNotFound):
+		return response.Fail(c, fiber.StatusNotFound, "not_found", "Camera not registered")
+	case errors.Is(err, registry.ErrExists):
+		return response.Fail(c, fiber.StatusConflict, "camera_exists", err.Error())
+	default:
+		return response.Fail(c, fiber.StatusInternalServerError, "save_failed", err.Error())
+	}
+}
@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // DetectionHandler handles detection configuration operations
-type DetectionHandler struct{}
+type DetectionHandler struct {
+	pool *tapo.Pool
+}
 
 // NewDetectionHandler creates a new detection handler
-func NewDetectionHandler() *DetectionHandler {
-	return &DetectionHandler{}
+func NewDetectionHandler(pool *tapo.Pool) *DetectionHandler {
+	return &DetectionHandler{pool: pool}
 }
 
 // SetMotionDetectionRequest represents a motion detection configuration request
@@ -29,156 +32,71 @@ type SetPersonDetectionRequest struct {
 // GetMotionDetection gets motion detection configuration
 // GET /api/cameras/:ip/detection/motion
 func (h *DetectionHandler) GetMotionDetection(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getDetectionConfig", map[string]interface{}{
-		"motion_detection": map[string]interface{}{
-			"name": []string{"motion_det"},
-		},
-	})
+	result, err := client.GetMotionDetection(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetMotionDetection sets motion detection configuration
 // PUT /api/cameras/:ip/detection/motion
 func (h *DetectionHandler) SetMotionDetection(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetMotionDetectionRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
-	enabled := "off"
-	if req.Enabled {
-		enabled = "on"
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	params := map[string]interface{}{
-		"motion_detection": map[string]interface{}{
-			"motion_det": map[string]interface{}{
-				"enabled": enabled,
-			},
-		},
+	if err := client.SetMotionDetection(c.UserContext(), req.Enabled, req.Sensitivity); err != nil {
+		return executionError(c, err)
 	}
 
-	// Add sensitivity if specified
-	if req.Sensitivity > 0 {
-		params["motion_detection"].(map[string]interface{})["motion_det"].(map[string]interface{})["digital_sensitivity"] = formatNumber(req.Sensitivity)
-	}
-
-	client := tapo.NewClient(cameraIP, username, password)
-
-	result, err := client.Execute("setDetectionConfig", params)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // GetPersonDetection gets person detection configuration
 // GET /api/cameras/:ip/detection/person
 func (h *DetectionHandler) GetPersonDetection(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getPersonDetectionConfig", map[string]interface{}{
-		"people_detection": map[string]interface{}{
-			"name": []string{"detection"},
-		},
-	})
+	result, err := client.GetPersonDetection(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetPersonDetection sets person detection configuration
 // PUT /api/cameras/:ip/detection/person
 func (h *DetectionHandler) SetPersonDetection(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetPersonDetectionRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
-	enabled := "off"
-	if req.Enabled {
-		enabled = "on"
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	params := map[string]interface{}{
-		"people_detection": map[string]interface{}{
-			"detection": map[string]interface{}{
-				"enabled": enabled,
-			},
-		},
+	if err := client.SetPersonDetection(c.UserContext(), req.Enabled, req.Sensitivity); err != nil {
+		return executionError(c, err)
 	}
 
-	// Add sensitivity if specified
-	if req.Sensitivity > 0 {
-		params["people_detection"].(map[string]interface{})["detection"].(map[string]interface{})["sensitivity"] = formatNumber(req.Sensitivity)
-	}
-
-	client := tapo.NewClient(cameraIP, username, password)
-
-	result, err := client.Execute("setPersonDetectionConfig", params)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
-}
-
-// formatNumber converts an int to a string
-func formatNumber(n int) string {
-	return string([]byte{
-		byte('0' + n/100),
-		byte('0' + (n%100)/10),
-		byte('0' + n%10),
-	})
+	return response.OK(c, nil)
 }
@@ -2,95 +2,65 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // DeviceHandler handles device information operations
-type DeviceHandler struct{}
+type DeviceHandler struct {
+	pool *tapo.Pool
+}
 
 // NewDeviceHandler creates a new device handler
-func NewDeviceHandler() *DeviceHandler {
-	return &DeviceHandler{}
+func NewDeviceHandler(pool *tapo.Pool) *DeviceHandler {
+	return &DeviceHandler{pool: pool}
 }
 
 // GetInfo gets device basic information
 // GET /api/cameras/:ip/info
 func (h *DeviceHandler) GetInfo(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getDeviceInfo", map[string]interface{}{
-		"device_info": map[string]interface{}{
-			"name": []string{"basic_info"},
-		},
-	})
+	result, err := client.GetDeviceInfo(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // GetTime gets device clock status
 // GET /api/cameras/:ip/time
 func (h *DeviceHandler) GetTime(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getClockStatus", map[string]interface{}{
-		"system": map[string]string{
-			"name": "clock_status",
-		},
-	})
+	result, err := client.GetClockStatus(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // GetSpecs gets module specifications
 // GET /api/cameras/:ip/specs
 func (h *DeviceHandler) GetSpecs(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "get",
-		"function": map[string]interface{}{
-			"name": []string{"module_spec"},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
+	result, err := client.GetModuleSpec(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"sync"
+
+	"github.com/budhilaw/gotapo-api/internal/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DocsHandler serves the OpenAPI document of the API and its docs page
+type DocsHandler struct {
+	build   func() *openapi.Document
+	specURL string
+
+	once sync.Once
+	json []byte
+	yaml []byte
+	err  error
+}
+
+// NewDocsHandler creates a new docs handler. The document is built by
+// build on first use, once every route has been registered, and served at
+// specURL with a .json or .yaml extension.
+func NewDocsHandler(build func() *openapi.Document, specURL string) *DocsHandler {
+	return &DocsHandler{build: build, specURL: specURL}
+}
+
+// UI serves the interactive API documentation
+// GET /api
+func (h *DocsHandler) UI(c *fiber.Ctx) error {
+	c.Type("html")
+	return c.Send(openapi.UI(h.specURL))
+}
+
+// JSON serves the OpenAPI document as JSON
+// GET /api/openapi.json
+func (h *DocsHandler) JSON(c *fiber.Ctx) error {
+	if err := h.render(); err != nil {
+		return err
+	}
+
+	c.Type("json")
+	return c.Send(h.json)
+}
+
+// YAML serves the OpenAPI document as YAML
+// GET /api/openapi.yaml
+func (h *DocsHandler) YAML(c *fiber.Ctx) error {
+	if err := h.render(); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/yaml")
+	return c.Send(h.yaml)
+}
+
+// render builds and encodes the document once
+func (h *DocsHandler) render() error {
+	h.once.Do(func() {
+		doc := h.build()
+		if h.json, h.err = doc.JSON(); h.err != nil {
+			return
+		}
+		h.yaml, h.err = doc.YAML()
+	})
+	return h.err
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// errorStatus is the HTTP response for a kind of camera error
+type errorStatus struct {
+	kind      error
+	status    int
+	code      string
+	retryable bool
+}
+
+// errorStatuses maps camera errors to HTTP responses. The first matching
+// kind wins, so specific kinds come before the generic transport error.
+// Retryable errors are transient: the same call may succeed later.
+var errorStatuses = []errorStatus{
+	{tapo.ErrCertificateMismatch, fiber.StatusBadGateway, "certificate_mismatch", false},
+	{tapo.ErrLockedOut, fiber.StatusTooManyRequests, "login_locked_out", true},
+	{tapo.ErrRateLimited, fiber.StatusTooManyRequests, "rate_limited", true},
+	{tapo.ErrAuthentication, fiber.StatusUnauthorized, "camera_auth_failed", false},
+	{tapo.ErrSessionExpired, fiber.StatusUnauthorized, "session_expired", true},
+	{tapo.ErrPermission, fiber.StatusForbidden, "camera_forbidden", false},
+	{tapo.ErrNotFound, fiber.StatusNotFound, "not_found", false},
+	{tapo.ErrConflict, fiber.StatusConflict, "conflict", false},
+	{tapo.ErrInvalidParams, fiber.StatusBadRequest, "invalid_params", false},
+	{tapo.ErrUnsupported, fiber.StatusNotImplemented, "unsupported", false},
+	{tapo.ErrTimeout, fiber.StatusGatewayTimeout, "camera_timeout", true},
+	{context.DeadlineExceeded, fiber.StatusGatewayTimeout, "camera_timeout", true},
+	{tapo.ErrTransport, fiber.StatusBadGateway, "camera_unreachable", true},
+}
+
+// statusFor returns the HTTP response for err
+func statusFor(err error) errorStatus {
+	for _, s := range errorStatuses {
+		if errors.Is(err, s.kind) {
+			return s
+		}
+	}
+	return errorStatus{status: fiber.StatusInternalServerError, code: "execution_failed"}
+}
+
+// executionError writes the response for a failed camera call and logs it,
+// at warn level when the camera or its connection failed. Camera rate
+// limiting and login lockouts are reported as 429 with a Retry-After header
+// so clients back off instead of extending the suspension. Transport errors
+// quote the camera URL, so its session token is redacted from the message.
+func executionError(c *fiber.Ctx, err error) error {
+	s := statusFor(err)
+
+	e := response.Error{
+		Code:      s.code,
+		Message:   logging.RedactString(err.Error()),
+		Retryable: s.retryable,
+	}
+
+	var tapoErr *tapo.TapoError
+	if errors.As(err, &tapoErr) {
+		e.TapoCode = tapoErr.Code
+
+		if tapoErr.Suspended() {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(tapoErr.SecLeft))
+			e.RetryAfter = tapoErr.SecLeft
+		}
+	}
+
+	level := slog.LevelDebug
+	if s.status >= fiber.StatusInternalServerError {
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(c.UserContext(), level, "Camera request failed",
+		slog.String("code", e.Code), slog.Int("tapo_error_code", e.TapoCode), slog.Any("error", err))
+
+	return response.FailWith(c, s.status, e)
+}
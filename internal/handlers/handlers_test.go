@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -19,7 +25,7 @@ func mockAuthMiddleware(c *fiber.Ctx) error {
 
 func TestPTZHandler_Step_InvalidBody(t *testing.T) {
 	app := fiber.New()
-	handler := NewPTZHandler()
+	handler := NewPTZHandler(tapo.NewPool(0))
 
 	app.Post("/cameras/:ip/ptz/step", mockAuthMiddleware, handler.Step)
 
@@ -39,7 +45,7 @@ func TestPTZHandler_Step_InvalidBody(t *testing.T) {
 
 func TestPTZHandler_Step_InvalidDirection(t *testing.T) {
 	app := fiber.New()
-	handler := NewPTZHandler()
+	handler := NewPTZHandler(tapo.NewPool(0))
 
 	app.Post("/cameras/:ip/ptz/step", mockAuthMiddleware, handler.Step)
 
@@ -60,7 +66,7 @@ func TestPTZHandler_Step_InvalidDirection(t *testing.T) {
 
 func TestPresetsHandler_Create_EmptyName(t *testing.T) {
 	app := fiber.New()
-	handler := NewPresetsHandler()
+	handler := NewPresetsHandler(tapo.NewPool(0))
 
 	app.Post("/cameras/:ip/presets", mockAuthMiddleware, handler.Create)
 
@@ -81,7 +87,7 @@ func TestPresetsHandler_Create_EmptyName(t *testing.T) {
 
 func TestImageHandler_SetNightMode_InvalidMode(t *testing.T) {
 	app := fiber.New()
-	handler := NewImageHandler()
+	handler := NewImageHandler(tapo.NewPool(0))
 
 	app.Put("/cameras/:ip/image/nightmode", mockAuthMiddleware, handler.SetNightMode)
 
@@ -102,7 +108,7 @@ func TestImageHandler_SetNightMode_InvalidMode(t *testing.T) {
 
 func TestAudioHandler_SetSpeaker_InvalidVolume(t *testing.T) {
 	app := fiber.New()
-	handler := NewAudioHandler()
+	handler := NewAudioHandler(tapo.NewPool(0))
 
 	app.Put("/cameras/:ip/audio/speaker", mockAuthMiddleware, handler.SetSpeaker)
 
@@ -122,10 +128,183 @@ func TestAudioHandler_SetSpeaker_InvalidVolume(t *testing.T) {
 
 	// Check response body
 	respBody, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
+	var result response.Body
 	json.Unmarshal(respBody, &result)
 
-	if result["error"] != "invalid_volume" {
-		t.Errorf("Expected error=invalid_volume, got %v", result["error"])
+	if result.Success || result.Error == nil || result.Error.Code != "invalid_volume" {
+		t.Errorf("Expected error code invalid_volume, got %+v", result.Error)
+	}
+}
+
+func TestExecutionError_RateLimited(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		tapoErr := tapo.NewTapoError(tapo.ErrorCodeRateLimited, "Rate limited - temporary suspension")
+		tapoErr.SecLeft = 90
+		return executionError(c, fmt.Errorf("authentication failed: %w", tapoErr))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "90" {
+		t.Errorf("Expected Retry-After 90, got %q", resp.Header.Get("Retry-After"))
+	}
+
+	var body response.Body
+	json.NewDecoder(resp.Body).Decode(&body)
+	want := response.Error{
+		Code:       "rate_limited",
+		Message:    "authentication failed: Rate limited - temporary suspension",
+		Retryable:  true,
+		RetryAfter: 90,
+		TapoCode:   tapo.ErrorCodeRateLimited,
+	}
+	if body.Success || body.Error == nil || *body.Error != want {
+		t.Errorf("Expected error %+v, got %+v", want, body.Error)
+	}
+}
+
+func TestExecutionError_Generic(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return executionError(c, tapo.NewTapoError(tapo.ErrorCodeGeneral, "General error"))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	}
+}
+
+func TestExecutionError_RedactsToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		cause := errors.New(`Post "https://192.168.1.100/stok=0a1b2c3d/ds": connection reset by peer`)
+		return executionError(c, fmt.Errorf("%w: %w", tapo.ErrTransport, cause))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	var body response.Body
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error == nil || body.Error.Code != "camera_unreachable" {
+		t.Fatalf("Expected error code camera_unreachable, got %+v", body.Error)
+	}
+	if strings.Contains(body.Error.Message, "0a1b2c3d") || !strings.Contains(body.Error.Message, "stok=[REDACTED]/ds") {
+		t.Errorf("Expected the session token to be redacted, got %q", body.Error.Message)
+	}
+}
+
+func TestExecutionError_CertificateMismatch(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		mismatch := &tapo.CertificateMismatchError{Host: "192.168.1.100", Expected: "aa", Got: "bb"}
+		return executionError(c, fmt.Errorf("request failed: %w", mismatch))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", resp.StatusCode)
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{"bad credentials", fmt.Errorf("authentication failed: %w", tapo.NewTapoError(tapo.ErrorCodeInvalidAuth, "")), 401, "camera_auth_failed", false},
+		{"user not authorized", tapo.NewTapoError(tapo.ErrorCodeUserNotAuthorized, ""), 403, "camera_forbidden", false},
+		{"preset not found", tapo.NewTapoError(tapo.ErrorCodePresetNotFound, ""), 404, "not_found", false},
+		{"cruise in progress", tapo.NewTapoError(tapo.ErrorCodeCruiseInProgress, ""), 409, "conflict", false},
+		{"privacy mode", tapo.NewTapoError(tapo.ErrorCodePrivacyModeOn, ""), 409, "conflict", false},
+		{"rate limited", tapo.NewTapoError(tapo.ErrorCodeRateLimited, ""), 429, "rate_limited", true},
+		{"locked out", &tapo.TapoError{Code: tapo.ErrorCodeLoginRequired, SecLeft: 300, LockedOut: true}, 429, "login_locked_out", true},
+		{"invalid params", tapo.NewTapoError(tapo.ErrorCodeParamNotExist, ""), 400, "invalid_params", false},
+		{"unsupported", tapo.NewTapoError(tapo.ErrorCodeMethodNotExist, ""), 501, "unsupported", false},
+		{"unreachable", &tapo.TransportError{Op: "request failed", Err: errors.New("connection refused")}, 502, "camera_unreachable", true},
+		{"timeout", &tapo.TransportError{Op: "request failed", Err: context.DeadlineExceeded}, 504, "camera_timeout", true},
+		{"certificate mismatch", &tapo.TransportError{Op: "request failed", Err: &tapo.CertificateMismatchError{}}, 502, "certificate_mismatch", false},
+		{"unknown camera error", tapo.NewTapoError(-99999, ""), 500, "execution_failed", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statusFor(tt.err)
+			if got.status != tt.status || got.code != tt.code || got.retryable != tt.retryable {
+				t.Errorf("Expected %d %s retryable=%v, got %d %s retryable=%v", tt.status, tt.code, tt.retryable, got.status, got.code, got.retryable)
+			}
+		})
+	}
+}
+
+func TestBatchHandler_Execute_InvalidBatch(t *testing.T) {
+	app := fiber.New()
+	handler := NewBatchHandler(tapo.NewPool(0))
+
+	app.Post("/cameras/:ip/batch", mockAuthMiddleware, handler.Execute)
+
+	tooMany := make([]tapo.SingleRequest, maxBatchRequests+1)
+	for i := range tooMany {
+		tooMany[i].Method = "getLedStatus"
+	}
+
+	tests := []struct {
+		name     string
+		requests []tapo.SingleRequest
+	}{
+		{"empty", nil},
+		{"too many", tooMany},
+		{"missing method", []tapo.SingleRequest{{Method: "getLedStatus"}, {}}},
+	}
+
+	for _, tt := range tests {
+		body, _ := json.Marshal(BatchRequest{Requests: tt.requests})
+		req := httptest.NewRequest("POST", "/cameras/192.168.1.100/batch",
+			bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, resp.StatusCode)
+		}
+	}
+}
+
+func TestBatchCallResults(t *testing.T) {
+	results := batchCallResults([]tapo.BatchResult{
+		{Method: "getLedStatus", Result: json.RawMessage(`{"led":{}}`)},
+		{Method: "motorMoveToPreset", Err: tapo.NewTapoError(tapo.ErrorCodeCruiseInProgress, "Cruise in progress - stop cruise first")},
+	})
+
+	if !results[0].Success || string(results[0].Result) != `{"led":{}}` {
+		t.Errorf("Expected successful first result, got %+v", results[0])
+	}
+	if results[1].Success || results[1].ErrorCode != tapo.ErrorCodeCruiseInProgress {
+		t.Errorf("Expected failed second result with code %d, got %+v", tapo.ErrorCodeCruiseInProgress, results[1])
 	}
 }
@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // ImageHandler handles image and video settings operations
-type ImageHandler struct{}
+type ImageHandler struct {
+	pool *tapo.Pool
+}
 
 // NewImageHandler creates a new image handler
-func NewImageHandler() *ImageHandler {
-	return &ImageHandler{}
+func NewImageHandler(pool *tapo.Pool) *ImageHandler {
+	return &ImageHandler{pool: pool}
 }
 
 // SetFlipRequest represents an image flip request
@@ -27,110 +30,65 @@ type SetNightModeRequest struct {
 // GetSettings gets common image settings
 // GET /api/cameras/:ip/image
 func (h *ImageHandler) GetSettings(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getLdc", map[string]interface{}{
-		"image": map[string]interface{}{
-			"name": []string{"common", "switch"},
-		},
-	})
+	result, err := client.GetImageSettings(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetFlip sets image flip mode
 // PUT /api/cameras/:ip/image/flip
 func (h *ImageHandler) SetFlip(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetFlipRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	if req.FlipType == "" {
 		req.FlipType = "off"
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("setLdc", map[string]interface{}{
-		"image": map[string]interface{}{
-			"switch": map[string]string{
-				"flip_type": req.FlipType,
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.SetFlip(c.UserContext(), req.FlipType); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // SetNightMode sets day/night mode
 // PUT /api/cameras/:ip/image/nightmode
 func (h *ImageHandler) SetNightMode(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetNightModeRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	// Validate mode
 	validModes := map[string]bool{"auto": true, "on": true, "off": true}
 	if !validModes[req.Mode] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_mode",
-			"message": "Mode must be 'auto', 'on' (night), or 'off' (day)",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_mode", "Mode must be 'auto', 'on' (night), or 'off' (day)")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("setLdc", map[string]interface{}{
-		"image": map[string]interface{}{
-			"common": map[string]string{
-				"inf_type": req.Mode,
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.SetNightMode(c.UserContext(), req.Mode); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
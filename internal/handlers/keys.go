@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/auth"
+	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// KeysHandler manages API keys and issues bearer tokens
+type KeysHandler struct {
+	auth *auth.Service
+}
+
+// NewKeysHandler creates a new API keys handler
+func NewKeysHandler(svc *auth.Service) *KeysHandler {
+	return &KeysHandler{auth: svc}
+}
+
+// CreateKeyRequest represents a create API key request. Role is a
+// shorthand for a grant of that role on every camera.
+type CreateKeyRequest struct {
+	Name      string       `json:"name"`
+	Admin     bool         `json:"admin"`
+	Role      string       `json:"role,omitempty"`
+	Grants    []rbac.Grant `json:"grants,omitempty"`
+	ExpiresIn string       `json:"expires_in,omitempty"` // e.g. "720h"; empty never expires
+}
+
+// SetGrantsRequest represents a replace grants request
+type SetGrantsRequest struct {
+	Grants []rbac.Grant `json:"grants"`
+}
+
+// TokenRequest represents a token request
+type TokenRequest struct {
+	TTL string `json:"ttl,omitempty"` // e.g. "15m"; capped by the server maximum
+}
+
+// KeyResponse is an API key as returned by the API
+type KeyResponse struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Admin     bool         `json:"admin"`
+	Grants    []rbac.Grant `json:"grants"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
+
+// CreatedKeyResponse is a new API key with its secret, which is returned
+// only once
+type CreatedKeyResponse struct {
+	Key    KeyResponse `json:"key"`
+	Secret string      `json:"secret"`
+}
+
+// TokenResponse is an issued bearer token
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// newKeyResponse converts an API key for the API, leaving out its hash
+func newKeyResponse(key auth.APIKey) KeyResponse {
+	grants := key.Grants
+	if grants == nil {
+		grants = []rbac.Grant{}
+	}
+
+	return KeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Admin:     key.Admin,
+		Grants:    grants,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+	}
+}
+
+// List returns all API keys
+// GET /api/admin/keys
+func (h *KeysHandler) List(c *fiber.Ctx) error {
+	keys := []KeyResponse{}
+	for _, key := range h.auth.Keys().List() {
+		keys = append(keys, newKeyResponse(key))
+	}
+
+	return response.OK(c, keys)
+}
+
+// Create issues an API key. The secret is returned only in this response.
+// POST /api/admin/keys
+func (h *KeysHandler) Create(c *fiber.Ctx) error {
+	var req CreateKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return response.Fail(c, fiber.StatusBadRequest, "invalid_expiry", "expires_in must be a positive duration such as 720h")
+		}
+		t := time.Now().Add(d).UTC().Truncate(time.Second)
+		expiresAt = &t
+	}
+
+	grants := req.Grants
+	if req.Role != "" {
+		grants = append([]rbac.Grant{{Role: req.Role}}, grants...)
+	}
+
+	key, secret, err := h.auth.Keys().Create(req.Name, req.Admin, grants, expiresAt)
+	if err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_key", err.Error())
+	}
+
+	return response.Created(c, CreatedKeyResponse{
+		Key:    newKeyResponse(key),
+		Secret: secret,
+	})
+}
+
+// Delete revokes an API key and the tokens issued to it
+// DELETE /api/admin/keys/:id
+func (h *KeysHandler) Delete(c *fiber.Ctx) error {
+	if err := h.auth.Keys().Delete(c.Params("id")); err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			return response.Fail(c, fiber.StatusNotFound, "not_found", "API key not found")
+		}
+		return response.Fail(c, fiber.StatusInternalServerError, "delete_failed", err.Error())
+	}
+
+	return response.Message(c, "API key revoked")
+}
+
+// SetGrants replaces the camera permissions of an API key. Tokens already
+// issued to the key follow the new grants.
+// PUT /api/admin/keys/:id/grants
+func (h *KeysHandler) SetGrants(c *fiber.Ctx) error {
+	var req SetGrantsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	key, err := h.auth.Keys().SetGrants(c.Params("id"), req.Grants)
+	if err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			return response.Fail(c, fiber.StatusNotFound, "not_found", "API key not found")
+		}
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_key", err.Error())
+	}
+
+	return response.OK(c, newKeyResponse(key))
+}
+
+// IssueToken issues a bearer token for an API key
+// POST /api/admin/keys/:id/token
+func (h *KeysHandler) IssueToken(c *fiber.Ctx) error {
+	if _, ok := h.auth.Keys().Get(c.Params("id")); !ok {
+		return response.Fail(c, fiber.StatusNotFound, "not_found", "API key not found")
+	}
+
+	return h.issueToken(c, c.Params("id"))
+}
+
+// Token exchanges the caller's API key for a bearer token. Callers signed
+// in with a token are refused, so a token cannot be renewed past its expiry
+// without the key.
+// POST /api/auth/token
+func (h *KeysHandler) Token(c *fiber.Ctx) error {
+	p, _ := middleware.GetPrincipal(c)
+	if p.Method != "api_key" {
+		return response.Fail(c, fiber.StatusForbidden, "api_key_required", "Tokens are only issued in exchange for an API key")
+	}
+	return h.issueToken(c, p.KeyID)
+}
+
+// Whoami returns the authenticated API client
+// GET /api/auth/whoami
+func (h *KeysHandler) Whoami(c *fiber.Ctx) error {
+	p, _ := middleware.GetPrincipal(c)
+
+	return response.OK(c, p)
+}
+
+// issueToken writes a token for keyID with the TTL requested in the body
+func (h *KeysHandler) issueToken(c *fiber.Ctx, keyID string) error {
+	var req TokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+		}
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			return response.Fail(c, fiber.StatusBadRequest, "invalid_ttl", "ttl must be a positive duration such as 15m")
+		}
+		ttl = d
+	}
+
+	token, expiresAt, err := h.auth.IssueToken(keyID, ttl)
+	if err != nil {
+		return response.Fail(c, fiber.StatusUnauthorized, "unauthenticated", err.Error())
+	}
+
+	return response.OK(c, TokenResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt,
+	})
+}
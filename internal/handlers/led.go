@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // LEDHandler handles LED operations
-type LEDHandler struct{}
+type LEDHandler struct {
+	pool *tapo.Pool
+}
 
 // NewLEDHandler creates a new LED handler
-func NewLEDHandler() *LEDHandler {
-	return &LEDHandler{}
+func NewLEDHandler(pool *tapo.Pool) *LEDHandler {
+	return &LEDHandler{pool: pool}
 }
 
 // SetLEDRequest represents an LED configuration request
@@ -22,66 +25,35 @@ type SetLEDRequest struct {
 // GetStatus gets LED status
 // GET /api/cameras/:ip/led
 func (h *LEDHandler) GetStatus(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getLedStatus", map[string]interface{}{
-		"led": map[string]interface{}{
-			"name": []string{"config"},
-		},
-	})
+	result, err := client.GetLEDStatus(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // SetStatus sets LED enabled state
 // PUT /api/cameras/:ip/led
 func (h *LEDHandler) SetStatus(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req SetLEDRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
-	}
-
-	enabled := "off"
-	if req.Enabled {
-		enabled = "on"
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("setLedStatus", map[string]interface{}{
-		"led": map[string]interface{}{
-			"config": map[string]string{
-				"enabled": enabled,
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.SetLEDStatus(c.UserContext(), req.Enabled); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
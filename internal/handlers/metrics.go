@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"bytes"
+
+	"github.com/budhilaw/gotapo-api/internal/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// MetricsHandler serves the Prometheus metrics
+type MetricsHandler struct {
+	metrics *metrics.Metrics
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(m *metrics.Metrics) *MetricsHandler {
+	return &MetricsHandler{metrics: m}
+}
+
+// Serve writes the metrics in the Prometheus text format
+// GET /metrics
+func (h *MetricsHandler) Serve(c *fiber.Ctx) error {
+	var buf bytes.Buffer
+	if _, err := h.metrics.Registry().WriteTo(&buf); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, metrics.ContentType)
+	return c.Send(buf.Bytes())
+}
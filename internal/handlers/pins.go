@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PinsHandler manages trusted camera certificate pins
+type PinsHandler struct {
+	store tapo.PinStore
+	pool  *tapo.Pool
+}
+
+// NewPinsHandler creates a new certificate pins handler
+func NewPinsHandler(store tapo.PinStore, pool *tapo.Pool) *PinsHandler {
+	return &PinsHandler{store: store, pool: pool}
+}
+
+// List returns all pinned certificate fingerprints
+// GET /api/admin/pins
+func (h *PinsHandler) List(c *fiber.Ctx) error {
+	return response.OK(c, h.store.List())
+}
+
+// Get returns the pinned certificate fingerprint of a camera
+// GET /api/admin/pins/:host
+func (h *PinsHandler) Get(c *fiber.Ctx) error {
+	host, err := url.PathUnescape(c.Params("host"))
+	if err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_host", "Invalid camera host: "+err.Error())
+	}
+
+	pin, ok := h.store.Get(host)
+	if !ok {
+		return response.Fail(c, fiber.StatusNotFound, "not_found", "No certificate pinned for this camera")
+	}
+
+	return response.OK(c, pin)
+}
+
+// Reset removes the pin of a camera so its next certificate is trusted
+// DELETE /api/admin/pins/:host
+func (h *PinsHandler) Reset(c *fiber.Ctx) error {
+	host, err := url.PathUnescape(c.Params("host"))
+	if err != nil {
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_host", "Invalid camera host: "+err.Error())
+	}
+
+	if _, ok := h.store.Get(host); !ok {
+		return response.Fail(c, fiber.StatusNotFound, "not_found", "No certificate pinned for this camera")
+	}
+
+	if err := h.store.Delete(host); err != nil {
+		return response.Fail(c, fiber.StatusInternalServerError, "reset_failed", err.Error())
+	}
+
+	// Drop sessions whose connections were verified against the old pin
+	h.pool.EvictHost(host)
+
+	return response.Message(c, "Certificate pin reset")
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newPinsApp(t *testing.T) (*fiber.App, tapo.PinStore) {
+	t.Helper()
+
+	store, err := tapo.NewFilePinStore(filepath.Join(t.TempDir(), "pins.json"))
+	if err != nil {
+		t.Fatalf("NewFilePinStore failed: %v", err)
+	}
+	for _, host := range []string{"192.168.1.100", "[fe80::1]:8443"} {
+		if err := store.Set(tapo.Pin{Host: host, Fingerprint: "abcd"}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	handler := NewPinsHandler(store, tapo.NewPool(0))
+
+	app := fiber.New()
+	app.Get("/admin/pins", handler.List)
+	app.Get("/admin/pins/:host", handler.Get)
+	app.Delete("/admin/pins/:host", handler.Reset)
+
+	return app, store
+}
+
+func TestPinsHandler_List(t *testing.T) {
+	app, _ := newPinsApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/pins", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var result struct {
+		Result []tapo.Pin `json:"result"`
+	}
+	json.Unmarshal(respBody, &result)
+
+	if len(result.Result) != 2 || result.Result[0].Fingerprint != "abcd" {
+		t.Errorf("Expected two pins with fingerprint abcd, got %+v", result.Result)
+	}
+}
+
+func TestPinsHandler_Get(t *testing.T) {
+	app, _ := newPinsApp(t)
+
+	tests := []struct {
+		host   string
+		status int
+	}{
+		{"192.168.1.100", fiber.StatusOK},
+		{"192.168.1.101", fiber.StatusNotFound},
+		{"%5Bfe80::1%5D:8443", fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/admin/pins/"+tt.host, nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("Get(%s): expected status %d, got %d", tt.host, tt.status, resp.StatusCode)
+		}
+	}
+
+	// httptest.NewRequest rejects a bad escape, so send the path as is
+	req := httptest.NewRequest("GET", "/admin/pins/host", nil)
+	req.RequestURI = "/admin/pins/%zz"
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for a bad escape, got %d", resp.StatusCode)
+	}
+}
+
+func TestPinsHandler_Reset(t *testing.T) {
+	app, store := newPinsApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/admin/pins/192.168.1.100", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if _, ok := store.Get("192.168.1.100"); ok {
+		t.Error("Expected pin to be removed")
+	}
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/admin/pins/192.168.1.100", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown pin, got %d", resp.StatusCode)
+	}
+}
+
+func TestPinsHandler_Reset_EscapedHost(t *testing.T) {
+	app, store := newPinsApp(t)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/admin/pins/%5Bfe80::1%5D:8443", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if _, ok := store.Get("[fe80::1]:8443"); ok {
+		t.Error("Expected the pin of [fe80::1]:8443 to be removed")
+	}
+	if _, ok := store.Get("192.168.1.100"); !ok {
+		t.Error("Expected the other pin to be kept")
+	}
+}
@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // PresetsHandler handles preset operations
-type PresetsHandler struct{}
+type PresetsHandler struct {
+	pool *tapo.Pool
+}
 
 // NewPresetsHandler creates a new presets handler
-func NewPresetsHandler() *PresetsHandler {
-	return &PresetsHandler{}
+func NewPresetsHandler(pool *tapo.Pool) *PresetsHandler {
+	return &PresetsHandler{pool: pool}
 }
 
 // CreatePresetRequest represents a create preset request
@@ -22,128 +25,71 @@ type CreatePresetRequest struct {
 // List gets all presets
 // GET /api/cameras/:ip/presets
 func (h *PresetsHandler) List(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getPresetConfig", map[string]interface{}{
-		"preset": map[string]interface{}{
-			"name": []string{"preset"},
-		},
-	})
+	result, err := client.GetPresets(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // Create saves current position as a preset
 // POST /api/cameras/:ip/presets
 func (h *PresetsHandler) Create(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req CreatePresetRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_name",
-			"message": "Preset name is required",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_name", "Preset name is required")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	// Note: The API has a typo "addMotorPostion" - this is intentional
-	result, err := client.Execute("addMotorPostion", map[string]interface{}{
-		"preset": map[string]interface{}{
-			"set_preset": map[string]string{
-				"name":     req.Name,
-				"save_ptz": "1",
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	client := h.pool.Get(cameraIP, username, password)
+
+	if err := client.SavePreset(c.UserContext(), req.Name); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.Created(c, nil)
 }
 
 // Goto moves camera to a preset position
 // POST /api/cameras/:ip/presets/:id/goto
 func (h *PresetsHandler) Goto(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	presetID := c.Params("id")
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("motorMoveToPreset", map[string]interface{}{
-		"preset": map[string]interface{}{
-			"goto_preset": map[string]string{
-				"id": presetID,
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.GotoPreset(c.UserContext(), presetID); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // Delete removes a preset
 // DELETE /api/cameras/:ip/presets/:id
 func (h *PresetsHandler) Delete(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	presetID := c.Params("id")
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("deletePreset", map[string]interface{}{
-		"preset": map[string]interface{}{
-			"remove_preset": map[string][]string{
-				"id": {presetID},
-			},
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.DeletePreset(c.UserContext(), presetID); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // PTZHandler handles PTZ (Pan-Tilt-Zoom) operations
-type PTZHandler struct{}
+type PTZHandler struct {
+	pool *tapo.Pool
+}
 
 // NewPTZHandler creates a new PTZ handler
-func NewPTZHandler() *PTZHandler {
-	return &PTZHandler{}
+func NewPTZHandler(pool *tapo.Pool) *PTZHandler {
+	return &PTZHandler{pool: pool}
 }
 
 // MoveRequest represents a move to coordinates request
@@ -28,216 +31,105 @@ type StepRequest struct {
 // Move moves the camera to specific coordinates
 // POST /api/cameras/:ip/ptz/move
 func (h *PTZHandler) Move(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req MoveRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "do",
-		"motor": map[string]interface{}{
-			"move": map[string]string{
-				"x_coord": req.XCoord,
-				"y_coord": req.YCoord,
-			},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.MoveMotor(c.UserContext(), req.XCoord, req.YCoord); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // Step moves the camera in a direction
 // POST /api/cameras/:ip/ptz/step
 func (h *PTZHandler) Step(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
 	var req StepRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_request",
-			"message": "Invalid request body",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	// Validate direction (0-359)
 	if req.Direction < 0 || req.Direction > 359 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "invalid_direction",
-			"message": "Direction must be between 0 and 359",
-		})
+		return response.Fail(c, fiber.StatusBadRequest, "invalid_direction", "Direction must be between 0 and 359")
 	}
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "do",
-		"motor": map[string]interface{}{
-			"movestep": map[string]string{
-				"direction": string(rune('0'+req.Direction/100)) + string(rune('0'+(req.Direction%100)/10)) + string(rune('0'+req.Direction%10)),
-			},
-		},
+	if err := client.MoveMotorStep(c.UserContext(), req.Direction); err != nil {
+		return executionError(c, err)
 	}
 
-	// Properly format direction as string
-	payload["motor"].(map[string]interface{})["movestep"].(map[string]string)["direction"] = formatDirection(req.Direction)
-
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
-}
-
-// formatDirection formats direction as string
-func formatDirection(d int) string {
-	return string([]byte{
-		byte('0' + d/100),
-		byte('0' + (d%100)/10),
-		byte('0' + d%10),
-	})
+	return response.OK(c, nil)
 }
 
 // Calibrate starts motor calibration
 // POST /api/cameras/:ip/ptz/calibrate
 func (h *PTZHandler) Calibrate(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "do",
-		"motor": map[string]interface{}{
-			"manual_cali": "",
-		},
+	if err := client.CalibrateMotor(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // GetCapability gets motor capability info
 // GET /api/cameras/:ip/ptz/capability
 func (h *PTZHandler) GetCapability(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "get",
-		"motor": map[string]interface{}{
-			"name": []string{"capability"},
-		},
-	}
-
-	result, err := client.ExecuteDirect(payload)
+	result, err := client.GetMotorCapability(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // StartCruise starts cruise/patrol mode
 // POST /api/cameras/:ip/ptz/cruise/start
 func (h *PTZHandler) StartCruise(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	payload := map[string]interface{}{
-		"method": "do",
-		"motor": map[string]interface{}{
-			"cruise": map[string]string{
-				"coord": "0",
-			},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.StartCruise(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
 
 // StopCruise stops cruise/patrol mode
 // POST /api/cameras/:ip/ptz/cruise/stop
 func (h *PTZHandler) StopCruise(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "do",
-		"motor": map[string]interface{}{
-			"cruise_stop": map[string]interface{}{},
-		},
-	}
-
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.StopCruise(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
@@ -2,93 +2,64 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // RecordingHandler handles recording and storage operations
-type RecordingHandler struct{}
+type RecordingHandler struct {
+	pool *tapo.Pool
+}
 
 // NewRecordingHandler creates a new recording handler
-func NewRecordingHandler() *RecordingHandler {
-	return &RecordingHandler{}
+func NewRecordingHandler(pool *tapo.Pool) *RecordingHandler {
+	return &RecordingHandler{pool: pool}
 }
 
 // GetRecordPlan gets recording plan configuration
 // GET /api/cameras/:ip/recording/plan
 func (h *RecordingHandler) GetRecordPlan(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getRecordPlan", map[string]interface{}{
-		"record_plan": map[string]interface{}{
-			"name": []string{"chn1_channel"},
-		},
-	})
+	result, err := client.GetRecordPlan(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // GetStorageStatus gets SD card status
 // GET /api/cameras/:ip/storage
 func (h *RecordingHandler) GetStorageStatus(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("getSdCardStatus", map[string]interface{}{
-		"harddisk_manage": map[string]interface{}{
-			"table": []string{"hd_info"},
-		},
-	})
+	result, err := client.GetSDCardStatus(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, result)
 }
 
 // FormatStorage formats SD card
 // POST /api/cameras/:ip/storage/format
 func (h *RecordingHandler) FormatStorage(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("formatSdCard", map[string]interface{}{
-		"harddisk_manage": map[string]string{
-			"format_hd": "1",
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.FormatSDCard(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "SD card format initiated",
-		"result":  result,
-	})
+	return response.OK(c, nil)
 }
@@ -2,118 +2,63 @@ package handlers
 
 import (
 	"github.com/budhilaw/gotapo-api/internal/middleware"
-	"github.com/budhilaw/gotapo-api/internal/tapo"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
 // SystemHandler handles system operations
-type SystemHandler struct{}
+type SystemHandler struct {
+	pool *tapo.Pool
+}
 
 // NewSystemHandler creates a new system handler
-func NewSystemHandler() *SystemHandler {
-	return &SystemHandler{}
+func NewSystemHandler(pool *tapo.Pool) *SystemHandler {
+	return &SystemHandler{pool: pool}
 }
 
 // Reboot reboots the camera
 // POST /api/cameras/:ip/reboot
 func (h *SystemHandler) Reboot(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.Execute("rebootDevice", map[string]interface{}{
-		"system": map[string]string{
-			"reboot": "null",
-		},
-	})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.Reboot(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Camera reboot initiated",
-		"result":  result,
-	})
+	return response.Message(c, "Camera reboot initiated")
 }
 
 // GetFirmwareInfo checks for firmware updates
 // GET /api/cameras/:ip/firmware
 func (h *SystemHandler) GetFirmwareInfo(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
-
-	// Use multiple requests to check firmware and get upgrade info
-	request := tapo.MultipleRequest{
-		Method: "multipleRequest",
-		Params: tapo.MultipleReqParams{
-			Requests: []tapo.SingleRequest{
-				{
-					Method: "checkFirmwareVersionByCloud",
-					Params: map[string]interface{}{
-						"cloud_config": map[string]string{
-							"check_fw_version": "null",
-						},
-					},
-				},
-				{
-					Method: "getCloudConfig",
-					Params: map[string]interface{}{
-						"cloud_config": map[string]interface{}{
-							"name": []string{"upgrade_info"},
-						},
-					},
-				},
-			},
-		},
-	}
+	client := h.pool.Get(cameraIP, username, password)
 
-	result, err := client.ExecuteDirect(request)
+	results, err := client.CheckFirmwareUpdate(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"result":  result,
-	})
+	return response.OK(c, batchCallResults(results))
 }
 
 // StartFirmwareUpgrade starts firmware upgrade
 // POST /api/cameras/:ip/firmware/upgrade
 func (h *SystemHandler) StartFirmwareUpgrade(c *fiber.Ctx) error {
-	cameraIP := c.Params("ip")
+	cameraIP := middleware.GetCameraHost(c)
 	username, password := middleware.GetTapoCredentials(c)
 
-	client := tapo.NewClient(cameraIP, username, password)
+	client := h.pool.Get(cameraIP, username, password)
 
-	payload := map[string]interface{}{
-		"method": "do",
-		"cloud_config": map[string]string{
-			"fw_download": "null",
-		},
-	}
-
-	result, err := client.ExecuteDirect(payload)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "execution_failed",
-			"message": err.Error(),
-		})
+	if err := client.StartFirmwareUpgrade(c.UserContext()); err != nil {
+		return executionError(c, err)
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"message": "Firmware upgrade started",
-		"result":  result,
-	})
+	return response.Message(c, "Firmware upgrade started")
 }
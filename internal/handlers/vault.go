@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VaultHandler reports on and rotates the encryption of stored credentials
+type VaultHandler struct {
+	registry *registry.Registry
+}
+
+// NewVaultHandler creates a new credential vault handler
+func NewVaultHandler(registry *registry.Registry) *VaultHandler {
+	return &VaultHandler{registry: registry}
+}
+
+// RotateResponse reports a re-encryption of the stored passwords
+type RotateResponse struct {
+	Reencrypted int                  `json:"reencrypted"`
+	Status      registry.VaultStatus `json:"status"`
+}
+
+// Status returns the vault keys and how many passwords each one seals
+// GET /api/admin/vault
+func (h *VaultHandler) Status(c *fiber.Ctx) error {
+	return response.OK(c, h.registry.VaultStatus())
+}
+
+// Rotate re-encrypts every stored password with the active key; without a
+// vault key there is nothing to rotate to
+// POST /api/admin/vault/rotate
+func (h *VaultHandler) Rotate(c *fiber.Ctx) error {
+	n, err := h.registry.Rotate()
+	if errors.Is(err, registry.ErrNoVaultKey) {
+		return response.Fail(c, fiber.StatusConflict, "vault_not_configured", "No vault key is configured; set VAULT_KEY or VAULT_KEY_FILE")
+	}
+	if err != nil {
+		return response.Fail(c, fiber.StatusInternalServerError, "rotation_failed", err.Error())
+	}
+
+	return response.OK(c, RotateResponse{
+		Reencrypted: n,
+		Status:      h.registry.VaultStatus(),
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/vault"
+	"github.com/gofiber/fiber/v2"
+)
+
+func newVaultApp(t *testing.T, opts ...registry.Option) (*fiber.App, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cameras.json")
+	cameras, err := registry.Open(path, opts...)
+	if err != nil {
+		t.Fatalf("registry.Open failed: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/admin/vault/rotate", NewVaultHandler(cameras).Rotate)
+
+	return app, path
+}
+
+func TestVaultHandler_Rotate(t *testing.T) {
+	key, _ := vault.GenerateKey()
+	v, err := vault.Load(key, "")
+	if err != nil {
+		t.Fatalf("vault.Load failed: %v", err)
+	}
+
+	unconfigured, _ := newVaultApp(t)
+	configured, _ := newVaultApp(t, registry.WithVault(v))
+	// A directory in place of the temporary file makes the write fail
+	unwritable, path := newVaultApp(t, registry.WithVault(v))
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		app    *fiber.App
+		status int
+		code   string
+	}{
+		{"no vault key", unconfigured, fiber.StatusConflict, "vault_not_configured"},
+		{"vault key", configured, fiber.StatusOK, ""},
+		{"write failure", unwritable, fiber.StatusInternalServerError, "rotation_failed"},
+	}
+
+	for _, tt := range tests {
+		resp, err := tt.app.Test(httptest.NewRequest("POST", "/admin/vault/rotate", nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		var result struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal(respBody, &result)
+
+		if result.Error.Code != tt.code {
+			t.Errorf("%s: expected error code %q, got %q", tt.name, tt.code, result.Error.Code)
+		}
+	}
+}
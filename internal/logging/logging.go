@@ -0,0 +1,169 @@
+// Package logging builds the structured logger of the server. Records are
+// written as JSON or logfmt at the configured level, carry the attributes
+// attached to their context (request ID, camera) and have secrets redacted.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// Redacted replaces the value of secret attributes
+const Redacted = "[REDACTED]"
+
+// secretKeys are the attribute and header names whose values are never
+// logged, normalized by normalizeKey
+var secretKeys = map[string]bool{
+	"authorization": true,
+	"api_key":       true,
+	"x_api_key":     true,
+	"token":         true,
+	"access_token":  true,
+	"stok":          true,
+	"cookie":        true,
+	"set_cookie":    true,
+	"vault_key":     true,
+}
+
+// stokPattern matches the session token in camera URLs, which transport
+// errors include in their message
+var stokPattern = regexp.MustCompile(`(?i)(stok=)[^/\s"&]+`)
+
+// New returns a logger writing records of at least level (debug, info, warn
+// or error) to w in format (json or logfmt)
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatLogfmt, "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(NewContextHandler(handler)), nil
+}
+
+// ParseLevel parses a level name: debug, info, warn (or warning) or error
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
+// redact replaces the values of secret attributes, and session tokens in
+// string and error values, with Redacted
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSecret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// IsSecret reports whether values named key, an attribute or a header
+// name, must not be logged
+func IsSecret(key string) bool {
+	key = normalizeKey(key)
+	return secretKeys[key] || strings.Contains(key, "password") || strings.Contains(key, "secret")
+}
+
+// normalizeKey lowercases key and replaces dashes with underscores, so
+// X-Tapo-Password and x_tapo_password are the same key
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "-", "_")
+}
+
+// RedactString replaces session tokens in s with Redacted
+func RedactString(s string) string {
+	if !strings.Contains(strings.ToLower(s), "stok=") {
+		return s
+	}
+	return stokPattern.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// contextKey is the context key of the attributes added by With
+type contextKey struct{}
+
+// With returns a context whose log records carry attrs, in addition to the
+// attributes already attached to ctx
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// Attrs returns the attributes attached to ctx by With
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler adds the attributes attached to the context of a record
+// by With before passing it on
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h in a ContextHandler
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+// Handle implements slog.Handler
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return NewContextHandler(h.Handler.WithAttrs(attrs))
+}
+
+// WithGroup implements slog.Handler
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return NewContextHandler(h.Handler.WithGroup(name))
+}
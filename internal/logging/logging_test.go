@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"", slog.LevelInfo},
+		{"INFO", slog.LevelInfo},
+		{"warning", slog.LevelWarn},
+		{" error ", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.level)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseLevel(%q): expected %v, got %v (%v)", tt.level, tt.expected, got, err)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := With(context.Background(), slog.String("request_id", "abc"))
+	ctx = With(ctx, slog.String("camera", "front-door"))
+
+	logger.DebugContext(ctx, "Hidden")
+	logger.InfoContext(ctx, "Camera call",
+		"X-Tapo-Password", "hunter2",
+		slog.Group("headers", "Authorization", "Bearer xyz", "Accept", "*/*"),
+		"error", errors.New(`Post "https://10.0.0.1/stok=0a1b2c/ds": timeout`))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 record at info level, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", lines[0])
+	}
+
+	expected := map[string]interface{}{
+		"msg":             "Camera call",
+		"request_id":      "abc",
+		"camera":          "front-door",
+		"X-Tapo-Password": Redacted,
+		"error":           `Post "https://10.0.0.1/stok=[REDACTED]/ds": timeout`,
+	}
+	for key, want := range expected {
+		if record[key] != want {
+			t.Errorf("Expected %s %q, got %v", key, want, record[key])
+		}
+	}
+
+	headers, _ := record["headers"].(map[string]interface{})
+	if headers["Authorization"] != Redacted || headers["Accept"] != "*/*" {
+		t.Errorf("Expected only the Authorization header to be redacted, got %v", headers)
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	for _, key := range []string{"password", "X-Tapo-Password", "x_api_key", "Authorization", "jwt_secret", "stok", "Cookie"} {
+		if !IsSecret(key) {
+			t.Errorf("Expected %s to be secret", key)
+		}
+	}
+	for _, key := range []string{"camera", "key_id", "request_id", "X-Tapo-Username", "method"} {
+		if IsSecret(key) {
+			t.Errorf("Expected %s not to be secret", key)
+		}
+	}
+}
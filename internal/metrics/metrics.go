@@ -0,0 +1,157 @@
+// Package metrics exports Prometheus metrics of the API and of its camera
+// traffic. Metrics is a tapo.Observer; the HTTP side is fed by
+// middleware.Metrics.
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+)
+
+// Login outcomes
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginLockedOut          = "locked_out"
+	LoginRateLimited        = "rate_limited"
+	LoginError              = "error"
+)
+
+// Unregistered is the camera label of cameras missing from the registry,
+// so clients cannot add a series for every address they send
+const Unregistered = "unregistered"
+
+// maxMethodLength bounds the camera method label; longer or unusual
+// methods, which clients can choose through batches, are reported as other
+const maxMethodLength = 64
+
+// Metrics are the metrics of the API
+type Metrics struct {
+	registry *Registry
+
+	requests        *Counter
+	requestDuration *Histogram
+	callDuration    *Histogram
+	logins          *Counter
+	tapoErrors      *Counter
+
+	// cameraID returns the registry ID of a camera host
+	cameraID func(host string) (string, bool)
+}
+
+// New registers the metrics of the API in a new registry
+func New() *Metrics {
+	r := NewRegistry()
+
+	return &Metrics{
+		registry: r,
+		requests: r.Counter("gotapo_http_requests_total",
+			"HTTP requests answered, by method, route and status.",
+			"method", "route", "status"),
+		requestDuration: r.Histogram("gotapo_http_request_duration_seconds",
+			"Time taken to answer HTTP requests, by method and route.",
+			DefaultBuckets, "method", "route"),
+		callDuration: r.Histogram("gotapo_tapo_call_duration_seconds",
+			"Duration of camera calls including any login, by camera registry ID, method and outcome.",
+			DefaultBuckets, "camera", "method", "outcome"),
+		logins: r.Counter("gotapo_tapo_auth_attempts_total",
+			"Camera login attempts, by camera registry ID, protocol (secure or legacy) and outcome.",
+			"camera", "protocol", "outcome"),
+		tapoErrors: r.Counter("gotapo_tapo_errors_total",
+			"Errors reported by cameras, by camera registry ID and Tapo error code.",
+			"camera", "code"),
+	}
+}
+
+// Registry returns the registry holding the metrics
+func (m *Metrics) Registry() *Registry {
+	return m.registry
+}
+
+// SetCameraIDs labels the metrics of a camera with the registry ID lookup
+// returns for its host; other cameras are labelled Unregistered. It must
+// be called before cameras are observed.
+func (m *Metrics) SetCameraIDs(lookup func(host string) (id string, ok bool)) {
+	m.cameraID = lookup
+}
+
+// ObservePool exports the number of camera sessions held by pool
+func (m *Metrics) ObservePool(pool *tapo.Pool) {
+	m.registry.GaugeFunc("gotapo_tapo_pool_sessions",
+		"Camera clients held by the session pool.",
+		func() float64 { return float64(pool.Len()) })
+}
+
+// ObserveRequest records an answered HTTP request. route is the route
+// pattern, e.g. /api/cameras/:ip/led.
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	m.requests.Inc(method, route, strconv.Itoa(status))
+	m.requestDuration.Observe(elapsed.Seconds(), method, route)
+}
+
+// ObserveCall implements tapo.Observer. Errors of a login made for the
+// call are counted with the call.
+func (m *Metrics) ObserveCall(host, method string, elapsed time.Duration, err error) {
+	camera := m.cameraLabel(host)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.callDuration.Observe(elapsed.Seconds(), camera, methodLabel(method), outcome)
+
+	var tapoErr *tapo.TapoError
+	if errors.As(err, &tapoErr) {
+		m.tapoErrors.Inc(camera, strconv.Itoa(tapoErr.Code))
+	}
+}
+
+// ObserveLogin implements tapo.Observer
+func (m *Metrics) ObserveLogin(host, protocol string, err error) {
+	if protocol == "" {
+		protocol = "unknown"
+	}
+
+	outcome := LoginSuccess
+	switch {
+	case err == nil:
+	case errors.Is(err, tapo.ErrLockedOut):
+		outcome = LoginLockedOut
+	case errors.Is(err, tapo.ErrRateLimited):
+		outcome = LoginRateLimited
+	case errors.Is(err, tapo.ErrAuthentication):
+		outcome = LoginInvalidCredentials
+	default:
+		outcome = LoginError
+	}
+	m.logins.Inc(m.cameraLabel(host), protocol, outcome)
+}
+
+// cameraLabel returns the camera label of host
+func (m *Metrics) cameraLabel(host string) string {
+	if m.cameraID != nil {
+		if id, ok := m.cameraID(host); ok {
+			return id
+		}
+	}
+	return Unregistered
+}
+
+// methodLabel returns method as a label value, or other for methods that
+// are too long or have characters camera methods do not use
+func methodLabel(method string) string {
+	if method == "" || len(method) > maxMethodLength {
+		return "other"
+	}
+	for _, r := range method {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+		default:
+			return "other"
+		}
+	}
+	return method
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+)
+
+func TestMetrics_Observer(t *testing.T) {
+	m := New()
+	m.SetCameraIDs(func(host string) (string, bool) {
+		return "garage", host == "10.0.0.1"
+	})
+
+	m.ObserveCall("10.0.0.1", "getLedStatus", 20*time.Millisecond, nil)
+	m.ObserveCall("10.0.0.1", "motorMoveToPreset", time.Second, fmt.Errorf("failed: %w", tapo.NewTapoError(tapo.ErrorCodePresetNotFound, "")))
+	m.ObserveCall("10.0.0.1", "getLedStatus\nx", time.Millisecond, errors.New("connection refused"))
+
+	m.ObserveCall("203.0.113.7", "getLedStatus", time.Millisecond, nil)
+	m.ObserveCall("203.0.113.8", "getLedStatus", time.Millisecond, nil)
+
+	if got := m.callDuration.Count("garage", "getLedStatus", "success"); got != 1 {
+		t.Errorf("Expected 1 successful getLedStatus call, got %d", got)
+	}
+	if got := m.callDuration.Count(Unregistered, "getLedStatus", "success"); got != 2 {
+		t.Errorf("Expected unregistered cameras to share a series, got %d", got)
+	}
+	if got := m.callDuration.Count("garage", "other", "error"); got != 1 {
+		t.Errorf("Expected the unusual method to be reported as other, got %d", got)
+	}
+	if got := m.tapoErrors.Value("garage", "-64302"); got != 1 {
+		t.Errorf("Expected 1 -64302 error, got %v", got)
+	}
+
+	tests := []struct {
+		protocol string
+		err      error
+		outcome  string
+	}{
+		{tapo.ProtocolSecure, nil, LoginSuccess},
+		{tapo.ProtocolSecure, tapo.NewTapoError(tapo.ErrorCodeInvalidAuth, ""), LoginInvalidCredentials},
+		{tapo.ProtocolLegacy, tapo.NewTapoError(tapo.ErrorCodeRateLimited, ""), LoginRateLimited},
+		{tapo.ProtocolLegacy, &tapo.TapoError{Code: tapo.ErrorCodeLoginRequired, LockedOut: true}, LoginLockedOut},
+		{"", errors.New("connection refused"), LoginError},
+	}
+	for _, tt := range tests {
+		m.ObserveLogin("10.0.0.1", tt.protocol, tt.err)
+
+		protocol := tt.protocol
+		if protocol == "" {
+			protocol = "unknown"
+		}
+		if got := m.logins.Value("garage", protocol, tt.outcome); got != 1 {
+			t.Errorf("Expected 1 %s %s login, got %v", protocol, tt.outcome, got)
+		}
+	}
+}
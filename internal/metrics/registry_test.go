@@ -0,0 +1,323 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("test_requests_total", "Requests.\nBy path.", "path")
+	requests.Inc("/b")
+	requests.Add(2, `/a "quoted"`)
+
+	latency := r.Histogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(3)
+
+	r.GaugeFunc("test_sessions", "Sessions.", func() float64 { return 4 })
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	want := `# HELP test_requests_total Requests.\nBy path.
+# TYPE test_requests_total counter
+test_requests_total{path="/a \"quoted\""} 2
+test_requests_total{path="/b"} 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 2
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 3.15
+test_latency_seconds_count 3
+# HELP test_sessions Sessions.
+# TYPE test_sessions gauge
+test_sessions 4
+`
+	if b.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestRegistry_TextFormat(t *testing.T) {
+	r := NewRegistry()
+
+	values := []string{`back\slash`, `"quoted"`, "line\nbreak", "tab\tand space", "caf\u00e9 \u2028", "{a=\"b\",c}", "", "bad\xffbyte"}
+	calls := r.Counter("test_calls_total", "Calls with \\ and \"quotes\".\nSecond line.", "camera", "method")
+	latency := r.Histogram("test_latency_seconds", "Latency.", []float64{0.01, 0.1, 1}, "camera")
+	for i, v := range values {
+		calls.Add(float64(i+1), v, "getLedStatus")
+		for _, obs := range []float64{0.005, 0.01, 0.5, 7} {
+			latency.Observe(obs*float64(i+1), v)
+		}
+	}
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	families, err := parseTextFormat(b.String())
+	if err != nil {
+		t.Fatalf("Invalid text format: %v\n%s", err, b.String())
+	}
+
+	if help := families["test_calls_total"].help; help != "Calls with \\ and \"quotes\".\nSecond line." {
+		t.Errorf("Expected the help text to round-trip, got %q", help)
+	}
+
+	counters := map[string]float64{}
+	for _, s := range families["test_calls_total"].samples {
+		if s.name != "test_calls_total" || s.labels[1] != [2]string{"method", "getLedStatus"} {
+			t.Errorf("Unexpected sample %+v", s)
+		}
+		counters[s.labels[0][1]] = s.value
+	}
+	for i, v := range values {
+		want := strings.ToValidUTF8(v, "\uFFFD")
+		if counters[want] != float64(i+1) {
+			t.Errorf("Expected camera %q to round-trip with value %d, got %v", want, i+1, counters)
+		}
+	}
+
+	// Histograms: cumulative buckets in ascending order ending at +Inf,
+	// which equals the count, and le as the last label
+	type series struct {
+		le      []float64
+		buckets []float64
+		sum     float64
+		count   float64
+	}
+	histograms := map[string]*series{}
+	for _, s := range families["test_latency_seconds"].samples {
+		h := histograms[s.labels[0][1]]
+		if h == nil {
+			h = &series{}
+			histograms[s.labels[0][1]] = h
+		}
+		switch s.name {
+		case "test_latency_seconds_bucket":
+			last := s.labels[len(s.labels)-1]
+			le, err := strconv.ParseFloat(last[1], 64)
+			if last[0] != "le" || err != nil {
+				t.Fatalf("Expected le as the last label, got %v", s.labels)
+			}
+			h.le = append(h.le, le)
+			h.buckets = append(h.buckets, s.value)
+		case "test_latency_seconds_sum":
+			h.sum = s.value
+		case "test_latency_seconds_count":
+			h.count = s.value
+		default:
+			t.Errorf("Unexpected histogram sample %s", s.name)
+		}
+	}
+	if len(histograms) != len(values) {
+		t.Errorf("Expected %d histogram series, got %d", len(values), len(histograms))
+	}
+	for camera, h := range histograms {
+		if len(h.le) != 4 || !math.IsInf(h.le[3], 1) || h.buckets[3] != h.count || h.count != 4 {
+			t.Errorf("%q: expected 4 buckets ending at +Inf with the count, got le %v buckets %v count %v", camera, h.le, h.buckets, h.count)
+		}
+		for i := 1; i < len(h.le); i++ {
+			if h.le[i] <= h.le[i-1] || h.buckets[i] < h.buckets[i-1] {
+				t.Errorf("%q: expected ascending le and cumulative buckets, got le %v buckets %v", camera, h.le, h.buckets)
+			}
+		}
+		if h.sum <= 0 {
+			t.Errorf("%q: expected a positive sum, got %v", camera, h.sum)
+		}
+	}
+}
+
+// family is a metric family read from the text format
+type family struct {
+	help    string
+	kind    string
+	samples []sample
+}
+
+// sample is a sample line; labels keep their order
+type sample struct {
+	name   string
+	labels [][2]string
+	value  float64
+}
+
+var (
+	metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*`)
+	labelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// parseTextFormat reads the Prometheus text exposition format (version
+// 0.0.4) strictly: HELP and TYPE precede the samples of their family, the
+// samples of a family are contiguous, and label values and help texts use
+// only the escapes the format defines
+func parseTextFormat(text string) (map[string]*family, error) {
+	if !strings.HasSuffix(text, "\n") {
+		return nil, fmt.Errorf("missing final newline")
+	}
+
+	families := map[string]*family{}
+	var current string
+	for n, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("line %d %q: %s", n+1, line, fmt.Sprintf(format, args...))
+		}
+
+		if rest, ok := strings.CutPrefix(line, "# HELP "); ok {
+			name, help, _ := strings.Cut(rest, " ")
+			if families[name] != nil {
+				return nil, fail("family %s seen before", name)
+			}
+			text, err := unescape(help, false)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			families[name] = &family{help: text}
+			current = name
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, kind, _ := strings.Cut(rest, " ")
+			f := families[name]
+			if f == nil || name != current || f.kind != "" || len(f.samples) > 0 {
+				return nil, fail("TYPE out of place")
+			}
+			switch kind {
+			case "counter", "gauge", "histogram", "summary", "untyped":
+				f.kind = kind
+			default:
+				return nil, fail("unknown type %s", kind)
+			}
+			continue
+		}
+
+		name := metricName.FindString(line)
+		if name == "" {
+			return nil, fail("expected a metric name")
+		}
+		f := families[current]
+		if f == nil || f.kind == "" {
+			return nil, fail("sample before TYPE")
+		}
+		base := name
+		if f.kind == "histogram" {
+			for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+				base = strings.TrimSuffix(base, suffix)
+				if base != name {
+					break
+				}
+			}
+		}
+		if base != current {
+			return nil, fail("sample of %s inside family %s", name, current)
+		}
+
+		s := sample{name: name}
+		rest := line[len(name):]
+		if strings.HasPrefix(rest, "{") {
+			labels, after, err := parseLabels(rest[1:])
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			s.labels, rest = labels, after
+		}
+
+		value, ok := strings.CutPrefix(rest, " ")
+		if !ok {
+			return nil, fail("expected a space before the value")
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fail("invalid value: %v", err)
+		}
+		s.value = v
+		f.samples = append(f.samples, s)
+	}
+	return families, nil
+}
+
+// parseLabels reads the label pairs after "{" up to "}"
+func parseLabels(s string) ([][2]string, string, error) {
+	var labels [][2]string
+	for {
+		name := labelName.FindString(s)
+		if name == "" || !strings.HasPrefix(s[len(name):], `="`) {
+			return nil, "", fmt.Errorf("expected a label at %q", s)
+		}
+		s = s[len(name)+2:]
+
+		end := -1
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated value of label %s", name)
+		}
+		value, err := unescape(s[:end], true)
+		if err != nil {
+			return nil, "", err
+		}
+		labels = append(labels, [2]string{name, value})
+
+		s = s[end+1:]
+		switch {
+		case strings.HasPrefix(s, ","):
+			s = s[1:]
+		case strings.HasPrefix(s, "}"):
+			return labels, s[1:], nil
+		default:
+			return nil, "", fmt.Errorf("expected , or } at %q", s)
+		}
+	}
+}
+
+// unescape reads a help text or, with quotes, a label value: \\ and \n
+// are the only escapes, plus \" in label values, and the text is UTF-8
+func unescape(s string, quotes bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n' || (quotes && c == '"'):
+			return "", fmt.Errorf("unescaped %q in %q", c, s)
+		case c != '\\':
+			b.WriteByte(c)
+		case i+1 == len(s):
+			return "", fmt.Errorf("trailing backslash in %q", s)
+		default:
+			i++
+			switch s[i] {
+			case '\\':
+				b.WriteByte('\\')
+			case 'n':
+				b.WriteByte('\n')
+			case '"':
+				if !quotes {
+					b.WriteString(`\"`)
+					continue
+				}
+				b.WriteByte('"')
+			default:
+				return "", fmt.Errorf("unknown escape \\%c in %q", s[i], s)
+			}
+		}
+	}
+	if !utf8.ValidString(b.String()) {
+		return "", fmt.Errorf("invalid UTF-8 in %q", s)
+	}
+	return b.String(), nil
+}
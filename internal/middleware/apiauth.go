@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/budhilaw/gotapo-api/internal/auth"
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHeader carries an API key as an alternative to a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// APIAuth authenticates API clients with an API key (X-API-Key header or
+// bearer token) or a JWT bearer token
+func APIAuth(svc *auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		credential := c.Get(APIKeyHeader)
+		if credential == "" {
+			if scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " "); ok && strings.EqualFold(scheme, "Bearer") {
+				credential = strings.TrimSpace(token)
+			}
+		}
+
+		p, err := svc.Authenticate(credential)
+		if err != nil {
+			message := "Missing or invalid API key or bearer token"
+			if errors.Is(err, auth.ErrTokenExpired) {
+				message = "API key or bearer token expired"
+			}
+
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="gotapo-api"`)
+			return response.Fail(c, fiber.StatusUnauthorized, "unauthenticated", message)
+		}
+
+		c.Locals("principal", p)
+		c.SetUserContext(logging.With(c.UserContext(), slog.String("key_id", p.KeyID)))
+
+		return c.Next()
+	}
+}
+
+// RequireAdmin rejects clients not authenticated with an admin key
+func RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if p, ok := GetPrincipal(c); !ok || !p.Admin {
+			return response.Fail(c, fiber.StatusForbidden, "forbidden", "Admin API key required")
+		}
+
+		return c.Next()
+	}
+}
+
+// AuthDisabled rejects every request. It guards the admin routes when API
+// authentication is disabled, since no client can then prove it is an
+// admin.
+func AuthDisabled() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return response.Fail(c, fiber.StatusForbidden, "auth_disabled", "Enable API_AUTH and use an admin API key for this route")
+	}
+}
+
+// GetPrincipal retrieves the authenticated API client from context
+func GetPrincipal(c *fiber.Ctx) (auth.Principal, bool) {
+	p, ok := c.Locals("principal").(auth.Principal)
+	return p, ok
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Audit records every request that may change a camera or server state,
+// that is every request other than GET, HEAD and OPTIONS, once it has been
+// answered. It runs after APIAuth, so requests without valid credentials
+// are not recorded.
+func Audit(trail *audit.Log) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		start := time.Now()
+		request := audit.Body(c.Body())
+
+		err := c.Next()
+
+		// Request strings are only valid until the response is sent
+		entry := audit.Entry{
+			Time:       start,
+			Actor:      audit.Actor{IP: utils.CopyString(c.IP())},
+			Action:     utils.CopyString(actionName(c)),
+			Method:     utils.CopyString(c.Method()),
+			Path:       utils.CopyString(c.Path()),
+			RequestID:  utils.CopyString(GetRequestID(c)),
+			Request:    request,
+			Status:     responseStatus(c, err),
+			Response:   audit.Body(c.Response().Body()),
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+
+		if p, ok := GetPrincipal(c); ok {
+			entry.Actor.KeyID = p.KeyID
+			entry.Actor.Name = p.Name
+			entry.Actor.Method = p.Method
+		}
+
+		if ops, ok := c.Locals("audit_operations").([]string); ok {
+			entry.Operations = ops
+		}
+
+		if cam, ok := GetCamera(c); ok {
+			entry.Camera = cam.ID
+		}
+		if host, ok := c.Locals("camera_host").(string); ok {
+			entry.Address = utils.CopyString(host)
+		}
+
+		if err != nil {
+			entry.Error = err.Error()
+		} else if entry.Status >= fiber.StatusBadRequest {
+			var body response.Body
+			if json.Unmarshal(c.Response().Body(), &body) == nil && body.Error != nil {
+				entry.Error = body.Error.Code
+			}
+		}
+		entry.Outcome = audit.OutcomeFor(entry.Status)
+
+		if _, recErr := trail.Record(entry); recErr != nil {
+			slog.ErrorContext(c.UserContext(), "Failed to record audit entry",
+				"method", entry.Method, "path", entry.Path, "error", recErr)
+		}
+
+		return err
+	}
+}
+
+// SetAuditOperations records the camera methods run by a request, such as
+// the methods of a batch, in its audit entry
+func SetAuditOperations(c *fiber.Ctx, methods []string) {
+	c.Locals("audit_operations", methods)
+}
+
+// actionName returns the name of the route that answered the request, or
+// its method and path when it was answered before reaching a named route
+func actionName(c *fiber.Ctx) string {
+	if name := c.Route().Name; name != "" {
+		return name
+	}
+	return c.Method() + " " + c.Path()
+}
@@ -1,20 +1,33 @@
 package middleware
 
 import (
+	"github.com/budhilaw/gotapo-api/internal/response"
 	"github.com/gofiber/fiber/v2"
 )
 
-// TapoCredentials extracts Tapo camera credentials from request headers
-func TapoCredentials() fiber.Handler {
+// TapoCredentials extracts Tapo camera credentials from request headers.
+// For a registered camera it falls back to the credentials stored for the
+// camera and then to defaultUsername and defaultPassword. Other addresses
+// must send credentials, so they are never handed to an arbitrary host.
+func TapoCredentials(defaultUsername, defaultPassword string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		username := c.Get("X-Tapo-Username")
 		password := c.Get("X-Tapo-Password")
 
 		if username == "" || password == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error":   "unauthorized",
-				"message": "Missing X-Tapo-Username or X-Tapo-Password headers",
-			})
+			cam, ok := GetCamera(c)
+			if !ok {
+				return response.Fail(c, fiber.StatusUnauthorized, "unauthorized", "Missing X-Tapo-Username or X-Tapo-Password headers; stored and default credentials apply only to registered cameras")
+			}
+
+			username, password = defaultUsername, defaultPassword
+			if cam.Username != "" {
+				username, password = cam.Username, cam.Password
+			}
+		}
+
+		if username == "" || password == "" {
+			return response.Fail(c, fiber.StatusUnauthorized, "unauthorized", "Missing X-Tapo-Username or X-Tapo-Password headers and no stored or default credentials")
 		}
 
 		c.Locals("tapo_username", username)
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/url"
+
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// CameraHost resolves the :ip route parameter to a camera address. The
+// parameter may be the ID of a camera in cameras (nil disables the
+// registry) or an address: a host name, an IPv4 address or an IPv6 address
+// (with or without brackets), optionally followed by a port.
+func CameraHost(cameras *registry.Registry) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		raw, err := url.PathUnescape(c.Params("ip"))
+		if err != nil {
+			raw = c.Params("ip")
+		}
+
+		if cameras != nil {
+			if cam, ok := cameras.Resolve(raw); ok {
+				c.Locals("camera", cam)
+				c.Locals("camera_host", cam.Address)
+				response.SetCamera(c, cam.ID)
+				c.SetUserContext(logging.With(c.UserContext(),
+					slog.String("camera", cam.ID), slog.String("camera_host", cam.Address)))
+				return c.Next()
+			}
+		}
+
+		host, err := tapo.NormalizeAddress(raw)
+		if err != nil {
+			return response.Fail(c, fiber.StatusBadRequest, "invalid_camera", "Unknown camera ID or invalid address: "+err.Error())
+		}
+		host = utils.CopyString(host)
+		c.Locals("camera_host", host)
+		response.SetCamera(c, host)
+		c.SetUserContext(logging.With(c.UserContext(), slog.String("camera", host)))
+
+		return c.Next()
+	}
+}
+
+// GetCameraHost retrieves the camera address from context
+func GetCameraHost(c *fiber.Ctx) string {
+	if host, ok := c.Locals("camera_host").(string); ok {
+		return host
+	}
+	return c.Params("ip")
+}
+
+// GetCamera retrieves the registered camera from context, if the route
+// refers to one
+func GetCamera(c *fiber.Ctx) (registry.Camera, bool) {
+	cam, ok := c.Locals("camera").(registry.Camera)
+	return cam, ok
+}
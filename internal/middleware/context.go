@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestContext attaches a per-request context derived from base to the
+// Fiber context. Handlers pass c.UserContext() to camera calls so that a
+// server shutdown (cancelling base) aborts in-flight requests. It replaces
+// the user context, so it must run before middleware adding log attributes.
+func RequestContext(base context.Context) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(base)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+
+		return c.Next()
+	}
+}
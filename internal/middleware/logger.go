@@ -1,15 +1,73 @@
 package middleware
 
 import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
-// Logger returns a configured logger middleware
+// Logger logs every request once answered, with the attributes of its
+// context such as the request ID and camera: at error level for 5xx
+// responses, warn for 4xx and info otherwise. At debug level the request
+// headers are logged too; the logger redacts secrets such as
+// X-Tapo-Password and X-API-Key.
 func Logger() fiber.Handler {
-	return logger.New(logger.Config{
-		Format:     "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path}\n",
-		TimeFormat: "2006-01-02 15:04:05",
-		TimeZone:   "Local",
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := responseStatus(c, err)
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		ctx := c.UserContext()
+		logger := slog.Default()
+		if !logger.Enabled(ctx, level) {
+			return err
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.IP()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Attr{Key: "headers", Value: headerAttrs(c)})
+		}
+		logger.LogAttrs(ctx, level, "Request", attrs...)
+
+		return err
+	}
+}
+
+// headerAttrs returns the request headers as a group value
+func headerAttrs(c *fiber.Ctx) slog.Value {
+	var attrs []slog.Attr
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		attrs = append(attrs, slog.String(string(key), string(value)))
 	})
+	return slog.GroupValue(attrs...)
+}
+
+// LogPanic logs a panic recovered while answering c with its stack trace;
+// it is the StackTraceHandler of the recover middleware
+func LogPanic(c *fiber.Ctx, e interface{}) {
+	slog.ErrorContext(c.UserContext(), "Panic recovered",
+		"panic", fmt.Sprint(e), "stack", string(debug.Stack()))
 }
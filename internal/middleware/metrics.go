@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records the count and duration of HTTP requests by method, route
+// pattern and status. Requests rejected before reaching a route, such as
+// unauthenticated ones, are labelled with the pattern of the group that
+// rejected them.
+func Metrics(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		m.ObserveRequest(c.Method(), c.Route().Path, responseStatus(c, err), time.Since(start))
+
+		return err
+	}
+}
+
+// responseStatus returns the status of the response to c, given the error
+// returned by the handlers after the middleware
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	// Answered by the app's error handler after the middleware returns
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// maxRequestIDLength bounds the length of a request ID sent by a client
+const maxRequestIDLength = 64
+
+// RequestID sets the X-Request-ID response header to the ID sent by the
+// client, or to a new UUID when the client sent none or an ID that is too
+// long or has characters other than letters, digits, '.', '_' and '-'.
+// Response bodies and logs carry the same ID: it is attached to the user
+// context, so install RequestID after RequestContext.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if validRequestID(id) {
+			id = utils.CopyString(id)
+		} else {
+			id = utils.UUIDv4()
+		}
+
+		c.Set(fiber.HeaderXRequestID, id)
+		c.SetUserContext(logging.With(c.UserContext(), slog.String("request_id", id)))
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the request
+func GetRequestID(c *fiber.Ctx) string {
+	return c.GetRespHeader(fiber.HeaderXRequestID)
+}
+
+// validRequestID reports whether a client's request ID may be used as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
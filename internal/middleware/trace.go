@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TraceHeader enables protocol tracing for a single request of an admin
+const TraceHeader = "X-Tapo-Trace"
+
+// Trace logs the camera protocol trace of requests to the cameras listed in
+// cameras by address or registry ID ("*" traces all), and of requests of
+// admin API clients sending a true X-Tapo-Trace header; the header is
+// ignored for other clients and when API authentication is disabled.
+// Events are logged at info level with the attributes of the request
+// context; secrets are redacted by the client before logging.
+func Trace(cameras []string) fiber.Handler {
+	traced := make(map[string]bool, len(cameras))
+	for _, camera := range cameras {
+		traced[camera] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		enabled, _ := strconv.ParseBool(c.Get(TraceHeader))
+		if enabled {
+			p, ok := GetPrincipal(c)
+			enabled = ok && p.Admin
+		}
+
+		cam, _ := GetCamera(c)
+		if enabled || traced["*"] || traced[GetCameraHost(c)] || traced[cam.ID] {
+			ctx := c.UserContext()
+			c.SetUserContext(tapo.ContextWithTracer(ctx, func(ev tapo.TraceEvent) {
+				logTrace(ctx, ev)
+			}))
+		}
+
+		return c.Next()
+	}
+}
+
+// logTrace writes a trace event to the default logger
+func logTrace(ctx context.Context, ev tapo.TraceEvent) {
+	attrs := []slog.Attr{
+		slog.String("host", ev.Host),
+		slog.String("phase", ev.Phase),
+	}
+	if ev.Seq != 0 {
+		attrs = append(attrs, slog.Int("seq", ev.Seq))
+	}
+	if ev.Elapsed > 0 {
+		attrs = append(attrs, slog.Float64("elapsed_ms", float64(ev.Elapsed.Microseconds())/1000))
+	}
+	if ev.Message != "" {
+		attrs = append(attrs, slog.String("message", ev.Message))
+	}
+	if len(ev.Payload) > 0 {
+		attrs = append(attrs, slog.String("payload", string(ev.Payload)))
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, slog.Any("error", ev.Err))
+	}
+	slog.LogAttrs(ctx, slog.LevelInfo, "Camera trace", attrs...)
+}
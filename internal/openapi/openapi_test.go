@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testCamera struct {
+	ID      string          `json:"id"`
+	Name    *string         `json:"name,omitempty"`
+	Tags    []string        `json:"tags"`
+	Seen    time.Time       `json:"seen"`
+	Extra   json.RawMessage `json:"extra,omitempty"`
+	Labels  map[string]int  `json:"labels,omitempty"`
+	Parent  *testCamera     `json:"parent,omitempty"`
+	Secret  string          `json:"-"`
+	private string
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Zone string `json:"zone"`
+}
+
+func TestDocument_Schema(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+
+	ref := doc.Schema([]testCamera{})
+	if ref.Type != "array" || ref.Items.Ref != "#/components/schemas/testCamera" {
+		t.Fatalf("Expected an array of testCamera references, got %+v", ref)
+	}
+
+	s := doc.Components.Schemas["testCamera"]
+	want := map[string]Schema{
+		"id":     {Type: "string"},
+		"tags":   {Type: "array", Items: &Schema{Type: "string"}},
+		"seen":   {Type: "string", Format: "date-time"},
+		"extra":  {},
+		"labels": {Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int32"}},
+		"parent": {Ref: "#/components/schemas/testCamera"},
+		"name":   {Type: "string"},
+		"zone":   {Type: "string"},
+	}
+	if len(s.Properties) != len(want) {
+		t.Errorf("Expected %d properties, got %d: %v", len(want), len(s.Properties), s.Properties)
+	}
+	for name, w := range want {
+		if got := s.Properties[name]; got == nil || !reflect.DeepEqual(*got, w) {
+			t.Errorf("Property %s: expected %+v, got %+v", name, w, got)
+		}
+	}
+
+	if !reflect.DeepEqual(s.Required, []string{"id", "tags", "seen", "zone"}) {
+		t.Errorf("Expected required [id tags seen zone], got %v", s.Required)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := map[string]string{
+		"/health":                           "/health",
+		"/api/admin/keys/":                  "/api/admin/keys",
+		"/api/cameras/:ip/presets/:id":      "/api/cameras/{ip}/presets/{id}",
+		"/api/cameras/:ip/presets/:id/goto": "/api/cameras/{ip}/presets/{id}/goto",
+		"/":                                 "/",
+	}
+
+	for in, want := range tests {
+		if got := Path(in); got != want {
+			t.Errorf("Path(%q): expected %q, got %q", in, want, got)
+		}
+	}
+
+	if got := PathParams("/api/cameras/{ip}/presets/{id}"); !reflect.DeepEqual(got, []string{"ip", "id"}) {
+		t.Errorf("Expected [ip id], got %v", got)
+	}
+}
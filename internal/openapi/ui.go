@@ -0,0 +1,17 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+)
+
+//go:embed ui.html
+var ui []byte
+
+// UI returns the docs page rendering the document served at specURL with
+// a .json extension (and .yaml for the YAML link)
+func UI(specURL string) []byte {
+	quoted, _ := json.Marshal(specURL)
+	return bytes.Replace(ui, []byte("{{SPEC_URL}}"), quoted, 1)
+}
@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// plainKey matches the mapping keys written without quotes. Keys that YAML
+// could read as another type, like status codes, are quoted.
+var plainKey = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_.$-]*$`)
+
+// node is a parsed JSON value with object keys kept in order
+type node struct {
+	keys   []string
+	values []*node
+	items  []*node
+	scalar json.RawMessage
+	kind   byte // '{', '[' or 0 for scalars
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping key order. Strings
+// are written as double-quoted scalars, whose escapes JSON shares.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := parseNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeNode(&buf, root, 0, false)
+	return buf.Bytes(), nil
+}
+
+// parseNode reads the next JSON value from dec
+func parseNode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &node{kind: byte(tok)}
+		for dec.More() {
+			if n.kind == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+
+			child, err := parseNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if n.kind == '{' {
+				n.values = append(n.values, child)
+			} else {
+				n.items = append(n.items, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case nil:
+		return &node{scalar: json.RawMessage("null")}, nil
+	case json.Number:
+		return &node{scalar: json.RawMessage(tok.String())}, nil
+	default:
+		raw, err := json.Marshal(tok)
+		if err != nil {
+			return nil, err
+		}
+		return &node{scalar: raw}, nil
+	}
+}
+
+// empty reports whether n is an empty object or array, written inline
+func (n *node) empty() bool {
+	return n.kind != 0 && len(n.keys) == 0 && len(n.items) == 0
+}
+
+// writeNode writes n at indent. inline is set when n follows a "- " or
+// "key: " on the current line.
+func writeNode(buf *bytes.Buffer, n *node, indent int, inline bool) {
+	pad := strings.Repeat("  ", indent)
+
+	switch {
+	case n.kind == 0:
+		buf.Write(n.scalar)
+		buf.WriteByte('\n')
+	case n.kind == '{' && len(n.keys) == 0:
+		buf.WriteString("{}\n")
+	case n.kind == '[' && len(n.items) == 0:
+		buf.WriteString("[]\n")
+	case n.kind == '{':
+		for i, key := range n.keys {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(yamlKey(key))
+			buf.WriteByte(':')
+
+			child := n.values[i]
+			if child.kind == 0 || child.empty() {
+				buf.WriteByte(' ')
+				writeNode(buf, child, indent+1, true)
+			} else {
+				buf.WriteByte('\n')
+				writeNode(buf, child, indent+1, false)
+			}
+		}
+	case n.kind == '[':
+		for i, item := range n.items {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString("- ")
+			writeNode(buf, item, indent+1, true)
+		}
+	}
+}
+
+// yamlKey returns key as a YAML mapping key
+func yamlKey(key string) string {
+	if plainKey.MatchString(key) && !reservedWord(key) {
+		return key
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+// reservedWord reports whether YAML reads s as a boolean or null
+func reservedWord(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSONToYAML(t *testing.T) {
+	in := `{
+		"openapi": "3.0.3",
+		"paths": {
+			"/api/{ip}": {"get": {"responses": {"200": {"$ref": "#/r"}}}}
+		},
+		"tags": [{"name": "PTZ", "x": [1, true, null]}, "on"],
+		"empty": {},
+		"none": [],
+		"text": "line\nbreak: \"quoted\""
+	}`
+
+	want := `openapi: "3.0.3"
+paths:
+  "/api/{ip}":
+    get:
+      responses:
+        "200":
+          $ref: "#/r"
+tags:
+  - name: "PTZ"
+    x:
+      - 1
+      - true
+      - null
+  - "on"
+empty: {}
+none: []
+text: "line\nbreak: \"quoted\""
+`
+
+	got, err := jsonToYAML([]byte(in))
+	if err != nil {
+		t.Fatalf("jsonToYAML failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestJSONToYAML_RoundTrip(t *testing.T) {
+	tricky := []string{
+		"key: value", "a:b", "trailing:", "# comment", "value # comment", "- item", "-",
+		"? key", "[list]", "{map}", "*alias", "&anchor", "!tag", "|", ">", "%directive",
+		"@at", "`tick", "'single'", `"double"`, "yes", "No", "ON", "off", "y", "N",
+		"true", "False", "null", "~", "", " padded ", "123", "-1.5", "1e3", "0x1F",
+		"1_000", "1:30", ".inf", ".NaN", "inf", "NaN", "2025-01-01", "$ref", "x-extension", "tab\there",
+		"line\nbreak", "back\\slash", "<html> & 'quotes'", "caf\u00e9", "\u2028",
+	}
+
+	keys := map[string]interface{}{}
+	var values []interface{}
+	for i, s := range tricky {
+		keys[s] = s
+		values = append(values, s, map[string]interface{}{s: []interface{}{s, i}})
+	}
+	doc := map[string]interface{}{
+		"keys":   keys,
+		"values": values,
+		"nested": []interface{}{[]interface{}{"- a", []interface{}{}}, map[string]interface{}{}},
+	}
+
+	data, _ := json.Marshal(doc)
+	out, err := jsonToYAML(data)
+	if err != nil {
+		t.Fatalf("jsonToYAML failed: %v", err)
+	}
+
+	got, err := readYAML(string(out))
+	if err != nil {
+		t.Fatalf("Invalid YAML: %v\n%s", err, out)
+	}
+	var want interface{}
+	json.Unmarshal(data, &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip changed the document:\n%s", out)
+	}
+}
+
+func TestReadYAML(t *testing.T) {
+	// The test reader resolves plain scalars as YAML does
+	if v, err := readYAML("a: yes\nb: ~\nc: 1.5\n"); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": true, "b": nil, "c": 1.5}) {
+		t.Errorf("Expected plain scalars resolved, got %v %v", v, err)
+	}
+	for _, doc := range []string{"no: 1\n", "a: b: c\n", "a: # c\n", "a: 'b\n", "a: 2025-01-01\n"} {
+		if v, err := readYAML(doc); err == nil {
+			t.Errorf("Expected %q to be rejected, got %v", doc, v)
+		}
+	}
+}
+
+// readYAML parses the block YAML written by jsonToYAML: two-space indented
+// mappings and sequences of flow scalars. Plain scalars are resolved as
+// YAML 1.1 does, so a string written without the quotes it needs is read
+// back with another type or rejected.
+func readYAML(doc string) (interface{}, error) {
+	r := &yamlReader{lines: strings.Split(strings.TrimSuffix(doc, "\n"), "\n")}
+	v, err := r.block(0)
+	if err == nil && r.pos < len(r.lines) {
+		err = fmt.Errorf("line %d: unexpected %q", r.pos+1, r.lines[r.pos])
+	}
+	return v, err
+}
+
+type yamlReader struct {
+	lines []string
+	pos   int
+}
+
+// at reports whether the current line is indented by indent
+func (r *yamlReader) at(indent int) bool {
+	if r.pos >= len(r.lines) {
+		return false
+	}
+	line := r.lines[r.pos]
+	return len(line) > indent && strings.TrimLeft(line[:indent], " ") == "" && line[indent] != ' '
+}
+
+func (r *yamlReader) block(indent int) (interface{}, error) {
+	if !r.at(indent) {
+		return nil, fmt.Errorf("line %d: expected indent %d", r.pos+1, indent)
+	}
+
+	body := r.lines[r.pos][indent:]
+	if strings.HasPrefix(body, "- ") {
+		return r.sequence(indent)
+	}
+	if _, _, ok := splitKey(body); ok {
+		return r.mapping(indent)
+	}
+	r.pos++
+	return yamlScalar(body)
+}
+
+func (r *yamlReader) sequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for r.at(indent) && strings.HasPrefix(r.lines[r.pos][indent:], "- ") {
+		// Read the item as if it started on a line of its own
+		r.lines[r.pos] = strings.Repeat(" ", indent+2) + r.lines[r.pos][indent+2:]
+		item, err := r.block(indent + 2)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *yamlReader) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for r.at(indent) {
+		line := r.pos + 1
+		key, rest, ok := splitKey(r.lines[r.pos][indent:])
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a mapping entry", line)
+		}
+		k, err := yamlScalar(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		name, isString := k.(string)
+		if !isString {
+			return nil, fmt.Errorf("line %d: key %s reads as %T", line, key, k)
+		}
+		if _, dup := m[name]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %s", line, key)
+		}
+
+		r.pos++
+		if rest != "" {
+			if m[name], err = yamlScalar(rest); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		if m[name], err = r.block(indent + 2); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// splitKey splits a mapping entry into its key and the value on its line
+func splitKey(body string) (key, rest string, ok bool) {
+	end := strings.Index(body+" ", ": ")
+	if strings.HasPrefix(body, `"`) {
+		end = quotedEnd(body)
+	}
+	switch {
+	case end < 0 || end >= len(body) || body[end] != ':':
+		return "", "", false
+	case end == len(body)-1:
+		return body[:end], "", true
+	case body[end+1] == ' ':
+		return body[:end], body[end+2:], true
+	}
+	return "", "", false
+}
+
+// quotedEnd returns the index after the double-quoted scalar s starts with
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+var (
+	yamlBool      = regexp.MustCompile(`^(y|Y|yes|Yes|YES|n|N|no|No|NO|true|True|TRUE|false|False|FALSE|on|On|ON|off|Off|OFF)$`)
+	yamlNull      = regexp.MustCompile(`^(~|null|Null|NULL)$`)
+	yamlDecimal   = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9][0-9_]*(\.[0-9_]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlNumber    = regexp.MustCompile(`^([-+]?(0x[0-9a-fA-F_]+|0b[01_]+|[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?|\.(inf|Inf|INF))|\.(nan|NaN|NAN))$`)
+	yamlTimestamp = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}`)
+)
+
+// yamlScalar reads a flow scalar
+func yamlScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		if quotedEnd(s) != len(s) {
+			return nil, fmt.Errorf("text after quoted scalar %s", s)
+		}
+		var v string
+		err := json.Unmarshal([]byte(s), &v)
+		return v, err
+	case s == "{}":
+		return map[string]interface{}{}, nil
+	case s == "[]":
+		return []interface{}{}, nil
+	case s == "" || yamlNull.MatchString(s):
+		return nil, nil
+	case yamlBool.MatchString(s):
+		return s[0] == 'y' || s[0] == 'Y' || s[0] == 't' || s[0] == 'T' || strings.EqualFold(s, "on"), nil
+	case yamlNumber.MatchString(s) || yamlTimestamp.MatchString(s):
+		return nil, fmt.Errorf("plain scalar %s is not a JSON value", s)
+	case yamlDecimal.MatchString(s):
+		return strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+	}
+
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") || strings.HasSuffix(s, " ") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return nil, fmt.Errorf("plain scalar %s needs quotes", s)
+	}
+	return s, nil
+}
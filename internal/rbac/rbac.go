@@ -0,0 +1,139 @@
+// Package rbac decides which camera actions an API client may perform.
+// Permissions are granted through roles or listed individually, and each
+// grant is scoped to all cameras or to specific cameras and tags.
+package rbac
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Permission is an action on a camera
+type Permission string
+
+// Camera permissions
+const (
+	CameraView      Permission = "camera:view"      // read status and settings
+	CameraConfigure Permission = "camera:configure" // change settings
+	PTZControl      Permission = "ptz:control"      // move, calibrate, cruise, go to presets
+	PresetsManage   Permission = "presets:manage"   // create and delete presets
+	AlarmTrigger    Permission = "alarm:trigger"    // start and stop the siren
+	StorageFormat   Permission = "storage:format"   // erase the SD card
+	SystemReboot    Permission = "system:reboot"
+	FirmwareUpgrade Permission = "firmware:upgrade"
+	BatchExecute    Permission = "batch:execute" // run arbitrary camera methods
+)
+
+// All lists every permission
+var All = []Permission{
+	CameraView, CameraConfigure, PTZControl, PresetsManage, AlarmTrigger,
+	StorageFormat, SystemReboot, FirmwareUpgrade, BatchExecute,
+}
+
+// Roles are the predefined permission sets. A "custom" grant has no role
+// permissions, only those it lists.
+var Roles = map[string][]Permission{
+	"viewer":   {CameraView},
+	"operator": {CameraView, PTZControl, PresetsManage, AlarmTrigger},
+	"admin":    All,
+	"custom":   nil,
+}
+
+// Grant gives the permissions of Role plus Permissions on a set of cameras.
+// With neither Cameras nor Tags the grant covers every camera.
+type Grant struct {
+	Role        string       `json:"role,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	Cameras     []string     `json:"cameras,omitempty"` // registry IDs or addresses
+	Tags        []string     `json:"tags,omitempty"`
+}
+
+// Validate checks the role and permissions of the grant
+func (g Grant) Validate() error {
+	if g.Role == "" && len(g.Permissions) == 0 {
+		return fmt.Errorf("grant needs a role or permissions")
+	}
+	if _, ok := Roles[g.Role]; g.Role != "" && !ok {
+		return fmt.Errorf("unknown role %q (use viewer, operator, admin or custom)", g.Role)
+	}
+	for _, p := range g.Permissions {
+		if !known(p) {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	return nil
+}
+
+// Camera identifies the camera an action targets
+type Camera struct {
+	ID      string // registry ID; empty for unregistered cameras
+	Address string
+	Tags    []string
+}
+
+// Allows reports whether any grant gives permission p on cam
+func Allows(grants []Grant, p Permission, cam Camera) bool {
+	for _, g := range grants {
+		if g.has(p) && g.covers(cam) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the sorted permissions the grants give on cam
+func Permissions(grants []Grant, cam Camera) []Permission {
+	var perms []Permission
+	for _, p := range All {
+		if Allows(grants, p, cam) {
+			perms = append(perms, p)
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// has reports whether the grant includes permission p
+func (g Grant) has(p Permission) bool {
+	for _, rp := range Roles[g.Role] {
+		if rp == p {
+			return true
+		}
+	}
+	for _, gp := range g.Permissions {
+		if gp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether cam is in the scope of the grant
+func (g Grant) covers(cam Camera) bool {
+	if len(g.Cameras) == 0 && len(g.Tags) == 0 {
+		return true
+	}
+	for _, ref := range g.Cameras {
+		if (cam.ID != "" && ref == cam.ID) || ref == cam.Address {
+			return true
+		}
+	}
+	for _, tag := range g.Tags {
+		for _, camTag := range cam.Tags {
+			if tag == camTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// known reports whether p is a defined permission
+func known(p Permission) bool {
+	for _, kp := range All {
+		if kp == p {
+			return true
+		}
+	}
+	return false
+}
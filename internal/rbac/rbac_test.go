@@ -0,0 +1,77 @@
+package rbac
+
+import "testing"
+
+func TestAllows(t *testing.T) {
+	frontDoor := Camera{ID: "front-door", Address: "192.168.1.100", Tags: []string{"outdoor"}}
+	garage := Camera{ID: "garage", Address: "192.168.1.101", Tags: []string{"indoor"}}
+	adHoc := Camera{Address: "10.0.0.5"}
+
+	nightShift := []Grant{
+		{Role: "operator", Tags: []string{"outdoor"}},
+		{Role: "viewer"},
+	}
+
+	tests := []struct {
+		name     string
+		grants   []Grant
+		perm     Permission
+		camera   Camera
+		expected bool
+	}{
+		{"operator moves tagged camera", nightShift, PTZControl, frontDoor, true},
+		{"operator cannot reboot", nightShift, SystemReboot, frontDoor, false},
+		{"operator cannot format", nightShift, StorageFormat, frontDoor, false},
+		{"operator cannot upgrade", nightShift, FirmwareUpgrade, frontDoor, false},
+		{"viewer everywhere", nightShift, CameraView, garage, true},
+		{"untagged camera not operated", nightShift, PTZControl, garage, false},
+		{"unregistered camera viewed", nightShift, CameraView, adHoc, true},
+		{"admin role", []Grant{{Role: "admin", Cameras: []string{"garage"}}}, StorageFormat, garage, true},
+		{"admin role out of scope", []Grant{{Role: "admin", Cameras: []string{"garage"}}}, StorageFormat, frontDoor, false},
+		{"scope by address", []Grant{{Role: "viewer", Cameras: []string{"10.0.0.5"}}}, CameraView, adHoc, true},
+		{"custom permissions", []Grant{{Role: "custom", Permissions: []Permission{SystemReboot}}}, SystemReboot, garage, true},
+		{"custom without view", []Grant{{Role: "custom", Permissions: []Permission{SystemReboot}}}, CameraView, garage, false},
+		{"role plus permissions", []Grant{{Role: "viewer", Permissions: []Permission{CameraConfigure}}}, CameraConfigure, garage, true},
+		{"no grants", nil, CameraView, garage, false},
+	}
+
+	for _, tt := range tests {
+		if got := Allows(tt.grants, tt.perm, tt.camera); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestGrant_Validate(t *testing.T) {
+	tests := []struct {
+		grant Grant
+		valid bool
+	}{
+		{Grant{Role: "viewer"}, true},
+		{Grant{Role: "custom", Permissions: []Permission{PTZControl}}, true},
+		{Grant{Permissions: []Permission{PTZControl}}, true},
+		{Grant{}, false},
+		{Grant{Role: "superuser"}, false},
+		{Grant{Role: "viewer", Permissions: []Permission{"ptz:fly"}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.grant.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: expected valid=%v, got %v", tt.grant, tt.valid, err)
+		}
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	perms := Permissions([]Grant{{Role: "operator"}}, Camera{ID: "garage"})
+
+	expected := []Permission{AlarmTrigger, CameraView, PresetsManage, PTZControl}
+	if len(perms) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, perms)
+	}
+	for i := range expected {
+		if perms[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, perms)
+		}
+	}
+}
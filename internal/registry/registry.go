@@ -0,0 +1,475 @@
+// Package registry stores the cameras managed by the API under stable IDs
+// and friendly names, together with their address, credentials and tags.
+// Passwords written to disk are sealed by a credential vault.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/vault"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+)
+
+// Errors returned by the registry
+var (
+	ErrNotFound   = errors.New("camera not found")
+	ErrExists     = errors.New("camera already registered")
+	ErrNoVaultKey = errors.New("no vault key is configured")
+)
+
+// ValidationError reports an invalid camera field
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// Camera is a registered camera
+type Camera struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Username  string    `json:"username,omitempty"`
+	Password  string    `json:"-"`
+	Scheme    string    `json:"scheme,omitempty"`
+	BasePath  string    `json:"base_path,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// storedCamera is a camera as written to the registry file
+type storedCamera struct {
+	Camera
+	PasswordSealed string `json:"password_sealed,omitempty"`
+
+	// Password is only read, to migrate files written before the vault
+	Password string `json:"password,omitempty"`
+}
+
+// HasTag reports whether the camera carries tag
+func (c Camera) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// EndpointOptions returns the client options reaching the camera API
+// through its scheme and base path, for cameras behind a reverse proxy
+func (c Camera) EndpointOptions() []tapo.Option {
+	var opts []tapo.Option
+	if c.Scheme != "" {
+		opts = append(opts, tapo.WithScheme(c.Scheme))
+	}
+	if c.BasePath != "" {
+		opts = append(opts, tapo.WithBasePath(c.BasePath))
+	}
+	return opts
+}
+
+// idPattern restricts IDs to URL-safe slugs that cannot be mistaken for an
+// IP address or a host:port pair
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Registry is a camera registry persisted as a JSON file
+type Registry struct {
+	mu      sync.Mutex
+	path    string
+	cameras map[string]Camera
+	vault   *vault.Vault
+
+	// ID of the vault key each stored password is sealed with
+	sealedWith map[string]string
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithVault seals passwords with v before they are written to disk
+func WithVault(v *vault.Vault) Option {
+	return func(r *Registry) {
+		r.vault = v
+	}
+}
+
+// Open loads the registry file at path, creating it on first write. An
+// empty path keeps the registry in memory only. A file-backed registry
+// stores passwords only when a vault is configured.
+func Open(path string, opts ...Option) (*Registry, error) {
+	r := &Registry{
+		path:       path,
+		cameras:    make(map[string]Camera),
+		sealedWith: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read camera registry: %w", err)
+	}
+
+	var stored []storedCamera
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse camera registry: %w", err)
+	}
+
+	migrate := false
+	for _, sc := range stored {
+		cam := sc.Camera
+
+		switch {
+		case sc.PasswordSealed != "":
+			if r.vault == nil {
+				return nil, errors.New("camera registry holds encrypted passwords but no vault key is configured")
+			}
+			password, err := r.vault.Open(sc.PasswordSealed, cam.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt password of camera %s: %w", cam.ID, err)
+			}
+			cam.Password = password
+			r.sealedWith[cam.ID], _ = vault.SealedKeyID(sc.PasswordSealed)
+
+		case sc.Password != "":
+			if r.vault == nil {
+				return nil, errors.New("camera registry holds plaintext passwords: configure a vault key to encrypt them")
+			}
+			cam.Password = sc.Password
+			migrate = true
+		}
+
+		r.cameras[cam.ID] = cam
+	}
+
+	// Encrypt passwords written before the vault existed
+	if migrate {
+		if err := r.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// VaultStatus describes how the stored passwords are sealed
+type VaultStatus struct {
+	Enabled   bool           `json:"enabled"`
+	ActiveKey string         `json:"active_key,omitempty"`
+	Keys      []string       `json:"keys,omitempty"`
+	Sealed    map[string]int `json:"sealed"`
+}
+
+// VaultStatus returns the number of stored passwords sealed with each key
+func (r *Registry) VaultStatus() VaultStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := VaultStatus{Sealed: make(map[string]int)}
+	if r.vault != nil {
+		status.Enabled = true
+		status.ActiveKey = r.vault.ActiveKeyID()
+		status.Keys = r.vault.KeyIDs()
+	}
+	for _, keyID := range r.sealedWith {
+		status.Sealed[keyID]++
+	}
+	return status
+}
+
+// Rotate re-encrypts every stored password with the active vault key, so
+// retired keys can be removed. It returns the number of passwords sealed.
+func (r *Registry) Rotate() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.vault == nil {
+		return 0, ErrNoVaultKey
+	}
+	if err := r.save(); err != nil {
+		return 0, err
+	}
+	return len(r.sealedWith), nil
+}
+
+// List returns all cameras sorted by ID
+func (r *Registry) List() []Camera {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sorted()
+}
+
+// Get returns the camera with id
+func (r *Registry) Get(id string) (Camera, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cam, ok := r.cameras[id]
+	return cam, ok
+}
+
+// Resolve returns the camera whose ID or address is ref. IDs take
+// precedence, so a camera can be registered under a bare host name.
+func (r *Registry) Resolve(ref string) (Camera, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cam, ok := r.cameras[ref]; ok {
+		return cam, true
+	}
+
+	address, err := tapo.NormalizeAddress(ref)
+	if err != nil {
+		return Camera{}, false
+	}
+	for _, cam := range r.cameras {
+		if cam.Address == address {
+			return cam, true
+		}
+	}
+	return Camera{}, false
+}
+
+// Create validates and stores a new camera. An empty ID is derived from
+// the name.
+func (r *Registry) Create(cam Camera) (Camera, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cam.ID == "" {
+		cam.ID = r.uniqueID(cam.Name)
+	}
+	if _, ok := r.cameras[cam.ID]; ok {
+		return Camera{}, fmt.Errorf("%w: id %q", ErrExists, cam.ID)
+	}
+
+	if err := r.validate(&cam); err != nil {
+		return Camera{}, err
+	}
+
+	cam.CreatedAt = time.Now().UTC()
+	cam.UpdatedAt = cam.CreatedAt
+	r.cameras[cam.ID] = cam
+
+	if err := r.save(); err != nil {
+		delete(r.cameras, cam.ID)
+		return Camera{}, err
+	}
+	return cam, nil
+}
+
+// Update replaces the camera with the same ID, keeping its creation time
+func (r *Registry) Update(cam Camera) (Camera, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.cameras[cam.ID]
+	if !ok {
+		return Camera{}, ErrNotFound
+	}
+
+	if err := r.validate(&cam); err != nil {
+		return Camera{}, err
+	}
+
+	cam.CreatedAt = old.CreatedAt
+	cam.UpdatedAt = time.Now().UTC()
+	r.cameras[cam.ID] = cam
+
+	if err := r.save(); err != nil {
+		r.cameras[cam.ID] = old
+		return Camera{}, err
+	}
+	return cam, nil
+}
+
+// Delete removes the camera with id and returns it
+func (r *Registry) Delete(id string) (Camera, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cam, ok := r.cameras[id]
+	if !ok {
+		return Camera{}, ErrNotFound
+	}
+
+	delete(r.cameras, id)
+	if err := r.save(); err != nil {
+		r.cameras[id] = cam
+		return Camera{}, err
+	}
+	return cam, nil
+}
+
+// validate checks and normalizes cam; the caller must hold r.mu
+func (r *Registry) validate(cam *Camera) error {
+	if !idPattern.MatchString(cam.ID) {
+		return &ValidationError{"id", "use 1-63 lowercase letters, digits, '-' or '_'"}
+	}
+
+	cam.Name = strings.TrimSpace(cam.Name)
+	if cam.Name == "" {
+		return &ValidationError{"name", "name is required"}
+	}
+
+	address, err := tapo.NormalizeAddress(strings.TrimSpace(cam.Address))
+	if err != nil {
+		return &ValidationError{"address", err.Error()}
+	}
+	cam.Address = address
+
+	cam.Scheme = strings.ToLower(strings.TrimSpace(cam.Scheme))
+	if cam.Scheme != "" && cam.Scheme != "http" && cam.Scheme != "https" {
+		return &ValidationError{"scheme", "use http or https"}
+	}
+
+	cam.BasePath = strings.Trim(strings.TrimSpace(cam.BasePath), "/")
+	if strings.ContainsAny(cam.BasePath, "?# ") {
+		return &ValidationError{"base_path", "use a URL path without query, fragment or spaces"}
+	}
+	if cam.BasePath != "" {
+		cam.BasePath = "/" + cam.BasePath
+	}
+
+	for id, other := range r.cameras {
+		if id != cam.ID && other.Address == cam.Address {
+			return fmt.Errorf("%w: address %s is registered as %q", ErrExists, cam.Address, id)
+		}
+	}
+
+	if (cam.Username == "") != (cam.Password == "") {
+		return &ValidationError{"credentials", "username and password must be set together"}
+	}
+	if cam.Password != "" && r.path != "" && r.vault == nil {
+		return &ValidationError{"credentials", "storing passwords requires a vault key (VAULT_KEY or VAULT_KEY_FILE)"}
+	}
+
+	cam.Tags = normalizeTags(cam.Tags)
+
+	return nil
+}
+
+// uniqueID derives an unused ID from name; the caller must hold r.mu
+func (r *Registry) uniqueID(name string) string {
+	var b strings.Builder
+	for _, ch := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9':
+			b.WriteRune(ch)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+
+	base := strings.TrimSuffix(b.String(), "-")
+	if len(base) > 56 {
+		base = strings.TrimSuffix(base[:56], "-")
+	}
+	if base == "" {
+		base = "camera"
+	}
+
+	id := base
+	for n := 2; ; n++ {
+		if _, ok := r.cameras[id]; !ok {
+			return id
+		}
+		id = base + "-" + strconv.Itoa(n)
+	}
+}
+
+// normalizeTags lowercases, deduplicates and sorts tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sorted returns the cameras ordered by ID; the caller must hold r.mu
+func (r *Registry) sorted() []Camera {
+	cameras := make([]Camera, 0, len(r.cameras))
+	for _, cam := range r.cameras {
+		cameras = append(cameras, cam)
+	}
+	sort.Slice(cameras, func(i, j int) bool {
+		return cameras[i].ID < cameras[j].ID
+	})
+	return cameras
+}
+
+// save atomically writes the registry to disk; the caller must hold r.mu
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	sealedWith := make(map[string]string)
+	stored := make([]storedCamera, 0, len(r.cameras))
+	for _, cam := range r.sorted() {
+		sc := storedCamera{Camera: cam}
+		if cam.Password != "" {
+			if r.vault == nil {
+				return errors.New("refusing to write passwords without a vault key")
+			}
+			sealed, err := r.vault.Seal(cam.Password, cam.ID)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt password of camera %s: %w", cam.ID, err)
+			}
+			sc.PasswordSealed = sealed
+			sealedWith[cam.ID] = r.vault.ActiveKeyID()
+		}
+		stored = append(stored, sc)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write camera registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return err
+	}
+
+	r.sealedWith = sealedWith
+	return nil
+}
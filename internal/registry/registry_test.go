@@ -0,0 +1,260 @@
+package registry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/budhilaw/gotapo-api/internal/vault"
+)
+
+func TestRegistry_CreateAndResolve(t *testing.T) {
+	r, _ := Open("")
+
+	cam, err := r.Create(Camera{Name: "Front Door", Address: "[fe80::1]", Tags: []string{"Outdoor", "site-a", "outdoor"}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if cam.ID != "front-door" {
+		t.Errorf("Expected ID front-door, got %s", cam.ID)
+	}
+	if cam.Address != "fe80::1" {
+		t.Errorf("Expected normalized address fe80::1, got %s", cam.Address)
+	}
+	if len(cam.Tags) != 2 || cam.Tags[0] != "outdoor" || cam.Tags[1] != "site-a" {
+		t.Errorf("Expected tags [outdoor site-a], got %v", cam.Tags)
+	}
+
+	for _, ref := range []string{"front-door", "fe80::1", "[fe80::1]"} {
+		if got, ok := r.Resolve(ref); !ok || got.ID != "front-door" {
+			t.Errorf("Resolve(%q): expected front-door, got %v %v", ref, got.ID, ok)
+		}
+	}
+	if _, ok := r.Resolve("192.168.1.100"); ok {
+		t.Error("Expected unregistered address not to resolve")
+	}
+
+	second, err := r.Create(Camera{Name: "Front  Door!", Address: "192.168.1.101"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if second.ID != "front-door-2" {
+		t.Errorf("Expected ID front-door-2, got %s", second.ID)
+	}
+}
+
+func TestRegistry_Validation(t *testing.T) {
+	r, _ := Open("")
+	r.Create(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100"})
+
+	tests := []struct {
+		name string
+		cam  Camera
+		err  error
+	}{
+		{"duplicate id", Camera{ID: "garage", Name: "Garage", Address: "192.168.1.101"}, ErrExists},
+		{"duplicate address", Camera{Name: "Other", Address: "192.168.1.100"}, ErrExists},
+		{"id looks like an address", Camera{ID: "10.0.0.1", Name: "X", Address: "10.0.0.1"}, nil},
+		{"missing name", Camera{ID: "x", Address: "10.0.0.1"}, nil},
+		{"invalid address", Camera{Name: "X", Address: "10.0.0.1:99999"}, nil},
+		{"username without password", Camera{Name: "X", Address: "10.0.0.1", Username: "admin"}, nil},
+		{"unknown scheme", Camera{Name: "X", Address: "10.0.0.1", Scheme: "ftp"}, nil},
+		{"base path with query", Camera{Name: "X", Address: "10.0.0.1", BasePath: "/cam?id=1"}, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := r.Create(tt.cam)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+
+		var verr *ValidationError
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+		if tt.err == nil && !errors.As(err, &verr) {
+			t.Errorf("%s: expected a ValidationError, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestRegistry_Endpoint(t *testing.T) {
+	r, _ := Open("")
+
+	cam, err := r.Create(Camera{Name: "Barn", Address: "proxy.example:8443", Scheme: " HTTP ", BasePath: "barn/"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if cam.Scheme != "http" || cam.BasePath != "/barn" {
+		t.Errorf("Expected scheme http and base path /barn, got %q %q", cam.Scheme, cam.BasePath)
+	}
+	if opts := cam.EndpointOptions(); len(opts) != 2 {
+		t.Errorf("Expected 2 endpoint options, got %d", len(opts))
+	}
+
+	cam.Scheme, cam.BasePath = "", "/"
+	cam, err = r.Update(cam)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if cam.BasePath != "" || len(cam.EndpointOptions()) != 0 {
+		t.Errorf("Expected the default endpoint, got %q %q", cam.Scheme, cam.BasePath)
+	}
+}
+
+func newTestVault(t *testing.T) (*vault.Vault, string) {
+	t.Helper()
+
+	key, _ := vault.GenerateKey()
+	v, err := vault.Load(key, "")
+	if err != nil {
+		t.Fatalf("vault.Load failed: %v", err)
+	}
+	return v, key
+}
+
+func TestRegistry_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "cameras.json")
+	v, _ := newTestVault(t)
+
+	r, err := Open(path, WithVault(v))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	cam, _ := r.Create(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100", Username: "admin", Password: "secret"})
+	cam.Name = "Garage Door"
+	if _, err := r.Update(cam); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	r.Create(Camera{ID: "porch", Name: "Porch", Address: "192.168.1.101"})
+	if _, err := r.Delete("porch"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Registry file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Registry file contains a plaintext password: %s", data)
+	}
+
+	reopened, err := Open(path, WithVault(v))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	cameras := reopened.List()
+	if len(cameras) != 1 {
+		t.Fatalf("Expected 1 camera after reopening, got %d", len(cameras))
+	}
+	if cameras[0].Name != "Garage Door" || cameras[0].Password != "secret" {
+		t.Errorf("Unexpected camera after reopening: %+v", cameras[0])
+	}
+	if !cameras[0].CreatedAt.Equal(cam.CreatedAt) {
+		t.Errorf("Expected CreatedAt to survive updates")
+	}
+
+	if _, err := reopened.Update(Camera{ID: "porch", Name: "Porch", Address: "192.168.1.101"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating a deleted camera, got %v", err)
+	}
+}
+
+func TestRegistry_RequiresVaultForPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cameras.json")
+	r, _ := Open(path)
+
+	_, err := r.Create(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100", Username: "admin", Password: "secret"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Field != "credentials" {
+		t.Errorf("Expected a credentials ValidationError, got %v", err)
+	}
+
+	if _, err := r.Create(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100"}); err != nil {
+		t.Errorf("Expected a camera without password to be stored, got %v", err)
+	}
+
+	v, _ := newTestVault(t)
+	r, _ = Open(path, WithVault(v))
+	r.Update(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100", Username: "admin", Password: "secret"})
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected opening sealed passwords without a vault to fail")
+	}
+	other, _ := newTestVault(t)
+	if _, err := Open(path, WithVault(other)); !errors.Is(err, vault.ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey with the wrong vault key, got %v", err)
+	}
+}
+
+func TestRegistry_MigratesPlaintextPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cameras.json")
+	legacy := `[{"id":"garage","name":"Garage","address":"192.168.1.100","username":"admin","password":"secret"}]`
+	os.WriteFile(path, []byte(legacy), 0o600)
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected plaintext passwords without a vault to be rejected")
+	}
+
+	v, _ := newTestVault(t)
+	r, err := Open(path, WithVault(v))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if cam, _ := r.Get("garage"); cam.Password != "secret" {
+		t.Errorf("Expected password secret, got %q", cam.Password)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "password_sealed") {
+		t.Errorf("Expected the file to be re-written sealed, got %s", data)
+	}
+}
+
+func TestRegistry_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cameras.json")
+
+	_, oldKey := newTestVault(t)
+	_, newKey := newTestVault(t)
+	old, _ := vault.Load(oldKey, "")
+
+	r, _ := Open(path, WithVault(old))
+	r.Create(Camera{ID: "garage", Name: "Garage", Address: "192.168.1.100", Username: "admin", Password: "secret"})
+
+	rotated, _ := vault.Load(newKey+","+oldKey, "")
+	r, err := Open(path, WithVault(rotated))
+	if err != nil {
+		t.Fatalf("Open with rotated keys failed: %v", err)
+	}
+	if status := r.VaultStatus(); status.Sealed[old.ActiveKeyID()] != 1 {
+		t.Errorf("Expected 1 password sealed with the old key, got %v", status.Sealed)
+	}
+
+	if n, err := r.Rotate(); err != nil || n != 1 {
+		t.Fatalf("Expected 1 password re-encrypted, got %d %v", n, err)
+	}
+	if status := r.VaultStatus(); status.Sealed[rotated.ActiveKeyID()] != 1 || len(status.Sealed) != 1 {
+		t.Errorf("Expected every password sealed with the new key, got %v", status.Sealed)
+	}
+
+	// The old key can now be retired
+	current, _ := vault.Load(newKey, "")
+	r, err = Open(path, WithVault(current))
+	if err != nil {
+		t.Fatalf("Open without the retired key failed: %v", err)
+	}
+	if cam, _ := r.Get("garage"); cam.Password != "secret" {
+		t.Errorf("Expected password secret after rotation, got %q", cam.Password)
+	}
+}
@@ -0,0 +1,136 @@
+// Package response writes the JSON envelope shared by every API response.
+//
+// A success is {"success": true} with a result or a message; a failure is
+// {"success": false} with a structured error. Both carry the request ID
+// set by middleware.RequestID.
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Body is the envelope of every JSON response
+type Body struct {
+	Success   bool        `json:"success"`
+	Result    interface{} `json:"result,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Error     *Error      `json:"error,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Error describes a failed request. Code is a stable identifier such as
+// not_found or camera_timeout; the other fields are set by the errors they
+// describe.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Retryable reports whether the same request may succeed later
+	Retryable bool `json:"retryable"`
+
+	// RetryAfter is how many seconds to wait before retrying
+	RetryAfter int `json:"retry_after,omitempty"`
+
+	// TapoCode is the error code returned by the camera
+	TapoCode int `json:"tapo_error_code,omitempty"`
+
+	// Camera is the registry ID or address of the camera of the route
+	Camera string `json:"camera,omitempty"`
+
+	// MissingPermission explains a permission denial
+	MissingPermission string `json:"missing_permission,omitempty"`
+
+	// Field is the invalid field of a rejected request
+	Field string `json:"field,omitempty"`
+}
+
+// OK writes a success response with result; a nil result is left out
+func OK(c *fiber.Ctx, result interface{}) error {
+	return c.JSON(Body{Success: true, Result: result, RequestID: requestID(c)})
+}
+
+// Created writes a 201 success response with result
+func Created(c *fiber.Ctx, result interface{}) error {
+	c.Status(fiber.StatusCreated)
+	return OK(c, result)
+}
+
+// Message writes a success response with a message
+func Message(c *fiber.Ctx, message string) error {
+	return c.JSON(Body{Success: true, Message: message, RequestID: requestID(c)})
+}
+
+// Fail writes an error response with code and message. Rate limiting and
+// unavailable or timed out upstreams are retryable.
+func Fail(c *fiber.Ctx, status int, code, message string) error {
+	return FailWith(c, status, Error{Code: code, Message: message, Retryable: Retryable(status)})
+}
+
+// FailWith writes the error response e. The camera of the route is filled
+// in when e does not name one.
+func FailWith(c *fiber.Ctx, status int, e Error) error {
+	if e.Camera == "" {
+		e.Camera = camera(c)
+	}
+
+	return c.Status(status).JSON(Body{Error: &e, RequestID: requestID(c)})
+}
+
+// Retryable reports whether a request failing with status may succeed
+// when repeated unchanged
+func Retryable(status int) bool {
+	switch status {
+	case fiber.StatusTooManyRequests, fiber.StatusServiceUnavailable, fiber.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ErrorHandler is the Fiber error handler. Errors returned by handlers and
+// routing failures such as an unknown route are written as error
+// responses; the message of an unexpected error is not exposed.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	message := http.StatusText(status)
+
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+		message = fe.Message
+	}
+
+	return Fail(c, status, StatusCode(status), message)
+}
+
+// StatusCode returns the error code of an HTTP status: its text in snake
+// case, e.g. method_not_allowed
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return strings.ToLower(text)
+}
+
+// SetCamera records the camera of the route, ref being its registry ID or
+// address, for the error responses of the request
+func SetCamera(c *fiber.Ctx, ref string) {
+	c.Locals("response_camera", ref)
+}
+
+// camera returns the camera recorded by SetCamera
+func camera(c *fiber.Ctx) string {
+	ref, _ := c.Locals("response_camera").(string)
+	return ref
+}
+
+// requestID returns the ID of the request, as set on the response
+func requestID(c *fiber.Ctx) string {
+	return string(c.Response().Header.Peek(fiber.HeaderXRequestID))
+}
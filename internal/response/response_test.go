@@ -0,0 +1,105 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("database password is hunter2")
+	})
+	app.Get("/busy", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Try again later")
+	})
+
+	tests := []struct {
+		path      string
+		status    int
+		code      string
+		message   string
+		retryable bool
+	}{
+		{"/fail", 500, "internal_server_error", "Internal Server Error", false},
+		{"/busy", 503, "service_unavailable", "Try again later", true},
+		{"/missing", 404, "not_found", "Cannot GET /missing", false},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+
+		var body Body
+		json.NewDecoder(resp.Body).Decode(&body)
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.path, tt.status, resp.StatusCode)
+		}
+		want := Error{Code: tt.code, Message: tt.message, Retryable: tt.retryable}
+		if body.Success || body.Error == nil || *body.Error != want {
+			t.Errorf("GET %s: expected error %+v, got %+v", tt.path, want, body.Error)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderXRequestID, "req-1")
+		SetCamera(c, "front-door")
+		return c.Next()
+	})
+	app.Get("/ok", func(c *fiber.Ctx) error { return OK(c, []string{}) })
+	app.Get("/created", func(c *fiber.Ctx) error { return Created(c, nil) })
+	app.Get("/message", func(c *fiber.Ctx) error { return Message(c, "Done") })
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return Fail(c, fiber.StatusTooManyRequests, "rate_limited", "Slow down")
+	})
+
+	tests := map[string]struct {
+		status int
+		body   string
+	}{
+		"/ok":      {200, `{"success":true,"result":[],"request_id":"req-1"}`},
+		"/created": {201, `{"success":true,"request_id":"req-1"}`},
+		"/message": {200, `{"success":true,"message":"Done","request_id":"req-1"}`},
+		"/fail":    {429, `{"success":false,"error":{"code":"rate_limited","message":"Slow down","retryable":true,"camera":"front-door"},"request_id":"req-1"}`},
+	}
+
+	for path, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+
+		var body json.RawMessage
+		json.NewDecoder(resp.Body).Decode(&body)
+
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("GET %s: expected %d %s, got %d %s", path, tt.status, tt.body, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := map[int]string{
+		400: "bad_request",
+		405: "method_not_allowed",
+		413: "request_entity_too_large",
+		418: "im_a_teapot",
+		599: "error",
+	}
+
+	for status, want := range tests {
+		if got := StatusCode(status); got != want {
+			t.Errorf("StatusCode(%d): expected %s, got %s", status, want, got)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/auth"
+	"github.com/budhilaw/gotapo-api/internal/handlers"
+	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/openapi"
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SpecURL is where the OpenAPI document is served, with a .json or .yaml
+// extension
+const SpecURL = "/api/openapi"
+
+// endpoint documents a route in the OpenAPI document
+type endpoint struct {
+	tag         string
+	summary     string
+	description string
+
+	// permission is the camera permission the route needs
+	permission rbac.Permission
+
+	// admin routes need an admin key, public ones no authentication
+	admin  bool
+	public bool
+
+	// request is the JSON body, result the result of a success response;
+	// a nil result documents a message
+	request interface{}
+	result  interface{}
+
+	// body replaces the success envelope, content a non-JSON response
+	body    interface{}
+	content string
+
+	// status is the success status; 0 means 200
+	status int
+
+	query  []openapi.Parameter
+	params map[string]string
+	errors []int
+}
+
+// tags describe the groups of routes, in the order of the docs
+var tags = []openapi.Tag{
+	{Name: "Server", Description: "Health, metrics and API documentation"},
+	{Name: "Auth", Description: "API keys and bearer tokens; only present with API_AUTH enabled"},
+	{Name: "Cameras", Description: "Camera registry"},
+	{Name: "PTZ", Description: "Pan, tilt and presets"},
+	{Name: "Device", Description: "Device information and system operations"},
+	{Name: "Privacy", Description: "Privacy mode and media encryption"},
+	{Name: "Detection", Description: "Motion and person detection"},
+	{Name: "Alarm", Description: "Siren and light alarm"},
+	{Name: "Image", Description: "Image, LED and audio settings"},
+	{Name: "Recording", Description: "Recording plan and storage"},
+	{Name: "Batch", Description: "Several camera methods in one request"},
+	{Name: "Audit", Description: "Audit trail of mutating requests"},
+	{Name: "Admin", Description: "Credential vault and certificate pins"},
+}
+
+// pathParams describe path parameters by name, unless the endpoint does
+var pathParams = map[string]string{
+	"ip":   "Registry ID of the camera, or its address: an IPv4 address, host name or IPv6 address, optionally with a port",
+	"id":   "Registry ID or address of the camera",
+	"host": "Camera address",
+}
+
+// auditQuery are the filters of the audit routes
+var auditQuery = []openapi.Parameter{
+	queryParam("camera", "Registry ID or address of the camera"),
+	queryParam("actor", "API key ID or name"),
+	queryParam("action", "Route name, e.g. SetPrivacy, or camera method of a batch"),
+	queryParam("outcome", "success, denied or failed"),
+	{Name: "since", In: "query", Description: "Earliest time, RFC 3339", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+	{Name: "until", In: "query", Description: "Time before which entries were recorded, RFC 3339", Schema: &openapi.Schema{Type: "string", Format: "date-time"}},
+}
+
+// endpoints document the routes by route name. Every route registered in
+// Setup needs an entry.
+var endpoints = map[string]endpoint{
+	"Health": {tag: "Server", summary: "Health check", public: true, result: struct {
+		Status string `json:"status"`
+	}{}},
+	"Docs":        {tag: "Server", summary: "Interactive API documentation", public: true, content: "text/html"},
+	"OpenAPIJSON": {tag: "Server", summary: "OpenAPI document as JSON", public: true, body: map[string]interface{}{}},
+	"OpenAPIYAML": {tag: "Server", summary: "OpenAPI document as YAML", public: true, content: "application/yaml"},
+	"Metrics":     {tag: "Server", summary: "Prometheus metrics", description: "HTTP requests, camera calls and logins, Tapo errors and the session pool, in the Prometheus text format.", public: true, content: "text/plain"},
+
+	"Token":        {tag: "Auth", summary: "Exchange the caller's API key for a token", description: "Callers signed in with a token are refused with 403 api_key_required.", request: handlers.TokenRequest{}, result: handlers.TokenResponse{}, errors: []int{403}},
+	"Whoami":       {tag: "Auth", summary: "Show the authenticated key", result: auth.Principal{}},
+	"ListKeys":     {tag: "Auth", summary: "List API keys", admin: true, result: []handlers.KeyResponse{}},
+	"CreateKey":    {tag: "Auth", summary: "Create an API key", description: "The secret is returned only in this response. role is a shorthand for a grant of that role on every camera.", admin: true, request: handlers.CreateKeyRequest{}, result: handlers.CreatedKeyResponse{}, status: 201},
+	"DeleteKey":    {tag: "Auth", summary: "Revoke an API key and its tokens", admin: true, params: map[string]string{"id": "API key ID"}, errors: []int{404}},
+	"SetKeyGrants": {tag: "Auth", summary: "Replace the camera permissions of a key", admin: true, request: handlers.SetGrantsRequest{}, result: handlers.KeyResponse{}, params: map[string]string{"id": "API key ID"}, errors: []int{404}},
+	"IssueToken":   {tag: "Auth", summary: "Issue a token for a key", admin: true, request: handlers.TokenRequest{}, result: handlers.TokenResponse{}, params: map[string]string{"id": "API key ID"}, errors: []int{404}},
+
+	"ListCameras":  {tag: "Cameras", summary: "List registered cameras", description: "Only the cameras the key may view are listed.", result: []handlers.CameraResponse{}, query: []openapi.Parameter{queryParam("tag", "Only cameras with this tag")}},
+	"GetCamera":    {tag: "Cameras", summary: "Get a registered camera", result: handlers.CameraResponse{}, errors: []int{404}},
+	"CreateCamera": {tag: "Cameras", summary: "Register a camera", description: "The ID is derived from the name when omitted. Passwords are stored encrypted and never returned.", admin: true, request: handlers.CameraRequest{}, result: handlers.CameraResponse{}, status: 201, errors: []int{409}},
+	"UpdateCamera": {tag: "Cameras", summary: "Update a registered camera", description: "Omitted fields are left unchanged.", admin: true, request: handlers.CameraRequest{}, result: handlers.CameraResponse{}, errors: []int{404, 409}},
+	"DeleteCamera": {tag: "Cameras", summary: "Remove a registered camera", admin: true, errors: []int{404}},
+
+	"PTZMove":          {tag: "PTZ", summary: "Move to coordinates", permission: rbac.PTZControl, request: handlers.MoveRequest{}},
+	"PTZStep":          {tag: "PTZ", summary: "Step in a direction", description: "direction is 0 (right), 90 (up), 180 (left) or 270 (down).", permission: rbac.PTZControl, request: handlers.StepRequest{}},
+	"PTZCalibrate":     {tag: "PTZ", summary: "Calibrate the motor", permission: rbac.PTZControl},
+	"GetPTZCapability": {tag: "PTZ", summary: "Get motor capabilities", permission: rbac.CameraView, result: map[string]interface{}{}},
+	"StartCruise":      {tag: "PTZ", summary: "Start cruising", permission: rbac.PTZControl},
+	"StopCruise":       {tag: "PTZ", summary: "Stop cruising", permission: rbac.PTZControl},
+	"ListPresets":      {tag: "PTZ", summary: "List presets", permission: rbac.CameraView, result: tapo.PresetData{}},
+	"CreatePreset":     {tag: "PTZ", summary: "Save the current position as a preset", permission: rbac.PresetsManage, request: handlers.CreatePresetRequest{}, status: 201},
+	"GotoPreset":       {tag: "PTZ", summary: "Move to a preset", permission: rbac.PTZControl, params: map[string]string{"id": "Preset ID"}},
+	"DeletePreset":     {tag: "PTZ", summary: "Delete a preset", permission: rbac.PresetsManage, params: map[string]string{"id": "Preset ID"}},
+
+	"GetDeviceInfo":        {tag: "Device", summary: "Get device information", permission: rbac.CameraView, result: tapo.BasicInfo{}},
+	"GetDeviceTime":        {tag: "Device", summary: "Get the camera clock", permission: rbac.CameraView, result: tapo.ClockStatus{}},
+	"GetDeviceSpecs":       {tag: "Device", summary: "Get module specifications", permission: rbac.CameraView, result: map[string]interface{}{}},
+	"Reboot":               {tag: "Device", summary: "Reboot the camera", permission: rbac.SystemReboot},
+	"GetFirmware":          {tag: "Device", summary: "Check for firmware updates", permission: rbac.CameraView, result: []handlers.BatchCallResult{}},
+	"StartFirmwareUpgrade": {tag: "Device", summary: "Start a firmware upgrade", permission: rbac.FirmwareUpgrade},
+
+	"GetPrivacy":    {tag: "Privacy", summary: "Get privacy mode", permission: rbac.CameraView, result: tapo.LensMaskEnabled{}},
+	"SetPrivacy":    {tag: "Privacy", summary: "Enable or disable privacy mode", permission: rbac.CameraConfigure, request: handlers.SetPrivacyRequest{}},
+	"GetEncryption": {tag: "Privacy", summary: "Get media encryption", permission: rbac.CameraView, result: tapo.MediaEncryptEnabled{}},
+	"SetEncryption": {tag: "Privacy", summary: "Enable or disable media encryption", permission: rbac.CameraConfigure, request: handlers.SetEncryptionRequest{}},
+
+	"GetMotionDetection": {tag: "Detection", summary: "Get motion detection", permission: rbac.CameraView, result: tapo.MotionDetSettings{}},
+	"SetMotionDetection": {tag: "Detection", summary: "Configure motion detection", description: "sensitivity is 0-100.", permission: rbac.CameraConfigure, request: handlers.SetMotionDetectionRequest{}},
+	"GetPersonDetection": {tag: "Detection", summary: "Get person detection", permission: rbac.CameraView, result: tapo.PersonDetSettings{}},
+	"SetPersonDetection": {tag: "Detection", summary: "Configure person detection", description: "sensitivity is 0-100.", permission: rbac.CameraConfigure, request: handlers.SetPersonDetectionRequest{}},
+
+	"GetAlarm":     {tag: "Alarm", summary: "Get alarm settings", permission: rbac.CameraView, result: tapo.Chn1AlarmSettings{}},
+	"SetAlarm":     {tag: "Alarm", summary: "Configure the alarm", description: `alarm_mode lists "sound" and/or "light".`, permission: rbac.CameraConfigure, request: handlers.SetAlarmRequest{}},
+	"TriggerAlarm": {tag: "Alarm", summary: "Start the alarm", permission: rbac.AlarmTrigger},
+	"StopAlarm":    {tag: "Alarm", summary: "Stop the alarm", permission: rbac.AlarmTrigger},
+
+	"GetImageSettings": {tag: "Image", summary: "Get image settings", permission: rbac.CameraView, result: tapo.ImageSettings{}},
+	"SetFlip":          {tag: "Image", summary: "Flip the image", description: `flip_type is "off" or "center".`, permission: rbac.CameraConfigure, request: handlers.SetFlipRequest{}},
+	"SetNightMode":     {tag: "Image", summary: "Set night vision", description: `mode is "auto", "on" (night) or "off" (day).`, permission: rbac.CameraConfigure, request: handlers.SetNightModeRequest{}},
+	"GetLED":           {tag: "Image", summary: "Get the status LED", permission: rbac.CameraView, result: tapo.LEDEnabled{}},
+	"SetLED":           {tag: "Image", summary: "Turn the status LED on or off", permission: rbac.CameraConfigure, request: handlers.SetLEDRequest{}},
+	"GetAudio":         {tag: "Image", summary: "Get audio settings", permission: rbac.CameraView, result: tapo.AudioSettings{}},
+	"SetSpeaker":       {tag: "Image", summary: "Set the speaker volume", description: "volume is 0-100.", permission: rbac.CameraConfigure, request: handlers.SetSpeakerRequest{}},
+	"SetMicrophone":    {tag: "Image", summary: "Set the microphone volume or mute it", description: "volume is 0-100.", permission: rbac.CameraConfigure, request: handlers.SetMicrophoneRequest{}},
+
+	"GetRecordPlan": {tag: "Recording", summary: "Get the recording schedule", permission: rbac.CameraView, result: tapo.RecordPlanSchedule{}},
+	"GetStorage":    {tag: "Recording", summary: "Get SD card status", permission: rbac.CameraView, result: []tapo.SDCardInfo{}},
+	"FormatStorage": {tag: "Recording", summary: "Format the SD card", permission: rbac.StorageFormat},
+
+	"Batch": {tag: "Batch", summary: "Send several camera methods at once", description: "Up to 20 methods are sent in one multipleRequest; each has its own result.", permission: rbac.BatchExecute, request: handlers.BatchRequest{}, result: []handlers.BatchCallResult{}},
+
+	"ListAudit":   {tag: "Audit", summary: "Query the audit trail", description: "Latest entries first.", admin: true, result: []audit.Entry{}, query: append(auditQuery, openapi.Parameter{Name: "limit", In: "query", Description: "Maximum entries, 1-1000 (default 100)", Schema: &openapi.Schema{Type: "integer"}})},
+	"ExportAudit": {tag: "Audit", summary: "Export the audit trail as JSON Lines", description: "Oldest entries first, one JSON entry per line.", admin: true, content: "application/x-ndjson", query: auditQuery},
+
+	"GetVaultStatus": {tag: "Admin", summary: "Get the credential vault keys", admin: true, result: registry.VaultStatus{}},
+	"RotateVault":    {tag: "Admin", summary: "Re-encrypt stored passwords with the active key", description: "Answers 409 vault_not_configured when no vault key is configured.", admin: true, result: handlers.RotateResponse{}, errors: []int{409}},
+	"ListPins":       {tag: "Admin", summary: "List pinned certificates", admin: true, result: []tapo.Pin{}},
+	"GetPin":         {tag: "Admin", summary: "Get a camera's pinned certificate", description: "The host is percent-encoded when it needs escaping, such as an IPv6 address with a port.", admin: true, result: tapo.Pin{}, errors: []int{400, 404}},
+	"ResetPin":       {tag: "Admin", summary: "Reset a camera's certificate pin", description: "The host is percent-encoded when it needs escaping, such as an IPv6 address with a port.", admin: true, errors: []int{400, 404}},
+}
+
+// errorResponses are the shared error responses by status
+var errorResponses = map[int]string{
+	400: "BadRequest",
+	401: "Unauthorized",
+	403: "Forbidden",
+	404: "NotFound",
+	409: "Conflict",
+	429: "RateLimited",
+	500: "InternalError",
+	501: "NotImplemented",
+	502: "BadGateway",
+	504: "GatewayTimeout",
+}
+
+// cameraErrors are the statuses of a failed camera call
+var cameraErrors = []int{401, 403, 404, 409, 429, 500, 501, 502, 504}
+
+// queryParam returns an optional string query parameter
+func queryParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+// buildSpec describes the routes registered on app. Routes without an
+// entry in endpoints are left out.
+func buildSpec(app *fiber.App, deps Dependencies) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Tapo Camera API",
+		Version:     "1.0.0",
+		Description: "REST API for controlling TP-Link Tapo cameras.",
+	})
+	doc.Tags = tags
+
+	errorContent := map[string]openapi.MediaType{
+		fiber.MIMEApplicationJSON: {Schema: &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"success":    {Type: "boolean", Enum: []interface{}{false}},
+				"error":      doc.Schema(response.Error{}),
+				"request_id": requestIDSchema(),
+			},
+			Required: []string{"success", "error"},
+		}},
+	}
+	for status, name := range errorResponses {
+		doc.Components.Responses[name] = openapi.Response{
+			Description: http.StatusText(status),
+			Content:     errorContent,
+		}
+	}
+
+	if deps.Auth != nil {
+		doc.Components.SecuritySchemes["bearer"] = openapi.SecurityScheme{
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "API key or JWT issued by /api/auth/token",
+		}
+		doc.Components.SecuritySchemes["apiKey"] = openapi.SecurityScheme{
+			Type: "apiKey",
+			In:   "header",
+			Name: middleware.APIKeyHeader,
+		}
+		doc.Security = []openapi.SecurityRequirement{{"bearer": {}}, {"apiKey": {}}}
+	}
+
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+
+		ep, ok := endpoints[route.Name]
+		if !ok {
+			continue
+		}
+		doc.Add(route.Method, route.Path, operation(doc, route, ep, deps.Auth != nil))
+	}
+
+	return doc
+}
+
+// operation documents route
+func operation(doc *openapi.Document, route fiber.Route, ep endpoint, authEnabled bool) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: route.Name,
+		Summary:     ep.summary,
+		Description: ep.description,
+		Tags:        []string{ep.tag},
+		Responses:   make(map[string]openapi.Response),
+	}
+
+	path := openapi.Path(route.Path)
+	camera := strings.Contains(path, "{ip}")
+
+	for _, name := range openapi.PathParams(path) {
+		description := ep.params[name]
+		if description == "" {
+			description = pathParams[name]
+		}
+		op.Parameters = append(op.Parameters, openapi.Parameter{
+			Name:        name,
+			In:          "path",
+			Description: description,
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string"},
+		})
+	}
+	op.Parameters = append(op.Parameters, ep.query...)
+
+	if camera {
+		for _, h := range []string{"X-Tapo-Username", "X-Tapo-Password"} {
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name:        h,
+				In:          "header",
+				Description: "Camera credentials; default to the stored or default credentials of a registered camera",
+				Schema:      &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+
+	if ep.permission != "" && authEnabled {
+		op.Description = strings.TrimSpace(op.Description + " Requires the " + string(ep.permission) + " permission.")
+	}
+	if ep.admin {
+		if authEnabled {
+			op.Description = strings.TrimSpace(op.Description + " Requires an admin key.")
+		} else {
+			op.Description = strings.TrimSpace(op.Description + " Disabled while API authentication is off.")
+		}
+	}
+
+	if ep.request != nil {
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				fiber.MIMEApplicationJSON: {Schema: doc.Schema(ep.request)},
+			},
+		}
+	}
+
+	status := ep.status
+	if status == 0 {
+		status = fiber.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = successResponse(doc, ep)
+
+	errs := append([]int(nil), ep.errors...)
+	if ep.request != nil || len(ep.query) > 0 || camera {
+		errs = append(errs, 400)
+	}
+	if camera {
+		errs = append(errs, cameraErrors...)
+	}
+	switch {
+	case authEnabled && !ep.public:
+		errs = append(errs, 401)
+		if ep.admin || ep.permission != "" {
+			errs = append(errs, 403)
+		}
+	case ep.admin:
+		errs = append(errs, 403)
+	}
+	sort.Ints(errs)
+	for _, code := range errs {
+		op.Responses[strconv.Itoa(code)] = openapi.Response{Ref: "#/components/responses/" + errorResponses[code]}
+	}
+
+	if ep.public && authEnabled {
+		op.Security = []openapi.SecurityRequirement{{}}
+	}
+
+	return op
+}
+
+// requestIDSchema documents the request ID of a response envelope
+func requestIDSchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Description: "X-Request-ID of the request and response"}
+}
+
+// successResponse documents the response of a successful call: the
+// success envelope with the result or a message, or a route's own body
+func successResponse(doc *openapi.Document, ep endpoint) openapi.Response {
+	resp := openapi.Response{Description: "Success"}
+
+	switch {
+	case ep.content != "":
+		resp.Content = map[string]openapi.MediaType{
+			ep.content: {Schema: &openapi.Schema{Type: "string"}},
+		}
+	case ep.body != nil:
+		resp.Content = map[string]openapi.MediaType{
+			fiber.MIMEApplicationJSON: {Schema: doc.Schema(ep.body)},
+		}
+	default:
+		envelope := &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"success":    {Type: "boolean", Enum: []interface{}{true}},
+				"request_id": requestIDSchema(),
+			},
+			Required: []string{"success"},
+		}
+		if ep.result != nil {
+			envelope.Properties["result"] = doc.Schema(ep.result)
+			envelope.Required = append(envelope.Required, "result")
+		} else {
+			envelope.Properties["message"] = &openapi.Schema{Type: "string"}
+		}
+		resp.Content = map[string]openapi.MediaType{
+			fiber.MIMEApplicationJSON: {Schema: envelope},
+		}
+	}
+
+	return resp
+}
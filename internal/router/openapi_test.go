@@ -0,0 +1,190 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/auth"
+	"github.com/budhilaw/gotapo-api/internal/metrics"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newDocumentedApp returns the API with every optional route group enabled
+func newDocumentedApp(t *testing.T, withAuth bool) (*fiber.App, Dependencies) {
+	t.Helper()
+
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	trail, _ := audit.Open("")
+	pins, _ := tapo.NewFilePinStore("")
+
+	deps := Dependencies{
+		Pool:    tapo.NewPool(0),
+		Pins:    pins,
+		Cameras: cameras,
+		Audit:   trail,
+		Metrics: metrics.New(),
+	}
+	if withAuth {
+		deps.Auth = auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin")
+	}
+	t.Cleanup(deps.Pool.Close)
+
+	app := fiber.New()
+	Setup(app, deps)
+	return app, deps
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	for _, withAuth := range []bool{true, false} {
+		app, deps := newDocumentedApp(t, withAuth)
+		doc := buildSpec(app, deps)
+
+		used := make(map[string]bool)
+		for _, route := range app.GetRoutes(true) {
+			if route.Method == fiber.MethodHead {
+				continue
+			}
+			if !doc.Has(route.Method, route.Path) {
+				t.Errorf("Route %s %s (name %q) is missing from the OpenAPI document: name it and add it to endpoints", route.Method, route.Path, route.Name)
+			}
+			used[route.Name] = true
+		}
+
+		if withAuth {
+			for name := range endpoints {
+				if !used[name] {
+					t.Errorf("Endpoint %s is documented but no route has that name", name)
+				}
+			}
+		}
+
+		data, err := doc.JSON()
+		if err != nil {
+			t.Fatalf("Failed to encode document: %v", err)
+		}
+		for _, ref := range unresolvedRefs(t, data) {
+			t.Errorf("Unresolved reference %s", ref)
+		}
+	}
+}
+
+func TestOpenAPI_Operations(t *testing.T) {
+	app, deps := newDocumentedApp(t, true)
+	doc := buildSpec(app, deps)
+
+	move := doc.Paths["/api/cameras/{ip}/ptz/move"]["post"]
+	if move == nil {
+		t.Fatal("Expected POST /api/cameras/{ip}/ptz/move")
+	}
+	if move.OperationID != "PTZMove" {
+		t.Errorf("Expected operation ID PTZMove, got %s", move.OperationID)
+	}
+	if ref := move.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/MoveRequest" {
+		t.Errorf("Expected MoveRequest body, got %s", ref)
+	}
+	for _, status := range []string{"200", "400", "401", "403", "429", "502", "504"} {
+		if _, ok := move.Responses[status]; !ok {
+			t.Errorf("Expected a %s response, got %v", status, move.Responses)
+		}
+	}
+	if !strings.Contains(move.Description, "ptz:control") {
+		t.Errorf("Expected the permission in the description, got %q", move.Description)
+	}
+
+	for _, name := range []string{"MoveRequest", "StepRequest", "SetAlarmRequest", "SetNightModeRequest", "Error", "CameraResponse", "Entry"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Expected component schema %s", name)
+		}
+	}
+
+	info := doc.Paths["/api/cameras/{ip}/info"]["get"]
+	result := info.Responses["200"].Content["application/json"].Schema.Properties["result"]
+	if result == nil || result.Ref != "#/components/schemas/BasicInfo" {
+		t.Errorf("Expected a BasicInfo result, got %+v", result)
+	}
+
+	if health := doc.Paths["/health"]["get"]; len(health.Security) != 1 || len(health.Security[0]) != 0 {
+		t.Errorf("Expected /health to need no authentication, got %v", health.Security)
+	}
+	if _, ok := doc.Paths["/api/admin/keys"]["post"]; !ok {
+		t.Error("Expected the trailing slash of /api/admin/keys/ to be dropped")
+	}
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	app, _ := newDocumentedApp(t, true)
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/api", "text/html", SpecURL},
+		{"/api/openapi.json", "application/json", `"openapi": "3.0.3"`},
+		{"/api/openapi.yaml", "application/yaml", `openapi: "3.0.3"`},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("GET %s: expected status 200 without credentials, got %d", tt.path, resp.StatusCode)
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("GET %s: expected %s, got %s", tt.path, tt.contentType, ct)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.Contains(string(body), tt.contains) {
+			t.Errorf("GET %s: expected %q in the body", tt.path, tt.contains)
+		}
+	}
+}
+
+// unresolvedRefs returns the $ref values of a document that point nowhere
+func unresolvedRefs(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid JSON document: %v", err)
+	}
+
+	var missing []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				var target interface{} = doc
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					m, _ := target.(map[string]interface{})
+					target = m[part]
+				}
+				if target == nil {
+					missing = append(missing, ref)
+				}
+			}
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(doc)
+
+	return missing
+}
@@ -1,100 +1,247 @@
 package router
 
 import (
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/auth"
 	"github.com/budhilaw/gotapo-api/internal/handlers"
+	"github.com/budhilaw/gotapo-api/internal/metrics"
 	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/openapi"
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
 	"github.com/gofiber/fiber/v2"
 )
 
-// Setup configures all routes
-func Setup(app *fiber.App) {
+// Dependencies holds the shared services used by the route handlers
+type Dependencies struct {
+	// Pool caches camera sessions across requests
+	Pool *tapo.Pool
+
+	// Pins stores trusted camera certificates; nil disables the pin admin routes
+	Pins tapo.PinStore
+
+	// Cameras is the camera registry; nil disables registry IDs and the
+	// registry routes
+	Cameras *registry.Registry
+
+	// Auth authenticates API clients; nil leaves the API open
+	Auth *auth.Service
+
+	// Audit records the requests changing cameras or server state; nil
+	// disables the audit trail and its routes
+	Audit *audit.Log
+
+	// Metrics counts requests and serves them at /metrics; nil disables
+	// both
+	Metrics *metrics.Metrics
+
+	// Credentials used when a request sends none and the camera has none
+	// stored
+	DefaultUsername string
+	DefaultPassword string
+
+	// TraceCameras lists the cameras whose protocol exchanges are logged
+	TraceCameras []string
+}
+
+// Setup configures all routes. Every route is named; the name is the
+// action recorded in the audit trail and the operation ID in the OpenAPI
+// document, which needs an entry in endpoints for each route.
+func Setup(app *fiber.App, deps Dependencies) {
+	pool := deps.Pool
+
+	// Metrics, open to everyone like the health check; installed first so
+	// every route is measured
+	if deps.Metrics != nil {
+		if deps.Cameras != nil {
+			deps.Metrics.SetCameraIDs(func(host string) (string, bool) {
+				cam, ok := deps.Cameras.Resolve(host)
+				return cam.ID, ok
+			})
+		}
+		app.Use(middleware.Metrics(deps.Metrics))
+		app.Get("/metrics", handlers.NewMetricsHandler(deps.Metrics).Serve).Name("Metrics")
+	}
+
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
+		return response.OK(c, fiber.Map{
 			"status": "ok",
 		})
-	})
+	}).Name("Health")
 
 	// API v1 routes
 	api := app.Group("/api")
 
-	// Camera routes - require credentials
-	cameras := api.Group("/cameras/:ip", middleware.TapoCredentials())
+	// API documentation, open to everyone; the document describes the
+	// routes registered below
+	docsHandler := handlers.NewDocsHandler(func() *openapi.Document {
+		return buildSpec(app, deps)
+	}, SpecURL)
+
+	api.Get("/", docsHandler.UI).Name("Docs")
+	api.Get("/openapi.json", docsHandler.JSON).Name("OpenAPIJSON")
+	api.Get("/openapi.yaml", docsHandler.YAML).Name("OpenAPIYAML")
+
+	// Routes changing server state need an admin key, so they stay closed
+	// while auth is disabled; camera routes need the permission for their
+	// action once auth is enabled
+	adminOnly := []fiber.Handler{middleware.AuthDisabled()}
+	can := func(rbac.Permission) fiber.Handler {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	if deps.Auth != nil {
+		can = middleware.Require
+		api.Use(middleware.APIAuth(deps.Auth))
+		adminOnly = []fiber.Handler{middleware.RequireAdmin()}
+	}
+
+	// Audited after authentication, so requests without valid credentials
+	// cannot fill the trail
+	if deps.Audit != nil {
+		api.Use(middleware.Audit(deps.Audit))
+	}
+
+	if deps.Auth != nil {
+		keysHandler := handlers.NewKeysHandler(deps.Auth)
+
+		api.Post("/auth/token", keysHandler.Token).Name("Token")
+		api.Get("/auth/whoami", keysHandler.Whoami).Name("Whoami")
+
+		keys := api.Group("/admin/keys", adminOnly...)
+		keys.Get("/", keysHandler.List).Name("ListKeys")
+		keys.Post("/", keysHandler.Create).Name("CreateKey")
+		keys.Delete("/:id", keysHandler.Delete).Name("DeleteKey")
+		keys.Put("/:id/grants", keysHandler.SetGrants).Name("SetKeyGrants")
+		keys.Post("/:id/token", keysHandler.IssueToken).Name("IssueToken")
+	}
+
+	// Camera registry routes, registered before the camera routes so that
+	// /cameras/:id is not mistaken for a camera operation
+	if deps.Cameras != nil {
+		camerasHandler := handlers.NewCamerasHandler(deps.Cameras, pool)
+
+		api.Get("/cameras", camerasHandler.List).Name("ListCameras")
+		api.Post("/cameras", append(adminOnly, camerasHandler.Create)...).Name("CreateCamera")
+		api.Get("/cameras/:id", camerasHandler.Get).Name("GetCamera")
+		api.Put("/cameras/:id", append(adminOnly, camerasHandler.Update)...).Name("UpdateCamera")
+		api.Delete("/cameras/:id", append(adminOnly, camerasHandler.Delete)...).Name("DeleteCamera")
+	}
+
+	// Camera routes - :ip is a registry ID or an address; credentials come
+	// from headers, the registry or the defaults
+	cameras := api.Group("/cameras/:ip",
+		middleware.CameraHost(deps.Cameras),
+		middleware.TapoCredentials(deps.DefaultUsername, deps.DefaultPassword),
+		middleware.Trace(deps.TraceCameras),
+	)
 
 	// Initialize handlers
-	ptzHandler := handlers.NewPTZHandler()
-	presetsHandler := handlers.NewPresetsHandler()
-	deviceHandler := handlers.NewDeviceHandler()
-	privacyHandler := handlers.NewPrivacyHandler()
-	detectionHandler := handlers.NewDetectionHandler()
-	alarmHandler := handlers.NewAlarmHandler()
-	imageHandler := handlers.NewImageHandler()
-	ledHandler := handlers.NewLEDHandler()
-	audioHandler := handlers.NewAudioHandler()
-	recordingHandler := handlers.NewRecordingHandler()
-	systemHandler := handlers.NewSystemHandler()
+	ptzHandler := handlers.NewPTZHandler(pool)
+	presetsHandler := handlers.NewPresetsHandler(pool)
+	deviceHandler := handlers.NewDeviceHandler(pool)
+	privacyHandler := handlers.NewPrivacyHandler(pool)
+	detectionHandler := handlers.NewDetectionHandler(pool)
+	alarmHandler := handlers.NewAlarmHandler(pool)
+	imageHandler := handlers.NewImageHandler(pool)
+	ledHandler := handlers.NewLEDHandler(pool)
+	audioHandler := handlers.NewAudioHandler(pool)
+	recordingHandler := handlers.NewRecordingHandler(pool)
+	systemHandler := handlers.NewSystemHandler(pool)
+	batchHandler := handlers.NewBatchHandler(pool)
 
 	// PTZ routes
 	ptz := cameras.Group("/ptz")
-	ptz.Post("/move", ptzHandler.Move)
-	ptz.Post("/step", ptzHandler.Step)
-	ptz.Post("/calibrate", ptzHandler.Calibrate)
-	ptz.Get("/capability", ptzHandler.GetCapability)
-	ptz.Post("/cruise/start", ptzHandler.StartCruise)
-	ptz.Post("/cruise/stop", ptzHandler.StopCruise)
+	ptz.Post("/move", can(rbac.PTZControl), ptzHandler.Move).Name("PTZMove")
+	ptz.Post("/step", can(rbac.PTZControl), ptzHandler.Step).Name("PTZStep")
+	ptz.Post("/calibrate", can(rbac.PTZControl), ptzHandler.Calibrate).Name("PTZCalibrate")
+	ptz.Get("/capability", can(rbac.CameraView), ptzHandler.GetCapability).Name("GetPTZCapability")
+	ptz.Post("/cruise/start", can(rbac.PTZControl), ptzHandler.StartCruise).Name("StartCruise")
+	ptz.Post("/cruise/stop", can(rbac.PTZControl), ptzHandler.StopCruise).Name("StopCruise")
 
 	// Presets routes
 	presets := cameras.Group("/presets")
-	presets.Get("/", presetsHandler.List)
-	presets.Post("/", presetsHandler.Create)
-	presets.Post("/:id/goto", presetsHandler.Goto)
-	presets.Delete("/:id", presetsHandler.Delete)
+	presets.Get("/", can(rbac.CameraView), presetsHandler.List).Name("ListPresets")
+	presets.Post("/", can(rbac.PresetsManage), presetsHandler.Create).Name("CreatePreset")
+	presets.Post("/:id/goto", can(rbac.PTZControl), presetsHandler.Goto).Name("GotoPreset")
+	presets.Delete("/:id", can(rbac.PresetsManage), presetsHandler.Delete).Name("DeletePreset")
 
 	// Device info routes
-	cameras.Get("/info", deviceHandler.GetInfo)
-	cameras.Get("/time", deviceHandler.GetTime)
-	cameras.Get("/specs", deviceHandler.GetSpecs)
+	cameras.Get("/info", can(rbac.CameraView), deviceHandler.GetInfo).Name("GetDeviceInfo")
+	cameras.Get("/time", can(rbac.CameraView), deviceHandler.GetTime).Name("GetDeviceTime")
+	cameras.Get("/specs", can(rbac.CameraView), deviceHandler.GetSpecs).Name("GetDeviceSpecs")
 
 	// Privacy routes
-	cameras.Get("/privacy", privacyHandler.GetPrivacy)
-	cameras.Put("/privacy", privacyHandler.SetPrivacy)
-	cameras.Get("/encryption", privacyHandler.GetEncryption)
-	cameras.Put("/encryption", privacyHandler.SetEncryption)
+	cameras.Get("/privacy", can(rbac.CameraView), privacyHandler.GetPrivacy).Name("GetPrivacy")
+	cameras.Put("/privacy", can(rbac.CameraConfigure), privacyHandler.SetPrivacy).Name("SetPrivacy")
+	cameras.Get("/encryption", can(rbac.CameraView), privacyHandler.GetEncryption).Name("GetEncryption")
+	cameras.Put("/encryption", can(rbac.CameraConfigure), privacyHandler.SetEncryption).Name("SetEncryption")
 
 	// Detection routes
 	detection := cameras.Group("/detection")
-	detection.Get("/motion", detectionHandler.GetMotionDetection)
-	detection.Put("/motion", detectionHandler.SetMotionDetection)
-	detection.Get("/person", detectionHandler.GetPersonDetection)
-	detection.Put("/person", detectionHandler.SetPersonDetection)
+	detection.Get("/motion", can(rbac.CameraView), detectionHandler.GetMotionDetection).Name("GetMotionDetection")
+	detection.Put("/motion", can(rbac.CameraConfigure), detectionHandler.SetMotionDetection).Name("SetMotionDetection")
+	detection.Get("/person", can(rbac.CameraView), detectionHandler.GetPersonDetection).Name("GetPersonDetection")
+	detection.Put("/person", can(rbac.CameraConfigure), detectionHandler.SetPersonDetection).Name("SetPersonDetection")
 
 	// Alarm routes
-	cameras.Get("/alarm", alarmHandler.GetAlarm)
-	cameras.Put("/alarm", alarmHandler.SetAlarm)
-	cameras.Post("/alarm/trigger", alarmHandler.TriggerAlarm)
-	cameras.Delete("/alarm/trigger", alarmHandler.StopAlarm)
+	cameras.Get("/alarm", can(rbac.CameraView), alarmHandler.GetAlarm).Name("GetAlarm")
+	cameras.Put("/alarm", can(rbac.CameraConfigure), alarmHandler.SetAlarm).Name("SetAlarm")
+	cameras.Post("/alarm/trigger", can(rbac.AlarmTrigger), alarmHandler.TriggerAlarm).Name("TriggerAlarm")
+	cameras.Delete("/alarm/trigger", can(rbac.AlarmTrigger), alarmHandler.StopAlarm).Name("StopAlarm")
 
 	// Image settings routes
-	cameras.Get("/image", imageHandler.GetSettings)
-	cameras.Put("/image/flip", imageHandler.SetFlip)
-	cameras.Put("/image/nightmode", imageHandler.SetNightMode)
+	cameras.Get("/image", can(rbac.CameraView), imageHandler.GetSettings).Name("GetImageSettings")
+	cameras.Put("/image/flip", can(rbac.CameraConfigure), imageHandler.SetFlip).Name("SetFlip")
+	cameras.Put("/image/nightmode", can(rbac.CameraConfigure), imageHandler.SetNightMode).Name("SetNightMode")
 
 	// LED routes
-	cameras.Get("/led", ledHandler.GetStatus)
-	cameras.Put("/led", ledHandler.SetStatus)
+	cameras.Get("/led", can(rbac.CameraView), ledHandler.GetStatus).Name("GetLED")
+	cameras.Put("/led", can(rbac.CameraConfigure), ledHandler.SetStatus).Name("SetLED")
 
 	// Audio routes
-	cameras.Get("/audio", audioHandler.GetConfig)
-	cameras.Put("/audio/speaker", audioHandler.SetSpeaker)
-	cameras.Put("/audio/microphone", audioHandler.SetMicrophone)
+	cameras.Get("/audio", can(rbac.CameraView), audioHandler.GetConfig).Name("GetAudio")
+	cameras.Put("/audio/speaker", can(rbac.CameraConfigure), audioHandler.SetSpeaker).Name("SetSpeaker")
+	cameras.Put("/audio/microphone", can(rbac.CameraConfigure), audioHandler.SetMicrophone).Name("SetMicrophone")
 
 	// Recording routes
-	cameras.Get("/recording/plan", recordingHandler.GetRecordPlan)
-	cameras.Get("/storage", recordingHandler.GetStorageStatus)
-	cameras.Post("/storage/format", recordingHandler.FormatStorage)
+	cameras.Get("/recording/plan", can(rbac.CameraView), recordingHandler.GetRecordPlan).Name("GetRecordPlan")
+	cameras.Get("/storage", can(rbac.CameraView), recordingHandler.GetStorageStatus).Name("GetStorage")
+	cameras.Post("/storage/format", can(rbac.StorageFormat), recordingHandler.FormatStorage).Name("FormatStorage")
 
 	// System routes
-	cameras.Post("/reboot", systemHandler.Reboot)
-	cameras.Get("/firmware", systemHandler.GetFirmwareInfo)
-	cameras.Post("/firmware/upgrade", systemHandler.StartFirmwareUpgrade)
+	cameras.Post("/reboot", can(rbac.SystemReboot), systemHandler.Reboot).Name("Reboot")
+	cameras.Get("/firmware", can(rbac.CameraView), systemHandler.GetFirmwareInfo).Name("GetFirmware")
+	cameras.Post("/firmware/upgrade", can(rbac.FirmwareUpgrade), systemHandler.StartFirmwareUpgrade).Name("StartFirmwareUpgrade")
+
+	// Batch route
+	cameras.Post("/batch", can(rbac.BatchExecute), batchHandler.Execute).Name("Batch")
+
+	// Audit trail routes
+	if deps.Audit != nil {
+		auditHandler := handlers.NewAuditHandler(deps.Audit)
+
+		api.Get("/audit", append(adminOnly, auditHandler.List)...).Name("ListAudit")
+		api.Get("/audit/export", append(adminOnly, auditHandler.Export)...).Name("ExportAudit")
+	}
+
+	// Admin routes
+	admin := api.Group("/admin", adminOnly...)
+
+	if deps.Cameras != nil {
+		vaultHandler := handlers.NewVaultHandler(deps.Cameras)
+
+		admin.Get("/vault", vaultHandler.Status).Name("GetVaultStatus")
+		admin.Post("/vault/rotate", vaultHandler.Rotate).Name("RotateVault")
+	}
+
+	if deps.Pins != nil {
+		pinsHandler := handlers.NewPinsHandler(deps.Pins, pool)
+
+		admin.Get("/pins", pinsHandler.List).Name("ListPins")
+		admin.Get("/pins/:host", pinsHandler.Get).Name("GetPin")
+		admin.Delete("/pins/:host", pinsHandler.Reset).Name("ResetPin")
+	}
 }
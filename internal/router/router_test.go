@@ -0,0 +1,947 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/internal/audit"
+	"github.com/budhilaw/gotapo-api/internal/auth"
+	"github.com/budhilaw/gotapo-api/internal/logging"
+	"github.com/budhilaw/gotapo-api/internal/metrics"
+	"github.com/budhilaw/gotapo-api/internal/middleware"
+	"github.com/budhilaw/gotapo-api/internal/rbac"
+	"github.com/budhilaw/gotapo-api/internal/registry"
+	"github.com/budhilaw/gotapo-api/internal/response"
+	"github.com/budhilaw/gotapo-api/internal/vault"
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newEmulatedApp returns the API wired to an emulated camera, tracing the
+// listed cameras
+func newEmulatedApp(t *testing.T, traceCameras ...string) (*fiber.App, *emulator.Camera) {
+	t.Helper()
+	return newEmulatedAppWith(t, Dependencies{TraceCameras: traceCameras})
+}
+
+// newEmulatedAppWith returns the API wired to an emulated camera, with deps
+// apart from the pool
+func newEmulatedAppWith(t *testing.T, deps Dependencies) (*fiber.App, *emulator.Camera) {
+	t.Helper()
+
+	cam := emulator.New()
+	srv := emulator.NewServer(cam)
+
+	opts := []tapo.Option{
+		tapo.WithTransportFunc(func(tr *http.Transport) {
+			tr.DialContext = srv.DialContext
+		}),
+		tapo.WithLogger(slog.Default()),
+	}
+	if deps.Metrics != nil {
+		opts = append(opts, tapo.WithObserver(deps.Metrics))
+	}
+	pool := tapo.NewPool(0, opts...)
+	if deps.Metrics != nil {
+		deps.Metrics.ObservePool(pool)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+		srv.Close()
+		for _, v := range cam.Violations() {
+			t.Errorf("Protocol violation: %s", v)
+		}
+	})
+
+	deps.Pool = pool
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Logger())
+	Setup(app, deps)
+
+	return app, cam
+}
+
+// captureLogs sends the records of the default logger of at least level to
+// the returned buffer, in logfmt, until the test ends. Apps must be built
+// after it is called.
+func captureLogs(t *testing.T, level string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, level, logging.FormatLogfmt)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	return &buf
+}
+
+// call sends a request to the emulated camera's routes and decodes the JSON
+// response
+func call(t *testing.T, app *fiber.App, method, path string, body interface{}, headers ...string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+	return callCamera(t, app, "192.168.1.100", method, path, body, headers...)
+}
+
+// callCamera sends a request to the routes of camera and decodes the JSON
+// response
+func callCamera(t *testing.T, app *fiber.App, camera, method, path string, body interface{}, headers ...string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+	return callAPI(t, app, method, "/api/cameras/"+camera+path, body, headers...)
+}
+
+// callAPI sends a request with the emulated camera's credentials and
+// decodes the JSON response
+func callAPI(t *testing.T, app *fiber.App, method, path string, body interface{}, headers ...string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tapo-Username", emulator.DefaultUsername)
+	req.Header.Set("X-Tapo-Password", emulator.DefaultPassword)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+	}
+
+	return resp, result
+}
+
+// errorField returns a field of the error of a decoded error response
+func errorField(body map[string]interface{}, key string) interface{} {
+	e, _ := body["error"].(map[string]interface{})
+	return e[key]
+}
+
+func TestEmulatedCamera_Routes(t *testing.T) {
+	app, _ := newEmulatedApp(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+		result map[string]interface{}
+	}{
+		{"PUT", "/led", map[string]bool{"enabled": false}, 200, nil},
+		{"GET", "/led", nil, 200, map[string]interface{}{"enabled": "off"}},
+		{"PUT", "/privacy", map[string]bool{"enabled": true}, 200, nil},
+		{"GET", "/privacy", nil, 200, map[string]interface{}{"enabled": "on"}},
+		{"PUT", "/detection/person", map[string]interface{}{"enabled": false, "sensitivity": 30}, 200, nil},
+		{"GET", "/detection/person", nil, 200, map[string]interface{}{"enabled": "off", "sensitivity": "30"}},
+		{"POST", "/presets", map[string]string{"name": "Door"}, 201, nil},
+		{"GET", "/presets", nil, 200, map[string]interface{}{"id": []interface{}{"1"}, "name": []interface{}{"Door"}}},
+		{"POST", "/presets/1/goto", nil, 200, nil},
+		{"POST", "/presets/9/goto", nil, 404, nil},
+		{"PUT", "/audio/speaker", map[string]int{"volume": 80}, 200, nil},
+		{"POST", "/alarm/trigger", nil, 200, nil},
+		{"DELETE", "/alarm/trigger", nil, 200, nil},
+		{"POST", "/ptz/step", map[string]int{"direction": 90}, 200, nil},
+		{"DELETE", "/presets/1", nil, 200, nil},
+	}
+
+	for _, tt := range tests {
+		resp, body := call(t, app, tt.method, tt.path, tt.body)
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d (%v)", tt.method, tt.path, tt.status, resp.StatusCode, body)
+			continue
+		}
+
+		if tt.result != nil {
+			result, _ := body["result"].(map[string]interface{})
+			for key, expected := range tt.result {
+				if got, _ := json.Marshal(result[key]); string(got) != mustJSON(expected) {
+					t.Errorf("%s %s: expected %s=%s, got %s", tt.method, tt.path, key, mustJSON(expected), got)
+				}
+			}
+		}
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	app, _ := newEmulatedApp(t)
+
+	resp, body := call(t, app, "GET", "/led", nil, fiber.HeaderXRequestID, "client-42")
+	if got := resp.Header.Get(fiber.HeaderXRequestID); got != "client-42" {
+		t.Errorf("Expected the client's request ID, got %q", got)
+	}
+	if body["success"] != true || body["result"] == nil || body["request_id"] != "client-42" {
+		t.Errorf("Expected a success envelope with request ID client-42, got %v", body)
+	}
+
+	resp, body = call(t, app, "GET", "/led", nil, fiber.HeaderXRequestID, "bad id")
+	id := resp.Header.Get(fiber.HeaderXRequestID)
+	if id == "" || id == "bad id" || body["request_id"] != id {
+		t.Errorf("Expected a generated request ID in the header and body, got %q and %v", id, body["request_id"])
+	}
+
+	resp, body = call(t, app, "POST", "/presets/9/goto", nil)
+	if resp.StatusCode != fiber.StatusNotFound || body["success"] != false || body["request_id"] == nil {
+		t.Errorf("Expected a 404 error envelope, got %d %v", resp.StatusCode, body)
+	}
+	if errorField(body, "code") != "not_found" || errorField(body, "camera") != "192.168.1.100" || errorField(body, "retryable") != false {
+		t.Errorf("Expected not_found on camera 192.168.1.100, got %v", body["error"])
+	}
+
+	resp, body = callAPI(t, app, "GET", "/api/unknown", nil)
+	if resp.StatusCode != fiber.StatusNotFound || body["success"] != false || errorField(body, "code") != "not_found" {
+		t.Errorf("Expected the error handler's not_found envelope, got %d %v", resp.StatusCode, body)
+	}
+
+	resp, body = callAPI(t, app, "GET", "/health", nil)
+	result, _ := body["result"].(map[string]interface{})
+	if resp.StatusCode != fiber.StatusOK || body["success"] != true || result["status"] != "ok" {
+		t.Errorf("Expected a success envelope from /health, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	cameras.Create(registry.Camera{ID: "front-door", Name: "Front Door", Address: "192.168.1.100"})
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras: cameras,
+		Auth:    auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+		Metrics: metrics.New(),
+	})
+	admin := []string{"Authorization", "Bearer gtk_admin"}
+
+	call(t, app, "GET", "/led", nil, admin...)
+	call(t, app, "GET", "/led", nil, admin...)
+	call(t, app, "POST", "/presets/9/goto", nil, admin...)
+	call(t, app, "GET", "/led", nil)
+	call(t, app, "GET", "/led", nil, append(admin, "X-Tapo-Password", "wrong", "X-Tapo-Username", "other")...)
+	callCamera(t, app, "192.168.1.201", "GET", "/led", nil, admin...)
+	callCamera(t, app, "192.168.1.202", "GET", "/led", nil, admin...)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusOK || !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), "text/plain; version=0.0.4") {
+		t.Fatalf("Expected the Prometheus text format without credentials, got %d %s", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	data, _ := io.ReadAll(resp.Body)
+	body := string(data)
+
+	for _, line := range []string{
+		`gotapo_http_requests_total{method="GET",route="/api/cameras/:ip/led",status="200"} 4`,
+		`gotapo_http_requests_total{method="POST",route="/api/cameras/:ip/presets/:id/goto",status="404"} 1`,
+		`gotapo_http_requests_total{method="GET",route="/api",status="401"} 1`,
+		`gotapo_http_request_duration_seconds_count{method="GET",route="/api/cameras/:ip/led"} 5`,
+		`gotapo_tapo_call_duration_seconds_count{camera="front-door",method="getLedStatus",outcome="success"} 2`,
+		`gotapo_tapo_call_duration_seconds_count{camera="front-door",method="motorMoveToPreset",outcome="error"} 1`,
+		`gotapo_tapo_call_duration_seconds_count{camera="unregistered",method="getLedStatus",outcome="success"} 2`,
+		`gotapo_tapo_auth_attempts_total{camera="front-door",protocol="secure",outcome="success"} 1`,
+		`gotapo_tapo_auth_attempts_total{camera="front-door",protocol="secure",outcome="invalid_credentials"} 1`,
+		`gotapo_tapo_auth_attempts_total{camera="unregistered",protocol="secure",outcome="success"} 2`,
+		`gotapo_tapo_errors_total{camera="front-door",code="-64302"} 1`,
+		`gotapo_tapo_pool_sessions 4`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metric line %s", line)
+		}
+	}
+	if t.Failed() {
+		t.Log(body)
+	}
+}
+
+func TestEmulatedCamera_CruiseInProgress(t *testing.T) {
+	app, _ := newEmulatedApp(t)
+
+	call(t, app, "POST", "/presets", map[string]string{"name": "Door"})
+	call(t, app, "POST", "/ptz/cruise/start", nil)
+
+	resp, body := call(t, app, "POST", "/presets/1/goto", nil)
+	if resp.StatusCode != fiber.StatusConflict || errorField(body, "code") != "conflict" {
+		t.Errorf("Expected 409 conflict while cruising, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestEmulatedCamera_RateLimited(t *testing.T) {
+	app, cam := newEmulatedApp(t)
+	cam.Inject(emulator.Fault{Code: emulator.CodeSuspended, SecLeft: 60})
+
+	resp, body := call(t, app, "GET", "/led", nil)
+
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d (%v)", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+	if errorField(body, "retryable") != true || errorField(body, "retry_after") != float64(60) {
+		t.Errorf("Expected a retryable error after 60 seconds, got %v", body)
+	}
+}
+
+func TestEmulatedCamera_Trace(t *testing.T) {
+	buf := captureLogs(t, "info")
+
+	tests := []struct {
+		name     string
+		cameras  []string
+		headers  []string
+		expected bool
+	}{
+		{"off", nil, nil, false},
+		{"configured camera", []string{"192.168.1.100"}, nil, true},
+		{"other camera", []string{"192.168.1.101"}, nil, false},
+		{"all cameras", []string{"*"}, nil, true},
+		{"header without auth", nil, []string{"X-Tapo-Trace", "true"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			app, _ := newEmulatedApp(t, tt.cameras...)
+
+			call(t, app, "GET", "/led", nil, tt.headers...)
+
+			out := buf.String()
+			if traced := strings.Contains(out, `msg="Camera trace" host=192.168.1.100 phase=request`); traced != tt.expected {
+				t.Errorf("Expected traced=%v, got log %q", tt.expected, out)
+			}
+			if strings.Contains(out, `:\"`+emulator.DefaultPassword+`\"`) {
+				t.Errorf("Trace leaks the password: %s", out)
+			}
+		})
+	}
+}
+
+func TestEmulatedCamera_TraceHeader(t *testing.T) {
+	buf := captureLogs(t, "info")
+
+	keys, _ := auth.OpenKeyStore("")
+	_, secret, _ := keys.Create("operator", false, []rbac.Grant{{Role: "operator"}}, nil)
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Auth: auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{"operator", secret, false},
+		{"admin", "gtk_admin", true},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+		call(t, app, "GET", "/led", nil, "Authorization", "Bearer "+tt.key, "X-Tapo-Trace", "true")
+
+		if traced := strings.Contains(buf.String(), `msg="Camera trace"`); traced != tt.expected {
+			t.Errorf("%s: expected traced=%v, got log %q", tt.name, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	buf := captureLogs(t, "debug")
+	app, _ := newEmulatedApp(t)
+
+	call(t, app, "GET", "/led", nil, fiber.HeaderXRequestID, "log-test-1")
+
+	lines := map[string]string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		for _, msg := range []string{`msg=Request `, `msg="Camera call" `} {
+			if strings.Contains(line, msg) {
+				lines[msg] = line
+			}
+		}
+	}
+
+	request := lines[`msg=Request `]
+	for _, want := range []string{
+		"level=INFO",
+		"method=GET path=/api/cameras/192.168.1.100/led",
+		"status=200",
+		"headers.X-Tapo-Password=[REDACTED]",
+		"request_id=log-test-1",
+		"camera=192.168.1.100",
+	} {
+		if !strings.Contains(request, want) {
+			t.Errorf("Expected %q in the access log, got %q", want, request)
+		}
+	}
+	if strings.Contains(request, "X-Tapo-Password="+emulator.DefaultPassword) {
+		t.Errorf("Access log leaks the password: %s", request)
+	}
+
+	camera := lines[`msg="Camera call" `]
+	for _, want := range []string{"host=192.168.1.100", "method=getLedStatus", "request_id=log-test-1", "camera=192.168.1.100"} {
+		if !strings.Contains(camera, want) {
+			t.Errorf("Expected %q in the camera call log, got %q", want, camera)
+		}
+	}
+}
+
+func TestCameraAddress(t *testing.T) {
+	cam := emulator.New()
+	srv := emulator.NewServer(cam)
+	defer srv.Close()
+
+	// No dial override: requests go to the address in the route
+	pool := tapo.NewPool(0)
+	defer pool.Close()
+
+	app := fiber.New()
+	Setup(app, Dependencies{Pool: pool})
+
+	cameras := []string{strings.TrimPrefix(srv.URL, "https://")}
+
+	if ln, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		srv6 := httptest.NewUnstartedServer(cam)
+		srv6.Listener.Close()
+		srv6.Listener = ln
+		srv6.StartTLS()
+		defer srv6.Close()
+
+		cameras = append(cameras, ln.Addr().String(), url.PathEscape(ln.Addr().String()))
+	}
+
+	for _, camera := range cameras {
+		resp, body := callCamera(t, app, camera, "GET", "/led", nil)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: expected status 200, got %d (%v)", camera, resp.StatusCode, body)
+		}
+	}
+
+	for _, camera := range []string{"10.0.0.1:70000", "10.0.0.1:http", "bad%20host", "[10.0.0.1]", "fe80::zz"} {
+		resp, body := callCamera(t, app, camera, "GET", "/led", nil)
+		if resp.StatusCode != fiber.StatusBadRequest || errorField(body, "code") != "invalid_camera" {
+			t.Errorf("%s: expected 400 invalid_camera, got %d %v", camera, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestCameraRegistry(t *testing.T) {
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras: cameras,
+		Auth:    auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+	})
+
+	admin := []string{"Authorization", "Bearer gtk_admin"}
+	noCredentials := append([]string{"X-Tapo-Username", "", "X-Tapo-Password", ""}, admin...)
+
+	resp, body := callAPI(t, app, "POST", "/api/cameras", map[string]interface{}{
+		"name":     "Front Door",
+		"address":  "192.168.1.100",
+		"username": emulator.DefaultUsername,
+		"password": emulator.DefaultPassword,
+		"tags":     []string{"outdoor"},
+	}, admin...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d (%v)", resp.StatusCode, body)
+	}
+
+	result, _ := body["result"].(map[string]interface{})
+	if result["id"] != "front-door" || result["has_password"] != true {
+		t.Errorf("Unexpected camera: %v", result)
+	}
+	if _, ok := result["password"]; ok {
+		t.Errorf("Password returned by the API: %v", result)
+	}
+
+	// Registry ID and address both use the stored credentials
+	for _, camera := range []string{"front-door", "192.168.1.100"} {
+		resp, body = callCamera(t, app, camera, "GET", "/led", nil, noCredentials...)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: expected status 200 with stored credentials, got %d (%v)", camera, resp.StatusCode, body)
+		}
+	}
+
+	// Header credentials take precedence over stored ones
+	resp, _ = callCamera(t, app, "front-door", "GET", "/led", nil, append([]string{"X-Tapo-Password", "wrong"}, admin...)...)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 with wrong header credentials, got %d", resp.StatusCode)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+		count  int
+	}{
+		{"GET", "/api/cameras?tag=outdoor", nil, 200, 1},
+		{"GET", "/api/cameras?tag=indoor", nil, 200, 0},
+		{"POST", "/api/cameras", map[string]string{"name": "Dup", "address": "192.168.1.100"}, 409, -1},
+		{"POST", "/api/cameras", map[string]string{"name": "Bad", "address": "192.168.1.1:0"}, 400, -1},
+		{"PUT", "/api/cameras/front-door", map[string]interface{}{"tags": []string{"indoor"}}, 200, -1},
+		{"GET", "/api/cameras?tag=indoor", nil, 200, 1},
+		{"GET", "/api/cameras/192.168.1.100", nil, 200, -1},
+		{"DELETE", "/api/cameras/front-door", nil, 200, -1},
+		{"GET", "/api/cameras/front-door", nil, 404, -1},
+		{"GET", "/api/cameras", nil, 200, 0},
+	}
+
+	for _, tt := range tests {
+		resp, body := callAPI(t, app, tt.method, tt.path, tt.body, admin...)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d (%v)", tt.method, tt.path, tt.status, resp.StatusCode, body)
+			continue
+		}
+		if list, ok := body["result"].([]interface{}); tt.count >= 0 && (!ok || len(list) != tt.count) {
+			t.Errorf("%s %s: expected %d cameras, got %v", tt.method, tt.path, tt.count, body["result"])
+		}
+	}
+
+	resp, _ = callCamera(t, app, "192.168.1.100", "GET", "/led", nil, noCredentials...)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 after removing the camera, got %d", resp.StatusCode)
+	}
+}
+
+func TestDefaultCredentials(t *testing.T) {
+	cameras, _ := registry.Open("")
+	cameras.Create(registry.Camera{ID: "front-door", Name: "Front Door", Address: "192.168.1.100"})
+
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras:         cameras,
+		DefaultUsername: emulator.DefaultUsername,
+		DefaultPassword: emulator.DefaultPassword,
+	})
+	noCredentials := []string{"X-Tapo-Username", "", "X-Tapo-Password", ""}
+
+	resp, body := call(t, app, "GET", "/led", nil, noCredentials...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200 with default credentials, got %d (%v)", resp.StatusCode, body)
+	}
+
+	// Default credentials are never sent to an unregistered host
+	resp, body = callCamera(t, app, "192.168.1.200", "GET", "/led", nil, noCredentials...)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an unregistered camera, got %d (%v)", resp.StatusCode, body)
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	cameras.Create(registry.Camera{
+		ID:       "front-door",
+		Name:     "Front Door",
+		Address:  "192.168.1.100",
+		Username: emulator.DefaultUsername,
+		Password: emulator.DefaultPassword,
+	})
+
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras: cameras,
+		Auth:    auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+	})
+
+	// Camera credentials never travel in headers
+	noCredentials := []string{"X-Tapo-Username", "", "X-Tapo-Password", ""}
+	as := func(credential string) []string {
+		return append([]string{"Authorization", "Bearer " + credential}, noCredentials...)
+	}
+
+	resp, body := callAPI(t, app, "POST", "/api/admin/keys", map[string]string{"name": "dashboard", "role": "operator"}, as("gtk_admin")...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201 creating a key, got %d (%v)", resp.StatusCode, body)
+	}
+	result, _ := body["result"].(map[string]interface{})
+	secret, _ := result["secret"].(string)
+	keyID, _ := result["key"].(map[string]interface{})["id"].(string)
+
+	resp, body = callAPI(t, app, "POST", "/api/auth/token", map[string]string{"ttl": "5m"}, as(secret)...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200 issuing a token, got %d (%v)", resp.StatusCode, body)
+	}
+	token, _ := body["result"].(map[string]interface{})["token"].(string)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		headers []string
+		status  int
+	}{
+		{"no credentials", "GET", "/api/cameras/front-door/led", nil, noCredentials, 401},
+		{"invalid key", "GET", "/api/cameras/front-door/led", nil, as("gtk_invalid"), 401},
+		{"camera headers alone", "GET", "/api/cameras/front-door/led", nil, nil, 401},
+		{"api key header", "GET", "/api/cameras/front-door/led", nil, append([]string{"X-API-Key", secret}, noCredentials...), 200},
+		{"api key", "GET", "/api/cameras/front-door/led", nil, as(secret), 200},
+		{"token", "GET", "/api/cameras/front-door/led", nil, as(token), 200},
+		{"list cameras", "GET", "/api/cameras", nil, as(token), 200},
+		{"register camera", "POST", "/api/cameras", map[string]string{"name": "Porch", "address": "192.168.1.101"}, as(token), 403},
+		{"list keys", "GET", "/api/admin/keys", nil, as(secret), 403},
+		{"whoami", "GET", "/api/auth/whoami", nil, as(token), 200},
+		{"renew token", "POST", "/api/auth/token", nil, as(token), 403},
+		{"admin lists keys", "GET", "/api/admin/keys", nil, as("gtk_admin"), 200},
+		{"revoke key", "DELETE", "/api/admin/keys/" + keyID, nil, as("gtk_admin"), 200},
+		{"token of revoked key", "GET", "/api/cameras/front-door/led", nil, as(token), 401},
+		{"revoked key", "GET", "/api/cameras/front-door/led", nil, as(secret), 401},
+	}
+
+	for _, tt := range tests {
+		resp, body := callAPI(t, app, tt.method, tt.path, tt.body, tt.headers...)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d (%v)", tt.name, tt.status, resp.StatusCode, body)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/health", nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected /health to stay open, got %v %v", resp.StatusCode, err)
+	}
+}
+
+func TestAdminRoutesWithoutAuth(t *testing.T) {
+	pins, _ := tapo.NewFilePinStore("")
+	cameras, _ := registry.Open("")
+	cameras.Create(registry.Camera{ID: "front-door", Name: "Front Door", Address: "192.168.1.100"})
+	trail, _ := audit.Open("")
+
+	openApp, _ := newEmulatedAppWith(t, Dependencies{Pins: pins, Cameras: cameras, Audit: trail})
+	for _, route := range []struct{ method, path string }{
+		{"POST", "/api/cameras"},
+		{"PUT", "/api/cameras/front-door"},
+		{"DELETE", "/api/cameras/front-door"},
+		{"GET", "/api/audit"},
+		{"GET", "/api/audit/export"},
+		{"GET", "/api/admin/vault"},
+		{"POST", "/api/admin/vault/rotate"},
+		{"GET", "/api/admin/pins"},
+		{"GET", "/api/admin/pins/192.168.1.100"},
+		{"DELETE", "/api/admin/pins/192.168.1.100"},
+	} {
+		resp, body := callAPI(t, openApp, route.method, route.path, map[string]string{"name": "Porch"})
+		if resp.StatusCode != fiber.StatusForbidden || errorField(body, "code") != "auth_disabled" {
+			t.Errorf("%s %s: expected 403 auth_disabled without API auth, got %d %v", route.method, route.path, resp.StatusCode, body)
+		}
+	}
+	if _, ok := cameras.Get("front-door"); !ok {
+		t.Error("Expected the camera to stay registered")
+	}
+
+	keys, _ := auth.OpenKeyStore("")
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Pins: pins,
+		Auth: auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+	})
+	resp, body := callAPI(t, app, "GET", "/api/admin/pins", nil, "Authorization", "Bearer gtk_admin")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200 for an admin, got %d (%v)", resp.StatusCode, body)
+	}
+}
+
+func TestCredentialVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cameras.json")
+	key, _ := vault.GenerateKey()
+	v, _ := vault.Load(key, "")
+	cameras, err := registry.Open(path, registry.WithVault(v))
+	if err != nil {
+		t.Fatalf("registry.Open failed: %v", err)
+	}
+
+	keys, _ := auth.OpenKeyStore("")
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras: cameras,
+		Auth:    auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+	})
+	admin := []string{"Authorization", "Bearer gtk_admin"}
+
+	resp, body := callAPI(t, app, "POST", "/api/cameras", map[string]string{
+		"id":       "garage",
+		"name":     "Garage",
+		"address":  "192.168.1.100",
+		"username": emulator.DefaultUsername,
+		"password": emulator.DefaultPassword,
+	}, admin...)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected status 201, got %d (%v)", resp.StatusCode, body)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), `": "`+emulator.DefaultPassword+`"`) {
+		t.Errorf("Registry file contains the plaintext password: %s", data)
+	}
+
+	// The sealed password still unlocks the camera
+	resp, body = callCamera(t, app, "garage", "GET", "/led", nil, append([]string{"X-Tapo-Username", "", "X-Tapo-Password", ""}, admin...)...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected status 200 with the stored password, got %d (%v)", resp.StatusCode, body)
+	}
+
+	for _, path := range []string{"/api/cameras", "/api/cameras/garage", "/api/admin/vault"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer gtk_admin")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if strings.Contains(string(raw), `":"`+emulator.DefaultPassword+`"`) || strings.Contains(string(raw), "password_sealed") {
+			t.Errorf("GET %s exposes the password: %s", path, raw)
+		}
+	}
+
+	resp, body = callAPI(t, app, "POST", "/api/admin/vault/rotate", nil, admin...)
+	result, _ := body["result"].(map[string]interface{})
+	if resp.StatusCode != fiber.StatusOK || result["reencrypted"] != float64(1) {
+		t.Errorf("Expected 1 password re-encrypted, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestRBAC(t *testing.T) {
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	for _, cam := range []registry.Camera{
+		{ID: "front-door", Name: "Front Door", Address: "192.168.1.100", Tags: []string{"outdoor"}},
+		{ID: "lab", Name: "Lab", Address: "192.168.1.101", Tags: []string{"restricted"}},
+	} {
+		cam.Username, cam.Password = emulator.DefaultUsername, emulator.DefaultPassword
+		cameras.Create(cam)
+	}
+	// Without stored credentials, the yard camera uses the defaults
+	cameras.Create(registry.Camera{ID: "yard", Name: "Yard", Address: "192.168.1.102"})
+
+	// Night shift operates outdoor cameras and may only look at the rest
+	_, secret, err := keys.Create("night-shift", false, []rbac.Grant{
+		{Role: "operator", Tags: []string{"outdoor"}},
+		{Role: "viewer", Cameras: []string{"front-door", "192.168.1.102"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Create key failed: %v", err)
+	}
+
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras:         cameras,
+		Auth:            auth.NewService(keys, []byte("test-secret"), time.Hour, ""),
+		DefaultUsername: emulator.DefaultUsername,
+		DefaultPassword: emulator.DefaultPassword,
+	})
+	headers := []string{"Authorization", "Bearer " + secret, "X-Tapo-Username", "", "X-Tapo-Password", ""}
+
+	tests := []struct {
+		method  string
+		path    string
+		body    interface{}
+		status  int
+		missing string
+	}{
+		{"GET", "/api/cameras/front-door/led", nil, 200, ""},
+		{"POST", "/api/cameras/front-door/ptz/step", map[string]int{"direction": 90}, 200, ""},
+		{"POST", "/api/cameras/front-door/presets", map[string]string{"name": "Gate"}, 201, ""},
+		{"POST", "/api/cameras/front-door/presets/1/goto", nil, 200, ""},
+		{"POST", "/api/cameras/front-door/reboot", nil, 403, "system:reboot"},
+		{"POST", "/api/cameras/front-door/storage/format", nil, 403, "storage:format"},
+		{"POST", "/api/cameras/front-door/firmware/upgrade", nil, 403, "firmware:upgrade"},
+		{"PUT", "/api/cameras/front-door/led", map[string]bool{"enabled": false}, 403, "camera:configure"},
+		{"POST", "/api/cameras/front-door/batch", map[string]interface{}{"requests": []interface{}{}}, 403, "batch:execute"},
+		{"GET", "/api/cameras/lab/led", nil, 403, "camera:view"},
+		{"GET", "/api/cameras/192.168.1.101/led", nil, 403, "camera:view"},
+		{"GET", "/api/cameras/192.168.1.102/led", nil, 200, ""},
+		{"POST", "/api/cameras/192.168.1.102/ptz/step", map[string]int{"direction": 90}, 403, "ptz:control"},
+		{"GET", "/api/cameras/lab", nil, 404, ""},
+	}
+
+	for _, tt := range tests {
+		resp, body := callAPI(t, app, tt.method, tt.path, tt.body, headers...)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d (%v)", tt.method, tt.path, tt.status, resp.StatusCode, body)
+			continue
+		}
+		if tt.missing != "" && errorField(body, "missing_permission") != tt.missing {
+			t.Errorf("%s %s: expected missing permission %s, got %v", tt.method, tt.path, tt.missing, body)
+		}
+	}
+
+	_, body := callAPI(t, app, "GET", "/api/cameras", nil, headers...)
+	if list, _ := body["result"].([]interface{}); len(list) != 2 {
+		t.Errorf("Expected only front-door and yard to be listed, got %v", body["result"])
+	}
+}
+
+func TestAuditTrail(t *testing.T) {
+	keys, _ := auth.OpenKeyStore("")
+	cameras, _ := registry.Open("")
+	cameras.Create(registry.Camera{
+		ID:       "front-door",
+		Name:     "Front Door",
+		Address:  "192.168.1.100",
+		Username: emulator.DefaultUsername,
+		Password: emulator.DefaultPassword,
+	})
+	_, secret, _ := keys.Create("night-shift", false, []rbac.Grant{
+		{Role: "operator"},
+		{Permissions: []rbac.Permission{rbac.CameraConfigure}},
+	}, nil)
+	trail, _ := audit.Open("")
+
+	app, _ := newEmulatedAppWith(t, Dependencies{
+		Cameras: cameras,
+		Auth:    auth.NewService(keys, []byte("test-secret"), time.Hour, "gtk_admin"),
+		Audit:   trail,
+	})
+	as := func(credential string) []string {
+		return []string{"Authorization", "Bearer " + credential, "X-Tapo-Username", "", "X-Tapo-Password", ""}
+	}
+
+	requests := []struct {
+		method  string
+		path    string
+		body    interface{}
+		headers []string
+	}{
+		{"PUT", "/api/cameras/front-door/alarm", map[string]interface{}{"enabled": true, "alarm_type": "sound"}, as(secret)},
+		{"GET", "/api/cameras/front-door/alarm", nil, as(secret)},
+		{"POST", "/api/cameras/front-door/reboot", nil, as(secret)},
+		{"DELETE", "/api/cameras/front-door/presets/1", nil, as(secret)},
+		{"POST", "/api/cameras/front-door/storage/format", nil, nil},
+		{"POST", "/api/cameras", map[string]string{"name": "Porch", "address": "192.168.1.101", "username": "admin", "password": "hunter2"}, as("gtk_admin")},
+	}
+	for _, r := range requests {
+		callAPI(t, app, r.method, r.path, r.body, r.headers...)
+	}
+
+	resp, body := callAPI(t, app, "GET", "/api/audit", nil, as(secret)...)
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status 403 querying the audit trail without an admin key, got %d", resp.StatusCode)
+	}
+
+	resp, body = callAPI(t, app, "GET", "/api/audit", nil, as("gtk_admin")...)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%v)", resp.StatusCode, body)
+	}
+	entries, _ := body["result"].([]interface{})
+
+	want := []struct {
+		action  string
+		actor   string
+		status  float64
+		outcome string
+	}{
+		// The unauthenticated format request is not recorded
+		{"CreateCamera", "bootstrap", 201, "success"},
+		{"DeletePreset", "night-shift", 404, "failed"},
+		{"Reboot", "night-shift", 403, "denied"},
+		{"SetAlarm", "night-shift", 200, "success"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d: %v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		e, _ := entries[i].(map[string]interface{})
+		actor, _ := e["actor"].(map[string]interface{})
+		if e["action"] != w.action || e["status"] != w.status || e["outcome"] != w.outcome {
+			t.Errorf("Entry %d: expected %s %v %s, got %v %v %v", i, w.action, w.status, w.outcome, e["action"], e["status"], e["outcome"])
+		}
+		if w.actor != "" && actor["name"] != w.actor {
+			t.Errorf("Entry %d: expected actor %s, got %v", i, w.actor, actor)
+		}
+	}
+
+	setAlarm, _ := entries[3].(map[string]interface{})
+	if setAlarm["camera"] != "front-door" || setAlarm["address"] != "192.168.1.100" {
+		t.Errorf("Expected the camera ID and address, got %v %v", setAlarm["camera"], setAlarm["address"])
+	}
+	if request, _ := setAlarm["request"].(map[string]interface{}); request["enabled"] != true {
+		t.Errorf("Expected the request body, got %v", setAlarm["request"])
+	}
+	if sent, _ := setAlarm["response"].(map[string]interface{}); sent["request_id"] == nil || sent["request_id"] != setAlarm["request_id"] {
+		t.Errorf("Expected the request ID of the response, got %v and %v", setAlarm["request_id"], setAlarm["response"])
+	}
+	if _, ok := setAlarm["duration_ms"].(float64); !ok {
+		t.Errorf("Expected a duration, got %v", setAlarm["duration_ms"])
+	}
+	if reboot, _ := entries[2].(map[string]interface{}); reboot["error"] != "forbidden" {
+		t.Errorf("Expected the reboot error, got %v", reboot["error"])
+	}
+	if deletePreset, _ := entries[1].(map[string]interface{}); deletePreset["error"] != "not_found" {
+		t.Errorf("Expected the camera error, got %v", deletePreset["error"])
+	}
+	if strings.Contains(mustJSON(entries), "hunter2") {
+		t.Error("Expected the camera password to be redacted")
+	}
+
+	_, body = callAPI(t, app, "GET", "/api/audit?camera=front-door&action=setalarm", nil, as("gtk_admin")...)
+	if list, _ := body["result"].([]interface{}); len(list) != 1 {
+		t.Errorf("Expected 1 filtered entry, got %v", body["result"])
+	}
+
+	resp, body = callAPI(t, app, "GET", "/api/audit?since=yesterday", nil, as("gtk_admin")...)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid time, got %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest("GET", "/api/audit/export?outcome=denied", nil)
+	req.Header.Set("Authorization", "Bearer gtk_admin")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected application/x-ndjson, got %s", ct)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"Reboot"`) {
+		t.Errorf("Expected the denied reboot, got %s", data)
+	}
+
+	// Batches record the methods they run, and are found by them
+	callAPI(t, app, "POST", "/api/cameras/front-door/batch", map[string]interface{}{
+		"requests": []map[string]string{{"method": "getLedStatus"}, {"method": "getLensMaskConfig"}},
+	}, as("gtk_admin")...)
+	_, body = callAPI(t, app, "GET", "/api/audit?action=getLensMaskConfig", nil, as("gtk_admin")...)
+	list, _ := body["result"].([]interface{})
+	if len(list) != 1 {
+		t.Fatalf("Expected the batch entry, got %v", body["result"])
+	}
+	if batch, _ := list[0].(map[string]interface{}); batch["action"] != "Batch" || mustJSON(batch["operations"]) != `["getLedStatus","getLensMaskConfig"]` {
+		t.Errorf("Expected the batch operations, got %v %v", batch["action"], batch["operations"])
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
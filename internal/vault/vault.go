@@ -0,0 +1,203 @@
+// Package vault encrypts credentials at rest with AES-256-GCM under a
+// master key ring. The first key seals new values; the others only open
+// values sealed before a rotation.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of a master key: AES-256
+const KeySize = 32
+
+// sealedPrefix versions the sealed format: "v1:<key id>:<base64 nonce+ciphertext>"
+const sealedPrefix = "v1"
+
+// Errors returned by Open
+var (
+	ErrUnknownKey = errors.New("sealed with a key that is not in the vault")
+	ErrCorrupt    = errors.New("sealed value is corrupt or was tampered with")
+)
+
+// Key is a master key
+type Key struct {
+	ID     string
+	secret []byte
+}
+
+// NewKey wraps a 32-byte master key. Its ID is derived from the key so it
+// stays stable across configurations.
+func NewKey(secret []byte) (Key, error) {
+	if len(secret) != KeySize {
+		return Key{}, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(secret))
+	}
+
+	sum := sha256.Sum256(secret)
+	return Key{ID: hex.EncodeToString(sum[:4]), secret: secret}, nil
+}
+
+// GenerateKey returns a new random master key, base64 encoded
+func GenerateKey() (string, error) {
+	secret := make([]byte, KeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// ParseKeys parses base64 master keys separated by commas or newlines.
+// Blank lines and lines starting with '#' are ignored. The first key is
+// the active one.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d is not valid base64", len(keys)+1)
+		}
+		key, err := NewKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d: %w", len(keys)+1, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Load reads the master keys from spec, or from the file at path when spec
+// is empty. It returns nil when neither is set.
+func Load(spec, path string) (*Vault, error) {
+	if spec == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault key file: %w", err)
+		}
+		spec = string(data)
+	}
+	if spec == "" {
+		return nil, nil
+	}
+
+	keys, err := ParseKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+	return New(keys...)
+}
+
+// Vault seals and opens credentials
+type Vault struct {
+	active string
+	aeads  map[string]cipher.AEAD
+	order  []string
+}
+
+// New creates a vault from master keys; keys[0] seals new values
+func New(keys ...Key) (*Vault, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("vault needs at least one master key")
+	}
+
+	v := &Vault{
+		active: keys[0].ID,
+		aeads:  make(map[string]cipher.AEAD, len(keys)),
+	}
+	for _, key := range keys {
+		if _, ok := v.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("master key %s is listed twice", key.ID)
+		}
+
+		block, err := aes.NewCipher(key.secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		v.aeads[key.ID] = aead
+		v.order = append(v.order, key.ID)
+	}
+
+	return v, nil
+}
+
+// ActiveKeyID returns the ID of the key sealing new values
+func (v *Vault) ActiveKeyID() string {
+	return v.active
+}
+
+// KeyIDs returns the IDs of all keys, the active one first
+func (v *Vault) KeyIDs() []string {
+	return append([]string(nil), v.order...)
+}
+
+// Seal encrypts plaintext with the active key. aad binds the value to its
+// owner, e.g. a camera ID, so it cannot be moved to another record.
+func (v *Vault) Seal(plaintext, aad string) (string, error) {
+	aead := v.aeads[v.active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	return sealedPrefix + ":" + v.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed for aad with any key of the vault
+func (v *Vault) Open(sealed, aad string) (string, error) {
+	keyID, data, err := parseSealed(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	aead, ok := v.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", ErrCorrupt
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	return string(plaintext), nil
+}
+
+// SealedKeyID returns the ID of the key a value was sealed with
+func SealedKeyID(sealed string) (string, error) {
+	keyID, _, err := parseSealed(sealed)
+	return keyID, err
+}
+
+// parseSealed splits a sealed value into its key ID and payload
+func parseSealed(sealed string) (string, []byte, error) {
+	parts := strings.SplitN(sealed, ":", 3)
+	if len(parts) != 3 || parts[0] != sealedPrefix {
+		return "", nil, ErrCorrupt
+	}
+
+	data, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, ErrCorrupt
+	}
+	return parts[1], data, nil
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustVault(t *testing.T, spec string) *Vault {
+	t.Helper()
+
+	v, err := Load(spec, "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return v
+}
+
+func TestVault_SealOpen(t *testing.T) {
+	key, _ := GenerateKey()
+	v := mustVault(t, key)
+
+	sealed, err := v.Seal("camera-password", "front-door")
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if strings.Contains(sealed, "camera-password") {
+		t.Error("Sealed value contains the plaintext")
+	}
+	if !strings.HasPrefix(sealed, "v1:"+v.ActiveKeyID()+":") {
+		t.Errorf("Unexpected sealed format: %s", sealed)
+	}
+
+	again, _ := v.Seal("camera-password", "front-door")
+	if again == sealed {
+		t.Error("Expected a fresh nonce for every seal")
+	}
+
+	plaintext, err := v.Open(sealed, "front-door")
+	if err != nil || plaintext != "camera-password" {
+		t.Errorf("Expected camera-password, got %q %v", plaintext, err)
+	}
+
+	tampered := sealed[:len(sealed)-4] + "AAAA"
+	otherKey, _ := GenerateKey()
+
+	tests := []struct {
+		name   string
+		vault  *Vault
+		sealed string
+		aad    string
+		err    error
+	}{
+		{"other record", v, sealed, "garage", ErrCorrupt},
+		{"tampered", v, tampered, "front-door", ErrCorrupt},
+		{"not sealed", v, "camera-password", "front-door", ErrCorrupt},
+		{"unknown key", mustVault(t, otherKey), sealed, "front-door", ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.vault.Open(tt.sealed, tt.aad); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestVault_Rotation(t *testing.T) {
+	oldKey, _ := GenerateKey()
+	newKey, _ := GenerateKey()
+
+	old := mustVault(t, oldKey)
+	sealed, _ := old.Seal("secret", "garage")
+
+	rotated := mustVault(t, newKey+","+oldKey)
+	if rotated.ActiveKeyID() == old.ActiveKeyID() {
+		t.Fatal("Expected the first key to be active")
+	}
+
+	plaintext, err := rotated.Open(sealed, "garage")
+	if err != nil || plaintext != "secret" {
+		t.Fatalf("Expected old value to open after rotation, got %q %v", plaintext, err)
+	}
+
+	resealed, _ := rotated.Seal(plaintext, "garage")
+	if id, _ := SealedKeyID(resealed); id != rotated.ActiveKeyID() {
+		t.Errorf("Expected reseal with key %s, got %s", rotated.ActiveKeyID(), id)
+	}
+	if _, err := mustVault(t, newKey).Open(resealed, "garage"); err != nil {
+		t.Errorf("Expected resealed value to open without the old key, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	key1, _ := GenerateKey()
+	key2, _ := GenerateKey()
+
+	path := filepath.Join(t.TempDir(), "vault.key")
+	os.WriteFile(path, []byte("# active\n"+key1+"\n\n# retired\n"+key2+"\n"), 0o600)
+
+	v, err := Load("", path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if ids := v.KeyIDs(); len(ids) != 2 || ids[0] != v.ActiveKeyID() {
+		t.Errorf("Expected 2 keys with the first active, got %v", ids)
+	}
+
+	if v, err := Load("", ""); v != nil || err != nil {
+		t.Errorf("Expected no vault without keys, got %v %v", v, err)
+	}
+
+	for _, spec := range []string{"not-base64!", "c2hvcnQ=", key1 + "," + key1} {
+		if _, err := Load(spec, ""); err == nil {
+			t.Errorf("Load(%q): expected an error", spec)
+		}
+	}
+}
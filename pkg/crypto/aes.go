@@ -1,3 +1,5 @@
+// Package crypto implements the hashing and AES helpers of the Tapo
+// camera protocol.
 package crypto
 
 import (
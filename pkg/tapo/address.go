@@ -0,0 +1,159 @@
+package tapo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Default camera endpoint used by NewClient
+const (
+	DefaultScheme = "https"
+	DefaultPort   = 443
+)
+
+// endpointOptions holds where the camera API is reached
+type endpointOptions struct {
+	scheme       string
+	port         int
+	basePath     string
+	hostOverride string
+}
+
+// defaultEndpointOptions returns the endpoint used by NewClient
+func defaultEndpointOptions() endpointOptions {
+	return endpointOptions{
+		scheme: DefaultScheme,
+		port:   DefaultPort,
+	}
+}
+
+// WithPort sets the port of the camera API, used when the host passed to
+// NewClient does not include one
+func WithPort(port int) Option {
+	return func(c *Client) {
+		c.endpoint.port = port
+	}
+}
+
+// WithScheme sets the URL scheme, e.g. "http" behind a TLS-terminating
+// reverse proxy
+func WithScheme(scheme string) Option {
+	return func(c *Client) {
+		c.endpoint.scheme = scheme
+	}
+}
+
+// WithBasePath prefixes every request path, e.g. "/front-door" when a
+// reverse proxy routes several cameras by path
+func WithBasePath(path string) Option {
+	return func(c *Client) {
+		path = strings.Trim(path, "/")
+		if path != "" {
+			path = "/" + path
+		}
+		c.endpoint.basePath = path
+	}
+}
+
+// WithHostOverride sets the Host and Referer headers to host instead of
+// the address connected to, for cameras reached through port forwarding or
+// a proxy that routes by host name
+func WithHostOverride(host string) Option {
+	return func(c *Client) {
+		c.endpoint.hostOverride = host
+	}
+}
+
+// ParseAddress splits a camera address into its host and optional port. It
+// accepts host names and IPv4 or IPv6 literals, with or without brackets,
+// optionally followed by ":port".
+func ParseAddress(address string) (host, port string, err error) {
+	switch {
+	case address == "":
+		return "", "", errors.New("empty camera address")
+
+	case strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]"):
+		host = address[1 : len(address)-1]
+
+	case strings.HasPrefix(address, "["), strings.Count(address, ":") == 1:
+		host, port, err = net.SplitHostPort(address)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid camera address %q: %w", address, err)
+		}
+
+	default:
+		host = address
+	}
+
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", fmt.Errorf("invalid port in camera address %q", address)
+		}
+	}
+
+	if strings.HasPrefix(address, "[") && !strings.Contains(host, ":") {
+		return "", "", fmt.Errorf("brackets are only allowed around IPv6 addresses in camera address %q", address)
+	}
+
+	if strings.Contains(host, ":") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", "", fmt.Errorf("invalid IPv6 address in camera address %q", address)
+		}
+	} else if !validHostName(host) {
+		return "", "", fmt.Errorf("invalid host in camera address %q", address)
+	}
+
+	return host, port, nil
+}
+
+// NormalizeAddress validates a camera address and returns it in canonical
+// form: IPv6 hosts lose their brackets unless a port follows
+func NormalizeAddress(address string) (string, error) {
+	host, port, err := ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if port == "" {
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// validHostName reports whether host is a plausible host name or IPv4 address
+func validHostName(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, r := range host {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// address returns the host:port the client connects to
+func (c *Client) address() string {
+	host, port, err := ParseAddress(c.Host)
+	if err != nil {
+		host, port = c.Host, ""
+	}
+	if port == "" {
+		port = strconv.Itoa(c.endpoint.port)
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// hostHeader returns the value of the Host and Referer headers
+func (c *Client) hostHeader() string {
+	if c.endpoint.hostOverride != "" {
+		return c.endpoint.hostOverride
+	}
+	return c.address()
+}
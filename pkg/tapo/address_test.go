@@ -0,0 +1,158 @@
+package tapo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		host    string
+		port    string
+		valid   bool
+	}{
+		{"192.168.1.100", "192.168.1.100", "", true},
+		{"192.168.1.100:8443", "192.168.1.100", "8443", true},
+		{"camera.local", "camera.local", "", true},
+		{"camera.local:443", "camera.local", "443", true},
+		{"fe80::1", "fe80::1", "", true},
+		{"fe80::1%eth0", "fe80::1%eth0", "", true},
+		{"[fe80::1]", "fe80::1", "", true},
+		{"[fe80::1]:8443", "fe80::1", "8443", true},
+		{"", "", "", false},
+		{"192.168.1.100:0", "", "", false},
+		{"192.168.1.100:65536", "", "", false},
+		{"192.168.1.100:http", "", "", false},
+		{"[192.168.1.100]", "", "", false},
+		{"fe80::zz", "", "", false},
+		{"camera/../admin", "", "", false},
+		{"[fe80::1", "", "", false},
+	}
+
+	for _, tt := range tests {
+		host, port, err := ParseAddress(tt.address)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseAddress(%q): expected valid=%v, got error %v", tt.address, tt.valid, err)
+			continue
+		}
+		if host != tt.host || port != tt.port {
+			t.Errorf("ParseAddress(%q): expected %q %q, got %q %q", tt.address, tt.host, tt.port, host, port)
+		}
+	}
+}
+
+func TestClient_Endpoint(t *testing.T) {
+	tests := []struct {
+		host     string
+		opts     []Option
+		base     string
+		referer  string
+		hostHead string
+	}{
+		{"192.168.1.100", nil, "https://192.168.1.100:443", "https://192.168.1.100:443", "192.168.1.100:443"},
+		{"192.168.1.100:8443", nil, "https://192.168.1.100:8443", "https://192.168.1.100:8443", "192.168.1.100:8443"},
+		{"192.168.1.100", []Option{WithPort(8443)}, "https://192.168.1.100:8443", "https://192.168.1.100:8443", "192.168.1.100:8443"},
+		{"192.168.1.100:9000", []Option{WithPort(8443)}, "https://192.168.1.100:9000", "https://192.168.1.100:9000", "192.168.1.100:9000"},
+		{"fe80::1", nil, "https://[fe80::1]:443", "https://[fe80::1]:443", "[fe80::1]:443"},
+		{"[fe80::1]:8443", nil, "https://[fe80::1]:8443", "https://[fe80::1]:8443", "[fe80::1]:8443"},
+		{"proxy.lan", []Option{WithScheme("http"), WithPort(80), WithBasePath("/front-door/")}, "http://proxy.lan:80/front-door", "http://proxy.lan:80", "proxy.lan:80"},
+		{"203.0.113.7:10443", []Option{WithHostOverride("192.168.1.100")}, "https://203.0.113.7:10443", "https://192.168.1.100", "192.168.1.100"},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(tt.host, "admin", "password", tt.opts...)
+
+		if got := client.getBaseURL(); got != tt.base {
+			t.Errorf("%s: expected base URL %s, got %s", tt.host, tt.base, got)
+		}
+		if got := client.defaultHeaders()["Referer"]; got != tt.referer {
+			t.Errorf("%s: expected Referer %s, got %s", tt.host, tt.referer, got)
+		}
+		if got := client.hostHeader(); got != tt.hostHead {
+			t.Errorf("%s: expected Host %s, got %s", tt.host, tt.hostHead, got)
+		}
+	}
+}
+
+func TestClient_NonStandardPort(t *testing.T) {
+	cam := emulator.New()
+	srv := emulator.NewServer(cam)
+	defer srv.Close()
+
+	// No dial override: the client must reach the emulator's random port
+	client := NewClient(strings.TrimPrefix(srv.URL, "https://"), emulator.DefaultUsername, emulator.DefaultPassword)
+	defer client.CloseIdleConnections()
+
+	if _, err := client.GetLEDStatus(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed, got %v", err)
+	}
+}
+
+func TestClient_IPv6(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+
+	cam := emulator.New()
+	srv := httptest.NewUnstartedServer(cam)
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := NewClient(ln.Addr().String(), emulator.DefaultUsername, emulator.DefaultPassword)
+	defer client.CloseIdleConnections()
+
+	if _, err := client.GetLEDStatus(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed, got %v", err)
+	}
+}
+
+func TestClient_BasePathAndHostOverride(t *testing.T) {
+	cam := emulator.New()
+
+	var mu sync.Mutex
+	var hosts, referers []string
+	mux := http.NewServeMux()
+	mux.Handle("/front-door/", http.StripPrefix("/front-door", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		referers = append(referers, r.Header.Get("Referer"))
+		mu.Unlock()
+		cam.ServeHTTP(w, r)
+	})))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewClient(strings.TrimPrefix(srv.URL, "http://"), emulator.DefaultUsername, emulator.DefaultPassword,
+		WithScheme("http"), WithBasePath("front-door"), WithHostOverride("camera.lan"))
+	defer client.CloseIdleConnections()
+
+	if _, err := client.GetLEDStatus(context.Background()); err != nil {
+		t.Fatalf("Expected request to succeed, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hosts) == 0 {
+		t.Fatal("Expected requests under the base path")
+	}
+	for i := range hosts {
+		if hosts[i] != "camera.lan" {
+			t.Errorf("Expected Host camera.lan, got %s", hosts[i])
+		}
+		if referers[i] != "http://camera.lan" {
+			t.Errorf("Expected Referer http://camera.lan, got %s", referers[i])
+		}
+	}
+}
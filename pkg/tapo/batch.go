@@ -0,0 +1,83 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BatchResult is the outcome of one request of a batch. Err is set when the
+// camera reported a non-zero error_code for the method; the other requests
+// of the batch are unaffected.
+type BatchResult struct {
+	Method string
+	Result json.RawMessage
+	Err    error
+}
+
+// Decode unmarshals the result into v, returning Err if the method failed
+func (r BatchResult) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", r.Method, err)
+	}
+	return nil
+}
+
+// ExecuteBatch sends requests to the camera in a single multipleRequest
+func (c *Client) ExecuteBatch(requests []SingleRequest) ([]BatchResult, error) {
+	return c.ExecuteBatchContext(context.Background(), requests)
+}
+
+// ExecuteBatchContext sends requests to the camera in a single
+// multipleRequest and returns one result per request, in order. The error
+// is only set when the batch as a whole failed.
+func (c *Client) ExecuteBatchContext(ctx context.Context, requests []SingleRequest) ([]BatchResult, error) {
+	raw, err := c.execute(ctx, newMultipleRequest(requests))
+	if err != nil {
+		return nil, err
+	}
+
+	return splitResponses(raw, requests)
+}
+
+// splitResponses matches the responses of a multipleRequest result to the
+// requests that produced them. The camera answers in request order.
+func splitResponses(raw json.RawMessage, requests []SingleRequest) ([]BatchResult, error) {
+	var multi MultipleResponse
+	if err := json.Unmarshal(raw, &multi); err != nil {
+		return nil, fmt.Errorf("failed to parse responses: %w", err)
+	}
+
+	results := make([]BatchResult, len(requests))
+	for i, req := range requests {
+		results[i].Method = req.Method
+
+		if i >= len(multi.Responses) {
+			results[i].Err = fmt.Errorf("no response for method %s", req.Method)
+			continue
+		}
+
+		resp := multi.Responses[i]
+		if resp.ErrorCode != 0 {
+			results[i].Err = methodError(resp)
+			continue
+		}
+
+		results[i].Result = resp.Result
+		if len(results[i].Result) == 0 {
+			results[i].Result = json.RawMessage("{}")
+		}
+	}
+
+	return results, nil
+}
+
+// methodError builds the error reported for a single method of a
+// multipleRequest call
+func methodError(resp MethodResponse) *TapoError {
+	body, _ := json.Marshal(resp)
+	return responseError(resp.ErrorCode, body, ErrorMessage(resp.ErrorCode))
+}
@@ -0,0 +1,109 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSplitResponses(t *testing.T) {
+	requests := []SingleRequest{
+		{Method: "getLedStatus"},
+		{Method: "setLedStatus"},
+		{Method: "motorMoveToPreset"},
+		{Method: "getLdc"},
+	}
+	raw := json.RawMessage(`{"responses":[
+		{"method":"getLedStatus","result":{"led":{}},"error_code":0},
+		{"method":"setLedStatus","error_code":0},
+		{"method":"motorMoveToPreset","result":{},"error_code":-64303}
+	]}`)
+
+	results, err := splitResponses(raw, requests)
+	if err != nil {
+		t.Fatalf("splitResponses failed: %v", err)
+	}
+
+	if len(results) != len(requests) {
+		t.Fatalf("Expected %d results, got %d", len(requests), len(results))
+	}
+
+	tests := []struct {
+		method   string
+		expected string
+		code     int
+		wantErr  bool
+	}{
+		{method: "getLedStatus", expected: `{"led":{}}`},
+		{method: "setLedStatus", expected: `{}`},
+		{method: "motorMoveToPreset", code: ErrorCodeCruiseInProgress, wantErr: true},
+		{method: "getLdc", wantErr: true},
+	}
+
+	for i, tt := range tests {
+		result := results[i]
+		if result.Method != tt.method {
+			t.Errorf("Result %d: expected method %s, got %s", i, tt.method, result.Method)
+		}
+
+		if tt.wantErr {
+			if result.Err == nil {
+				t.Errorf("%s: expected error, got nil", tt.method)
+			}
+			var tapoErr *TapoError
+			if tt.code != 0 && (!errors.As(result.Err, &tapoErr) || tapoErr.Code != tt.code) {
+				t.Errorf("%s: expected TapoError %d, got %v", tt.method, tt.code, result.Err)
+			}
+			continue
+		}
+
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.method, result.Err)
+		}
+		if string(result.Result) != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.method, tt.expected, result.Result)
+		}
+	}
+}
+
+func TestClient_ExecuteBatchPartialFailure(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	cam.setResult("getLedStatus", map[string]interface{}{
+		"led": map[string]interface{}{"config": map[string]string{"enabled": "on"}},
+	})
+	cam.setMethodError("motorMoveToPreset", ErrorCodeCruiseInProgress)
+
+	results, err := cam.client().ExecuteBatchContext(context.Background(), []SingleRequest{
+		{Method: "getLedStatus"},
+		{Method: "motorMoveToPreset"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteBatchContext failed: %v", err)
+	}
+
+	var led LEDConfig
+	if err := results[0].Decode(&led); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if led.LED.Config.Enabled != "on" {
+		t.Errorf("Expected LED enabled on, got %s", led.LED.Config.Enabled)
+	}
+
+	var tapoErr *TapoError
+	if !errors.As(results[1].Err, &tapoErr) || tapoErr.Code != ErrorCodeCruiseInProgress {
+		t.Errorf("Expected TapoError %d, got %v", ErrorCodeCruiseInProgress, results[1].Err)
+	}
+}
+
+func TestClient_ExecuteReportsMethodError(t *testing.T) {
+	cam := newFakeCamera(t, false)
+	cam.setMethodError("setLedStatus", ErrorCodeGeneral)
+
+	_, err := cam.client().Execute("setLedStatus", map[string]interface{}{})
+
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || tapoErr.Code != ErrorCodeGeneral {
+		t.Errorf("Expected TapoError %d, got %v", ErrorCodeGeneral, err)
+	}
+}
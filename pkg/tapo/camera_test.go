@@ -0,0 +1,104 @@
+package tapo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+)
+
+// fakeCamera adapts the camera emulator to the client tests
+type fakeCamera struct {
+	*emulator.Camera
+
+	server   *emulator.Server
+	username string
+	password string
+}
+
+// newFakeCamera starts an emulated camera speaking the secure or legacy
+// protocol. The test fails if the client violates the protocol.
+func newFakeCamera(t testing.TB, secure bool) *fakeCamera {
+	t.Helper()
+
+	var opts []emulator.Option
+	if !secure {
+		opts = append(opts, emulator.WithLegacyAuth())
+	}
+
+	cam := &fakeCamera{
+		Camera:   emulator.New(opts...),
+		username: emulator.DefaultUsername,
+		password: emulator.DefaultPassword,
+	}
+	cam.server = emulator.NewServer(cam.Camera)
+	t.Cleanup(func() {
+		cam.server.Close()
+		for _, v := range cam.Violations() {
+			t.Errorf("fake camera: %s", v)
+		}
+	})
+
+	return cam
+}
+
+// client returns a client whose connections are routed to the fake camera
+func (f *fakeCamera) client(opts ...Option) *Client {
+	return NewClient("camera.test", f.username, f.password, append(opts, f.dialOption())...)
+}
+
+// dialOption routes every connection of a client to the fake camera
+func (f *fakeCamera) dialOption() Option {
+	return WithTransportFunc(func(t *http.Transport) {
+		t.DialContext = f.dial
+	})
+}
+
+// dial connects to the fake camera regardless of the requested address
+func (f *fakeCamera) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f.server.DialContext(ctx, network, addr)
+}
+
+// expireSession makes the next n authenticated requests fail with -40401
+func (f *fakeCamera) expireSession(n int) {
+	f.Inject(emulator.Fault{Code: ErrorCodeInvalidToken, Times: n})
+}
+
+// setDelay makes the camera wait before answering each request
+func (f *fakeCamera) setDelay(d time.Duration) {
+	f.SetDelay(d)
+}
+
+// failNext makes the next authenticated request fail with code and result
+func (f *fakeCamera) failNext(code int, result map[string]interface{}) {
+	f.Inject(emulator.Fault{Code: code, Result: result})
+}
+
+// setResult makes the camera answer method with result inside multipleRequest
+func (f *fakeCamera) setResult(method string, result interface{}) {
+	f.Respond(method, result)
+}
+
+// setMethodError makes the camera report code for the next call of method
+// inside multipleRequest
+func (f *fakeCamera) setMethodError(method string, code int) {
+	f.Inject(emulator.Fault{Method: method, Code: code})
+}
+
+// connCount returns the number of TCP connections the camera accepted
+func (f *fakeCamera) connCount() int {
+	return f.server.ConnCount()
+}
+
+// loginCount returns the number of completed logins
+func (f *fakeCamera) loginCount() int {
+	return f.Logins()
+}
+
+// receivedMethods returns the methods of every command the camera executed
+func (f *fakeCamera) receivedMethods() []string {
+	return f.Methods()
+}
@@ -0,0 +1,329 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/budhilaw/gotapo-api/pkg/crypto"
+)
+
+// NewClient creates a new Tapo camera client
+func NewClient(host, username, password string, opts ...Option) *Client {
+	c := &Client{
+		Host:          host,
+		Username:      username,
+		Password:      password,
+		Timeout:       DefaultTimeout,
+		transportOpts: defaultTransportOptions(),
+		endpoint:      defaultEndpointOptions(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// getHTTPClient returns the HTTP client shared by all requests to the
+// camera, building it on first use so connections and TLS sessions are reused
+func (c *Client) getHTTPClient() *http.Client {
+	c.httpOnce.Do(func() {
+		transport := c.transport
+		if transport == nil {
+			transport = newTransport(c.transportOpts, c.Host)
+		}
+
+		c.httpClient = &http.Client{
+			Timeout:   c.Timeout,
+			Transport: transport,
+		}
+	})
+
+	return c.httpClient
+}
+
+// CloseIdleConnections closes idle connections kept open to the camera
+func (c *Client) CloseIdleConnections() {
+	c.getHTTPClient().CloseIdleConnections()
+}
+
+// getBaseURL returns the base URL for the camera
+func (c *Client) getBaseURL() string {
+	return fmt.Sprintf("%s://%s%s", c.endpoint.scheme, c.address(), c.endpoint.basePath)
+}
+
+// getRequestURL returns the URL for requests authenticated with stok
+func (c *Client) getRequestURL(stok string) string {
+	return fmt.Sprintf("%s/stok=%s/ds", c.getBaseURL(), stok)
+}
+
+// IsAuthenticated returns true if the client has a valid session
+func (c *Client) IsAuthenticated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stok != ""
+}
+
+// GetSessionToken returns the current session token
+func (c *Client) GetSessionToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stok
+}
+
+// Authenticate performs the full authentication flow
+func (c *Client) Authenticate() error {
+	return c.AuthenticateContext(context.Background())
+}
+
+// AuthenticateContext performs the full authentication flow, aborting when
+// ctx is cancelled or its deadline passes
+func (c *Client) AuthenticateContext(ctx context.Context) error {
+	if err := c.authLock.Lock(ctx); err != nil {
+		return err
+	}
+	defer c.authLock.Unlock()
+
+	return c.authenticate(ctx)
+}
+
+// ensureAuthenticated logs in unless a session exists. Concurrent callers
+// wait for a single login instead of each starting their own.
+func (c *Client) ensureAuthenticated(ctx context.Context) error {
+	if err := c.authLock.Lock(ctx); err != nil {
+		return err
+	}
+	defer c.authLock.Unlock()
+
+	if c.IsAuthenticated() {
+		return nil
+	}
+	return c.authenticate(ctx)
+}
+
+// authenticate performs the authentication flow and installs the new
+// session; the caller must hold c.authLock
+func (c *Client) authenticate(ctx context.Context) error {
+	if err := c.checkSuspended(); err != nil {
+		return err
+	}
+
+	sess, protocol, err := c.login(ctx)
+	c.recordSuspension(ctx, err)
+	c.observeLogin(protocol, err)
+	c.logLogin(ctx, protocol, err)
+	if c.onLogin != nil {
+		c.onLogin(err)
+	}
+	if err != nil {
+		c.trace(ctx, TraceEvent{Phase: "auth", Message: "login failed", Err: err})
+		return err
+	}
+
+	c.mu.Lock()
+	c.session = *sess
+	c.mu.Unlock()
+
+	if sess.isSecure {
+		c.trace(ctx, TraceEvent{Phase: "auth", Seq: sess.seq, Message: "logged in with secure authentication"})
+	} else {
+		c.trace(ctx, TraceEvent{Phase: "auth", Message: "logged in with legacy authentication"})
+	}
+
+	return nil
+}
+
+// login detects the connection type and runs the matching handshake,
+// returning the protocol it tried
+func (c *Client) login(ctx context.Context) (*session, string, error) {
+	// Try secure authentication first
+	isSecure, err := c.detectConnectionType(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to detect connection type: %w", err)
+	}
+
+	if isSecure {
+		sess, err := c.secureAuthenticate(ctx)
+		return sess, ProtocolSecure, err
+	}
+	sess, err := c.legacyAuthenticate(ctx)
+	return sess, ProtocolLegacy, err
+}
+
+// detectConnectionType checks if the camera supports secure authentication
+func (c *Client) detectConnectionType(ctx context.Context) (bool, error) {
+	req := LoginRequest{
+		Method: "login",
+		Params: LoginParams{
+			EncryptType: "3",
+			Username:    c.Username,
+		},
+	}
+
+	resp, err := c.makeRawRequest(ctx, "login.detect", req)
+	if err != nil {
+		return false, err
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(resp, &loginResp); err != nil {
+		return false, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Suspensions and lockouts are reported to the first login request
+	if tapoErr := responseError(loginResp.ErrorCode, resp, ErrorMessage(loginResp.ErrorCode)); tapoErr.Code == ErrorCodeRateLimited || tapoErr.LockedOut {
+		return false, tapoErr
+	}
+
+	// Error code -40413 with encrypt_type in result indicates secure connection
+	if loginResp.ErrorCode == ErrorCodeLoginRequired {
+		if loginResp.Result.Data != nil && len(loginResp.Result.Data.EncryptType) > 0 {
+			for _, t := range loginResp.Result.Data.EncryptType {
+				if t == "3" {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// secureAuthenticate performs the 3-phase secure authentication
+func (c *Client) secureAuthenticate(ctx context.Context) (*session, error) {
+	sess := &session{isSecure: true}
+
+	// Phase 1: Request server nonce
+	sess.cnonce = crypto.GenerateCnonce()
+
+	req := LoginRequest{
+		Method: "login",
+		Params: LoginParams{
+			Cnonce:      sess.cnonce,
+			EncryptType: "3",
+			Username:    c.Username,
+		},
+	}
+
+	resp, err := c.makeRawRequest(ctx, "login.nonce", req)
+	if err != nil {
+		return nil, fmt.Errorf("phase 1 failed: %w", err)
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(resp, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to parse phase 1 response: %w", err)
+	}
+
+	if loginResp.ErrorCode != 0 || loginResp.Result.Data == nil {
+		return nil, responseError(loginResp.ErrorCode, resp, "failed to get server nonce")
+	}
+
+	sess.nonce = loginResp.Result.Data.Nonce
+	deviceConfirm := loginResp.Result.Data.DeviceConfirm
+
+	// Phase 2: Validate device and generate keys
+	// Try SHA256 first, then MD5
+	hashedPassSHA := crypto.SHA256Hash(c.Password)
+	hashedPassMD5 := crypto.MD5Hash(c.Password)
+
+	var hashedPass string
+	if sess.validateDeviceConfirm(hashedPassSHA, deviceConfirm) {
+		hashedPass = hashedPassSHA
+	} else if sess.validateDeviceConfirm(hashedPassMD5, deviceConfirm) {
+		hashedPass = hashedPassMD5
+	} else {
+		return nil, NewTapoError(ErrorCodeInvalidAuth, "device validation failed - check password")
+	}
+
+	sess.hashedPass = hashedPass
+	sess.generateEncryptionKeys()
+
+	// Phase 3: Complete login
+	digestPasswd := crypto.SHA256Hash(hashedPass + sess.cnonce + sess.nonce)
+
+	req = LoginRequest{
+		Method: "login",
+		Params: LoginParams{
+			Cnonce:       sess.cnonce,
+			EncryptType:  "3",
+			DigestPasswd: digestPasswd + sess.cnonce + sess.nonce,
+			Username:     c.Username,
+		},
+	}
+
+	resp, err = c.makeRawRequest(ctx, "login.digest", req)
+	if err != nil {
+		return nil, fmt.Errorf("phase 3 failed: %w", err)
+	}
+
+	if err := json.Unmarshal(resp, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to parse phase 3 response: %w", err)
+	}
+
+	if loginResp.ErrorCode != 0 {
+		return nil, responseError(loginResp.ErrorCode, resp, ErrorMessage(loginResp.ErrorCode))
+	}
+
+	sess.stok = loginResp.Result.Stok
+	sess.seq = loginResp.Result.StartSeq
+
+	return sess, nil
+}
+
+// validateDeviceConfirm validates the device_confirm value
+func (s *session) validateDeviceConfirm(hashedPass, deviceConfirm string) bool {
+	expected := crypto.SHA256Hash(s.cnonce+hashedPass+s.nonce) + s.nonce + s.cnonce
+	return deviceConfirm == expected
+}
+
+// generateEncryptionKeys generates the AES encryption keys (lsk and ivb)
+func (s *session) generateEncryptionKeys() {
+	hashedKey := crypto.SHA256Hash(s.cnonce + s.hashedPass + s.nonce)
+
+	// lsk = first 16 bytes of SHA256("lsk" + cnonce + nonce + hashedKey)
+	lskFull := crypto.SHA256HashBytes("lsk" + s.cnonce + s.nonce + hashedKey)
+	s.lsk = lskFull[:16]
+
+	// ivb = first 16 bytes of SHA256("ivb" + cnonce + nonce + hashedKey)
+	ivbFull := crypto.SHA256HashBytes("ivb" + s.cnonce + s.nonce + hashedKey)
+	s.ivb = ivbFull[:16]
+}
+
+// legacyAuthenticate performs legacy MD5-based authentication
+func (c *Client) legacyAuthenticate(ctx context.Context) (*session, error) {
+	hashedPass := crypto.MD5Hash(c.Password)
+
+	req := LoginRequest{
+		Method: "login",
+		Params: LoginParams{
+			Hashed:   true,
+			Password: hashedPass,
+			Username: c.Username,
+		},
+	}
+
+	resp, err := c.makeRawRequest(ctx, "login.legacy", req)
+	if err != nil {
+		return nil, fmt.Errorf("legacy auth failed: %w", err)
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(resp, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if loginResp.ErrorCode != 0 {
+		return nil, responseError(loginResp.ErrorCode, resp, ErrorMessage(loginResp.ErrorCode))
+	}
+
+	return &session{
+		stok:       loginResp.Result.Stok,
+		hashedPass: hashedPass,
+	}, nil
+}
@@ -0,0 +1,437 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/crypto"
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+)
+
+func TestNewClient(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+
+	if client.Host != "192.168.1.100" {
+		t.Errorf("Expected host 192.168.1.100, got %s", client.Host)
+	}
+	if client.Username != "admin" {
+		t.Errorf("Expected username admin, got %s", client.Username)
+	}
+	if client.Password != "password" {
+		t.Errorf("Expected password password, got %s", client.Password)
+	}
+	if client.Timeout.Seconds() != 10 {
+		t.Errorf("Expected timeout 10s, got %v", client.Timeout)
+	}
+}
+
+func TestClient_IsAuthenticated(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+
+	if client.IsAuthenticated() {
+		t.Error("New client should not be authenticated")
+	}
+
+	client.stok = "test_token"
+	if !client.IsAuthenticated() {
+		t.Error("Client with stok should be authenticated")
+	}
+}
+
+func TestClient_GetSessionToken(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+
+	if client.GetSessionToken() != "" {
+		t.Error("New client should have empty token")
+	}
+
+	client.stok = "test_token"
+	if client.GetSessionToken() != "test_token" {
+		t.Error("Should return the stok value")
+	}
+}
+
+func TestClient_getBaseURL(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+
+	expected := "https://192.168.1.100:443"
+	if client.getBaseURL() != expected {
+		t.Errorf("Expected %s, got %s", expected, client.getBaseURL())
+	}
+}
+
+func TestClient_getRequestURL(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+	client.stok = "abc123"
+
+	expected := "https://192.168.1.100:443/stok=abc123/ds"
+	if client.getRequestURL(client.stok) != expected {
+		t.Errorf("Expected %s, got %s", expected, client.getRequestURL(client.stok))
+	}
+}
+
+func TestClient_validateDeviceConfirm(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+	client.cnonce = "ABCD1234"
+	client.nonce = "server_nonce"
+
+	hashedPass := crypto.SHA256Hash("password")
+
+	// Generate expected confirm
+	expectedConfirm := crypto.SHA256Hash(client.cnonce+hashedPass+client.nonce) + client.nonce + client.cnonce
+
+	if !client.validateDeviceConfirm(hashedPass, expectedConfirm) {
+		t.Error("Should validate correct device confirm")
+	}
+
+	if client.validateDeviceConfirm(hashedPass, "wrong_confirm") {
+		t.Error("Should not validate incorrect device confirm")
+	}
+}
+
+func TestClient_generateEncryptionKeys(t *testing.T) {
+	client := NewClient("192.168.1.100", "admin", "password")
+	client.cnonce = "ABCD1234"
+	client.nonce = "server_nonce"
+	client.hashedPass = crypto.SHA256Hash("password")
+
+	client.generateEncryptionKeys()
+
+	if len(client.lsk) != 16 {
+		t.Errorf("lsk should be 16 bytes, got %d", len(client.lsk))
+	}
+	if len(client.ivb) != 16 {
+		t.Errorf("ivb should be 16 bytes, got %d", len(client.ivb))
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		code     int
+		expected string
+	}{
+		{ErrorCodeSuccess, "Success"},
+		{ErrorCodeInvalidToken, "Invalid or expired token"},
+		{ErrorCodeRateLimited, "Rate limited - temporary suspension"},
+		{ErrorCodeInvalidAuth, "Invalid authentication data"},
+		{ErrorCodeLoginRequired, "Login required"},
+		{ErrorCodeCruiseInProgress, "Cruise in progress - stop cruise first"},
+		{ErrorCodeGeneral, "General error"},
+		{-99999, "Unknown error"},
+	}
+
+	for _, tt := range tests {
+		result := ErrorMessage(tt.code)
+		if result != tt.expected {
+			t.Errorf("ErrorMessage(%d) = %q, want %q", tt.code, result, tt.expected)
+		}
+	}
+}
+
+func TestTapoError(t *testing.T) {
+	err := NewTapoError(-40401, "Invalid token")
+
+	if err.Code != -40401 {
+		t.Errorf("Expected code -40401, got %d", err.Code)
+	}
+	if err.Message != "Invalid token" {
+		t.Errorf("Expected message 'Invalid token', got %s", err.Message)
+	}
+	if err.Error() != "Invalid token" {
+		t.Errorf("Error() should return message")
+	}
+}
+
+func TestClient_ExecuteReauthenticatesOnExpiredSession(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		t.Run("secure="+strconv.FormatBool(secure), func(t *testing.T) {
+			camera := newFakeCamera(t, secure)
+			client := camera.client()
+
+			if _, err := client.Execute("getLedStatus", nil); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			camera.expireSession(1)
+
+			if _, err := client.Execute("setLedStatus", nil); err != nil {
+				t.Fatalf("Execute should recover from an expired session, got %v", err)
+			}
+			if _, err := client.ExecuteDirect(map[string]string{"method": "get"}); err != nil {
+				t.Fatalf("ExecuteDirect failed after re-authentication: %v", err)
+			}
+
+			if camera.loginCount() != 2 {
+				t.Errorf("Expected 2 logins, got %d", camera.loginCount())
+			}
+
+			methods := camera.receivedMethods()
+			expected := []string{"getLedStatus", "setLedStatus", "get"}
+			if strings.Join(methods, ",") != strings.Join(expected, ",") {
+				t.Errorf("Expected methods %v, got %v", expected, methods)
+			}
+		})
+	}
+}
+
+func TestClient_ExecuteReplaysOnlyOnce(t *testing.T) {
+	camera := newFakeCamera(t, true)
+	client := camera.client()
+
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	camera.expireSession(2)
+
+	_, err := client.Execute("getLedStatus", nil)
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Expected session expired error after one replay, got %v", err)
+	}
+	if camera.loginCount() != 2 {
+		t.Errorf("Expected exactly one re-authentication, got %d logins", camera.loginCount())
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		errMsg  string
+		secLeft int
+	}{
+		{"nested sec_left", `{"error_code":-40404,"result":{"data":{"code":-40404,"sec_left":1800}}}`, "", 1800},
+		{"direct sec_left", `{"error_code":-40404,"result":{"err_msg":"suspended","sec_left":60}}`, "suspended", 60},
+		{"no result", `{"error_code":-40404}`, "", 0},
+		{"invalid json", `not json`, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := responseError(ErrorCodeRateLimited, []byte(tt.body), "rate limited")
+
+			if err.Code != ErrorCodeRateLimited {
+				t.Errorf("Expected code %d, got %d", ErrorCodeRateLimited, err.Code)
+			}
+			if err.ErrMsg != tt.errMsg {
+				t.Errorf("Expected err_msg %q, got %q", tt.errMsg, err.ErrMsg)
+			}
+			if err.SecLeft != tt.secLeft {
+				t.Errorf("Expected sec_left %d, got %d", tt.secLeft, err.SecLeft)
+			}
+			if err.RetryAfter() != time.Duration(tt.secLeft)*time.Second {
+				t.Errorf("Unexpected RetryAfter %v", err.RetryAfter())
+			}
+		})
+	}
+}
+
+func TestClient_LoginLockout(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	client := cam.client()
+
+	cam.Inject(emulator.Fault{
+		Code:   ErrorCodeLoginRequired,
+		Method: "login",
+		Result: map[string]interface{}{
+			"data": map[string]interface{}{"code": ErrorCodeRateLimited, "sec_left": 300},
+		},
+	})
+
+	err := client.AuthenticateContext(context.Background())
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || !tapoErr.LockedOut || tapoErr.SecLeft != 300 {
+		t.Fatalf("Expected a lockout for 300s, got %v", err)
+	}
+	if !errors.Is(err, ErrLockedOut) || !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrAuthentication) {
+		t.Errorf("Expected the lockout to match ErrLockedOut and ErrRateLimited only, got %v", err)
+	}
+
+	if _, err := client.GetLEDStatus(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected calls to fail fast during the lockout, got %v", err)
+	}
+}
+
+func TestClient_ExecuteBlocksDuringSuspension(t *testing.T) {
+	camera := newFakeCamera(t, true)
+	client := camera.client()
+
+	if err := client.Authenticate(); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	camera.failNext(ErrorCodeRateLimited, map[string]interface{}{
+		"data": map[string]interface{}{"code": ErrorCodeRateLimited, "sec_left": 120},
+	})
+
+	_, err := client.Execute("getLedStatus", nil)
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || tapoErr.Code != ErrorCodeRateLimited {
+		t.Fatalf("Expected rate limited error, got %v", err)
+	}
+	if tapoErr.SecLeft != 120 {
+		t.Errorf("Expected sec_left 120, got %d", tapoErr.SecLeft)
+	}
+
+	_, err = client.Execute("getLedStatus", nil)
+	if !errors.As(err, &tapoErr) || tapoErr.Code != ErrorCodeRateLimited {
+		t.Fatalf("Expected request to be blocked during suspension, got %v", err)
+	}
+	if tapoErr.SecLeft <= 0 || tapoErr.SecLeft > 120 {
+		t.Errorf("Expected remaining suspension within 120s, got %d", tapoErr.SecLeft)
+	}
+	if len(camera.receivedMethods()) != 0 {
+		t.Errorf("Blocked request should not reach the camera, got %v", camera.receivedMethods())
+	}
+
+	client.suspendedUntil = time.Now().Add(-time.Second)
+	if _, err := client.Execute("getLedStatus", nil); err != nil {
+		t.Fatalf("Expected requests to resume after the suspension, got %v", err)
+	}
+}
+
+func TestClient_ExecuteContextCancelled(t *testing.T) {
+	camera := newFakeCamera(t, true)
+	client := camera.client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ExecuteContext(ctx, "getLedStatus", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if camera.loginCount() != 0 {
+		t.Errorf("Cancelled request should not log in, got %d logins", camera.loginCount())
+	}
+}
+
+func TestClient_ExecuteDirectContextDeadline(t *testing.T) {
+	camera := newFakeCamera(t, false)
+	client := camera.client()
+
+	if err := client.AuthenticateContext(context.Background()); err != nil {
+		t.Fatalf("AuthenticateContext failed: %v", err)
+	}
+
+	camera.setDelay(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ExecuteDirectContext(ctx, map[string]string{"method": "get"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Request should abort at the deadline, took %v", elapsed)
+	}
+}
+
+func TestClient_ConcurrentExecute(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		t.Run("secure="+strconv.FormatBool(secure), func(t *testing.T) {
+			camera := newFakeCamera(t, secure)
+			client := camera.client()
+
+			const workers = 32
+			var wg sync.WaitGroup
+			errs := make(chan error, workers)
+
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if i%2 == 0 {
+						_, err := client.Execute("getLedStatus", nil)
+						errs <- err
+					} else {
+						_, err := client.ExecuteDirect(map[string]string{"method": "get"})
+						errs <- err
+					}
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("Concurrent execute failed: %v", err)
+				}
+			}
+
+			if camera.loginCount() != 1 {
+				t.Errorf("Concurrent callers should share one login, got %d", camera.loginCount())
+			}
+			if len(camera.receivedMethods()) != workers {
+				t.Errorf("Expected %d commands, got %d", workers, len(camera.receivedMethods()))
+			}
+		})
+	}
+}
+
+func TestClient_ConcurrentExecuteAcrossReauthentication(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		t.Run("secure="+strconv.FormatBool(secure), func(t *testing.T) {
+			camera := newFakeCamera(t, secure)
+			client := camera.client()
+
+			if err := client.Authenticate(); err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+
+			camera.expireSession(1)
+
+			const workers = 16
+			var wg sync.WaitGroup
+			errs := make(chan error, workers)
+
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.Execute("getLedStatus", nil)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("Concurrent execute failed: %v", err)
+				}
+			}
+
+			if camera.loginCount() != 2 {
+				t.Errorf("Expected a single shared re-authentication, got %d logins", camera.loginCount())
+			}
+		})
+	}
+}
+
+func TestCtxMutex_LockCancelled(t *testing.T) {
+	var m ctxMutex
+
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := m.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected waiting Lock to end with the context, got %v", err)
+	}
+
+	m.Unlock()
+	if err := m.Lock(context.Background()); err != nil {
+		t.Errorf("Lock should succeed after Unlock, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ExecuteInto sends method to the camera and decodes its entry of the
+// multipleRequest responses into a T. A non-zero error_code reported for
+// the method is returned as a *TapoError.
+func ExecuteInto[T any](ctx context.Context, c *Client, method string, params interface{}) (*T, error) {
+	results, err := c.ExecuteBatchContext(ctx, []SingleRequest{{Method: method, Params: params}})
+	if err != nil {
+		return nil, err
+	}
+
+	var result T
+	if err := results[0].Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ExecuteDirectInto sends a direct command without multipleRequest wrapper
+// and decodes the response into a T
+func ExecuteDirectInto[T any](ctx context.Context, c *Client, payload interface{}) (*T, error) {
+	raw, err := c.execute(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result T
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// decodeMap decodes a raw result into a generic map
+func decodeMap(raw json.RawMessage, err error) (map[string]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClient_GetDeviceInfo(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	cam.setResult("getDeviceInfo", map[string]interface{}{
+		"device_info": map[string]interface{}{
+			"basic_info": map[string]interface{}{
+				"device_model": "C200",
+				"sw_version":   "1.3.6",
+				"mac":          "AA-BB-CC-DD-EE-FF",
+			},
+		},
+	})
+
+	info, err := cam.client().GetDeviceInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetDeviceInfo failed: %v", err)
+	}
+
+	if info.DeviceModel != "C200" {
+		t.Errorf("Expected device model C200, got %s", info.DeviceModel)
+	}
+	if info.SwVersion != "1.3.6" {
+		t.Errorf("Expected sw version 1.3.6, got %s", info.SwVersion)
+	}
+	if info.MAC != "AA-BB-CC-DD-EE-FF" {
+		t.Errorf("Expected MAC AA-BB-CC-DD-EE-FF, got %s", info.MAC)
+	}
+}
+
+func TestClient_GetSDCardStatus(t *testing.T) {
+	cam := newFakeCamera(t, false)
+	cam.setResult("getSdCardStatus", map[string]interface{}{
+		"harddisk_manage": map[string]interface{}{
+			"hd_info": []interface{}{
+				map[string]interface{}{
+					"hd_info_1": map[string]interface{}{
+						"disk_name":   "1",
+						"status":      "normal",
+						"total_space": "59.5GB",
+						"free_space":  "12.0GB",
+					},
+				},
+			},
+		},
+	})
+
+	cards, err := cam.client().GetSDCardStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetSDCardStatus failed: %v", err)
+	}
+
+	if len(cards) != 1 {
+		t.Fatalf("Expected 1 card, got %d", len(cards))
+	}
+	if cards[0].Status != "normal" || cards[0].TotalSpace != "59.5GB" {
+		t.Errorf("Unexpected card status: %+v", cards[0])
+	}
+}
+
+func TestExecuteInto_MethodError(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	cam.setMethodError("getLedStatus", ErrorCodeGeneral)
+
+	_, err := cam.client().GetLEDStatus(context.Background())
+
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || tapoErr.Code != ErrorCodeGeneral {
+		t.Errorf("Expected TapoError %d, got %v", ErrorCodeGeneral, err)
+	}
+}
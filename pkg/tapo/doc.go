@@ -0,0 +1,27 @@
+// Package tapo is a client for the local HTTPS API of TP-Link Tapo cameras.
+//
+// A Client talks to one camera. It logs in on the first request, choosing
+// the secure (encrypt_type 3) or legacy handshake the camera supports, and
+// keeps the session for later requests. Typed operations such as
+// GetDeviceInfo or SetLEDStatus cover the common settings; Execute,
+// ExecuteBatch and ExecuteInto reach any other method.
+//
+// # Guarantees
+//
+//   - A Client is safe for concurrent use. Secure requests are serialized
+//     so their Seq headers reach the camera in order.
+//   - Every operation honours its context: cancellation or a deadline
+//     aborts a pending login or request.
+//   - When the camera reports an expired session (-40401) the client logs
+//     in again and replays the request once.
+//   - When the camera suspends logins (-40404) further calls fail fast with
+//     a *TapoError carrying SecLeft until the suspension lapses.
+//   - Errors reported by the camera are returned as *TapoError, both for a
+//     whole request and for a single method of a batch. They match an error
+//     kind such as ErrNotFound or ErrConflict with errors.Is.
+//   - Failures to reach the camera are returned as *TransportError and
+//     match ErrTransport, and ErrTimeout when the camera did not answer.
+//
+// A Pool shares clients between callers, keyed by host and username, and
+// evicts idle sessions.
+package tapo
@@ -0,0 +1,503 @@
+// Package emulator implements an in-process Tapo camera for testing clients
+// of the local camera API without hardware.
+//
+// A Camera speaks the same protocol as real firmware: the encrypt_type 3
+// handshake or the legacy MD5 login, securePassthrough AES-CBC framing with
+// Seq and Tapo_tag verification, and the multipleRequest and direct command
+// formats. Settings such as presets, lens mask, detection, LED, audio and
+// alarms are kept in memory so a setter is observed by the next getter.
+// Faults can be injected to exercise error handling.
+package emulator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/crypto"
+)
+
+// Error codes reported by the emulator, matching real firmware
+const (
+	CodeMethodNotExist   = -40105
+	CodeParamNotExist    = -40106
+	CodeSessionExpired   = -40401
+	CodeSuspended        = -40404
+	CodeInvalidAuth      = -40411
+	CodeLoginRequired    = -40413
+	CodePresetNotFound   = -64302
+	CodeCruiseInProgress = -64303
+	CodeGeneral          = -1
+)
+
+// Default credentials accepted by a Camera
+const (
+	DefaultUsername = "admin"
+	DefaultPassword = "password"
+)
+
+// Camera is an emulated Tapo camera. It implements http.Handler; serve it
+// over TLS with NewServer or any HTTPS server.
+type Camera struct {
+	mu sync.Mutex
+
+	username string
+	password string
+	secure   bool
+	delay    time.Duration
+
+	// Current session
+	stok       string
+	cnonce     string
+	nonce      string
+	hashedPass string
+	lsk        []byte
+	ivb        []byte
+	seq        int
+
+	logins     int
+	methods    []string
+	violations []string
+	faults     []Fault
+	responses  map[string]interface{}
+
+	state *state
+}
+
+// Option configures a Camera
+type Option func(*Camera)
+
+// WithCredentials sets the username and password the camera accepts
+func WithCredentials(username, password string) Option {
+	return func(c *Camera) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithLegacyAuth makes the camera use the legacy MD5 login and plaintext
+// commands instead of the encrypt_type 3 handshake
+func WithLegacyAuth() Option {
+	return func(c *Camera) {
+		c.secure = false
+	}
+}
+
+// WithDelay makes the camera wait before answering each request
+func WithDelay(d time.Duration) Option {
+	return func(c *Camera) {
+		c.delay = d
+	}
+}
+
+// New creates an emulated camera with factory settings
+func New(opts ...Option) *Camera {
+	c := &Camera{
+		username:  DefaultUsername,
+		password:  DefaultPassword,
+		secure:    true,
+		responses: make(map[string]interface{}),
+		state:     newState(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fault is an error the camera reports instead of handling a request
+type Fault struct {
+	// Code is the error_code to report
+	Code int
+
+	// Method restricts the fault to one method. Empty fails the next
+	// authenticated request as a whole and "login" fails the next login.
+	// Other values fail that method inside multipleRequest, or the direct
+	// request with that method ("get", "set" or "do").
+	Method string
+
+	// Times is how many requests fail; zero means once
+	Times int
+
+	// SecLeft is reported as result.data.sec_left, as with CodeSuspended
+	SecLeft int
+
+	// Result is sent as the error result when set
+	Result map[string]interface{}
+}
+
+// Inject queues a fault. A whole-request CodeSessionExpired fault also ends
+// the session, so the client has to log in again.
+func (c *Camera) Inject(f Fault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	c.faults = append(c.faults, f)
+}
+
+// Respond makes the camera answer method inside multipleRequest with result
+// instead of its emulated settings
+func (c *Camera) Respond(method string, result interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.responses[method] = result
+}
+
+// SetDelay changes how long the camera waits before answering each request
+func (c *Camera) SetDelay(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delay = d
+}
+
+// ExpireSession ends the current session; the next request fails with
+// CodeSessionExpired
+func (c *Camera) ExpireSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stok = ""
+}
+
+// Logins returns the number of completed logins
+func (c *Camera) Logins() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.logins
+}
+
+// Methods returns the methods of every command the camera executed, in
+// order. Direct requests are recorded by their method ("get", "set", "do").
+func (c *Camera) Methods() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.methods...)
+}
+
+// Violations returns the protocol errors the camera detected, such as an
+// unexpected Seq or an invalid Tapo_tag
+func (c *Camera) Violations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.violations...)
+}
+
+// ServeHTTP answers a camera API request
+func (c *Camera) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	delay := c.delay
+	c.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.URL.Path == "/" {
+		writeJSON(w, c.login(body))
+		return
+	}
+
+	writeJSON(w, c.authenticated(r, body))
+}
+
+// authenticated answers a request sent with a session token; the caller
+// must hold c.mu
+func (c *Camera) authenticated(r *http.Request, body []byte) map[string]interface{} {
+	if c.stok == "" || r.URL.Path != "/stok="+c.stok+"/ds" {
+		return errorResponse(CodeSessionExpired, nil)
+	}
+
+	if f, ok := c.takeFault("", CodeSessionExpired); ok {
+		c.stok = ""
+		return faultResponse(f)
+	}
+
+	if c.secure {
+		c.verifySecureHeaders(r, body)
+	}
+
+	if f, ok := c.takeFault("", 0); ok {
+		return faultResponse(f)
+	}
+
+	if !c.secure {
+		return c.command(body)
+	}
+
+	var secureReq struct {
+		Method string `json:"method"`
+		Params struct {
+			Request string `json:"request"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(body, &secureReq); err != nil || secureReq.Method != "securePassthrough" {
+		c.violation("expected securePassthrough request")
+		return errorResponse(CodeGeneral, nil)
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(secureReq.Params.Request)
+	if err != nil {
+		c.violation("request is not base64: %v", err)
+		return errorResponse(CodeGeneral, nil)
+	}
+	plain, err := crypto.AESDecrypt(encrypted, c.lsk, c.ivb)
+	if err != nil {
+		c.violation("cannot decrypt request: %v", err)
+		return errorResponse(CodeGeneral, nil)
+	}
+
+	respJSON, _ := json.Marshal(c.command(plain))
+	cipherText, _ := crypto.AESEncrypt(respJSON, c.lsk, c.ivb)
+
+	return map[string]interface{}{
+		"error_code": 0,
+		"result": map[string]string{
+			"response": base64.StdEncoding.EncodeToString(cipherText),
+		},
+	}
+}
+
+// verifySecureHeaders checks the Seq and Tapo_tag headers of a secure
+// request and advances the expected sequence number
+func (c *Camera) verifySecureHeaders(r *http.Request, body []byte) {
+	seq, err := strconv.Atoi(r.Header.Get("Seq"))
+	if err != nil || seq != c.seq {
+		c.violation("expected Seq %d, got %q", c.seq, r.Header.Get("Seq"))
+	}
+
+	tag1 := crypto.SHA256Hash(c.hashedPass + c.cnonce)
+	if tag := crypto.SHA256Hash(tag1 + string(body) + strconv.Itoa(seq)); tag != r.Header.Get("Tapo_tag") {
+		c.violation("invalid Tapo_tag for Seq %d", seq)
+	}
+
+	c.seq++
+}
+
+// login implements the secure and legacy login handshakes; the caller must
+// hold c.mu
+func (c *Camera) login(body []byte) map[string]interface{} {
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			Cnonce       string `json:"cnonce"`
+			EncryptType  string `json:"encrypt_type"`
+			Username     string `json:"username"`
+			DigestPasswd string `json:"digest_passwd"`
+			Hashed       bool   `json:"hashed"`
+			Password     string `json:"password"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.Method != "login" {
+		c.violation("expected login request")
+		return errorResponse(CodeGeneral, nil)
+	}
+	p := req.Params
+
+	if f, ok := c.takeFault("login", 0); ok {
+		return faultResponse(f)
+	}
+
+	if !c.secure {
+		if p.Hashed && p.Username == c.username && p.Password == crypto.MD5Hash(c.password) {
+			c.logins++
+			c.stok = "legacy" + strconv.Itoa(c.logins)
+			return map[string]interface{}{"error_code": 0, "result": map[string]string{"stok": c.stok}}
+		}
+		return errorResponse(CodeLoginRequired, map[string]interface{}{})
+	}
+
+	switch {
+	case p.Cnonce == "":
+		return errorResponse(CodeLoginRequired, map[string]interface{}{
+			"data": map[string]interface{}{"encrypt_type": []string{"3"}},
+		})
+
+	case p.DigestPasswd == "":
+		c.cnonce = p.Cnonce
+		c.nonce = fmt.Sprintf("%016X", time.Now().UnixNano())
+		c.hashedPass = crypto.SHA256Hash(c.password)
+		confirm := crypto.SHA256Hash(c.cnonce+c.hashedPass+c.nonce) + c.nonce + c.cnonce
+		return map[string]interface{}{
+			"error_code": 0,
+			"result": map[string]interface{}{"data": map[string]interface{}{
+				"nonce":          c.nonce,
+				"device_confirm": confirm,
+			}},
+		}
+
+	default:
+		expected := crypto.SHA256Hash(c.hashedPass+c.cnonce+c.nonce) + c.cnonce + c.nonce
+		if p.Username != c.username || p.DigestPasswd != expected {
+			return errorResponse(CodeInvalidAuth, nil)
+		}
+
+		hashedKey := crypto.SHA256Hash(c.cnonce + c.hashedPass + c.nonce)
+		c.lsk = crypto.SHA256HashBytes("lsk" + c.cnonce + c.nonce + hashedKey)[:16]
+		c.ivb = crypto.SHA256HashBytes("ivb" + c.cnonce + c.nonce + hashedKey)[:16]
+		c.logins++
+		c.seq = 100 * c.logins
+		c.stok = "secure" + strconv.Itoa(c.logins)
+		return map[string]interface{}{
+			"error_code": 0,
+			"result":     map[string]interface{}{"stok": c.stok, "start_seq": c.seq},
+		}
+	}
+}
+
+// command executes a decrypted command; the caller must hold c.mu
+func (c *Camera) command(body []byte) map[string]interface{} {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.violation("cannot parse command: %v", err)
+		return errorResponse(CodeGeneral, nil)
+	}
+
+	var method string
+	_ = json.Unmarshal(req["method"], &method)
+
+	if method != "multipleRequest" {
+		c.methods = append(c.methods, method)
+		if f, ok := c.takeFault(method, 0); ok {
+			return faultResponse(f)
+		}
+
+		result, code := c.state.direct(method, req)
+		if code != 0 {
+			return errorResponse(code, nil)
+		}
+		result["error_code"] = 0
+		return result
+	}
+
+	var params struct {
+		Requests []struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		} `json:"requests"`
+	}
+	if err := json.Unmarshal(req["params"], &params); err != nil {
+		c.violation("cannot parse multipleRequest: %v", err)
+		return errorResponse(CodeGeneral, nil)
+	}
+
+	responses := make([]map[string]interface{}, 0, len(params.Requests))
+	for _, single := range params.Requests {
+		c.methods = append(c.methods, single.Method)
+		responses = append(responses, c.call(single.Method, single.Params))
+	}
+
+	return map[string]interface{}{
+		"error_code": 0,
+		"result":     map[string]interface{}{"responses": responses},
+	}
+}
+
+// call executes one method of a multipleRequest; the caller must hold c.mu
+func (c *Camera) call(method string, params json.RawMessage) map[string]interface{} {
+	resp := map[string]interface{}{"method": method}
+
+	if f, ok := c.takeFault(method, 0); ok {
+		resp["error_code"] = f.Code
+		resp["result"] = faultResult(f)
+		return resp
+	}
+
+	if result, ok := c.responses[method]; ok {
+		resp["error_code"] = 0
+		resp["result"] = result
+		return resp
+	}
+
+	result, code := c.state.call(method, params)
+	resp["error_code"] = code
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	resp["result"] = result
+	return resp
+}
+
+// takeFault removes and returns the first pending fault for method. A
+// non-zero code only matches faults with that code and a zero code matches
+// any other. The caller must hold c.mu.
+func (c *Camera) takeFault(method string, code int) (Fault, bool) {
+	for i, f := range c.faults {
+		if f.Method != method {
+			continue
+		}
+		if code != 0 && f.Code != code || code == 0 && method == "" && f.Code == CodeSessionExpired {
+			continue
+		}
+
+		f.Times--
+		if f.Times == 0 {
+			c.faults = append(c.faults[:i], c.faults[i+1:]...)
+		} else {
+			c.faults[i] = f
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+// violation records a protocol error; the caller must hold c.mu
+func (c *Camera) violation(format string, args ...interface{}) {
+	c.violations = append(c.violations, fmt.Sprintf(format, args...))
+}
+
+// faultResult builds the error result of an injected fault
+func faultResult(f Fault) map[string]interface{} {
+	result := make(map[string]interface{}, len(f.Result)+1)
+	for k, v := range f.Result {
+		result[k] = v
+	}
+	if f.SecLeft > 0 {
+		result["data"] = map[string]interface{}{"code": f.Code, "sec_left": f.SecLeft}
+	}
+	return result
+}
+
+// faultResponse builds the response of an injected fault
+func faultResponse(f Fault) map[string]interface{} {
+	return errorResponse(f.Code, faultResult(f))
+}
+
+// errorResponse builds an error response with an optional result
+func errorResponse(code int, result map[string]interface{}) map[string]interface{} {
+	resp := map[string]interface{}{"error_code": code}
+	if result != nil {
+		resp["result"] = result
+	}
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,187 @@
+package emulator_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+	"github.com/budhilaw/gotapo-api/pkg/tapo/emulator"
+)
+
+// newClient starts a server for cam and returns a client connected to it
+func newClient(t *testing.T, cam *emulator.Camera) *tapo.Client {
+	t.Helper()
+
+	srv := emulator.NewServer(cam)
+	t.Cleanup(func() {
+		srv.Close()
+		for _, v := range cam.Violations() {
+			t.Errorf("Protocol violation: %s", v)
+		}
+	})
+
+	return tapo.NewClient("camera.test", emulator.DefaultUsername, emulator.DefaultPassword,
+		tapo.WithTransportFunc(func(tr *http.Transport) {
+			tr.DialContext = srv.DialContext
+		}),
+	)
+}
+
+func TestCamera_SettingsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []emulator.Option
+	}{
+		{"secure", nil},
+		{"legacy", []emulator.Option{emulator.WithLegacyAuth()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, emulator.New(tt.opts...))
+			ctx := context.Background()
+
+			if err := client.SetLEDStatus(ctx, false); err != nil {
+				t.Fatalf("SetLEDStatus failed: %v", err)
+			}
+			if led, err := client.GetLEDStatus(ctx); err != nil || led.Enabled != "off" {
+				t.Errorf("Expected LED off, got %+v (%v)", led, err)
+			}
+
+			if err := client.SetLensMask(ctx, true); err != nil {
+				t.Fatalf("SetLensMask failed: %v", err)
+			}
+			if mask, err := client.GetLensMask(ctx); err != nil || mask.Enabled != "on" {
+				t.Errorf("Expected lens mask on, got %+v (%v)", mask, err)
+			}
+
+			if err := client.SetMotionDetection(ctx, true, 80); err != nil {
+				t.Fatalf("SetMotionDetection failed: %v", err)
+			}
+			if motion, err := client.GetMotionDetection(ctx); err != nil || motion.DigitalSensitivity != "80" {
+				t.Errorf("Expected motion sensitivity 80, got %+v (%v)", motion, err)
+			}
+
+			if err := client.SetMicrophone(ctx, tapo.MicrophoneSettings{Mute: "on"}); err != nil {
+				t.Fatalf("SetMicrophone failed: %v", err)
+			}
+			audio, err := client.GetAudioConfig(ctx)
+			if err != nil || audio.Microphone == nil || audio.Microphone.Mute != "on" || audio.Microphone.Volume != "50" {
+				t.Errorf("Expected muted microphone at volume 50, got %+v (%v)", audio, err)
+			}
+
+			alarm := tapo.Chn1AlarmSettings{Enabled: "on", AlarmType: "1", LightType: "0", AlarmMode: []string{"sound"}}
+			if err := client.SetAlarm(ctx, alarm); err != nil {
+				t.Fatalf("SetAlarm failed: %v", err)
+			}
+			if got, err := client.GetAlarmInfo(ctx); err != nil || !reflect.DeepEqual(*got, alarm) {
+				t.Errorf("Expected alarm %+v, got %+v (%v)", alarm, got, err)
+			}
+		})
+	}
+}
+
+func TestCamera_Presets(t *testing.T) {
+	client := newClient(t, emulator.New())
+	ctx := context.Background()
+
+	for _, name := range []string{"Door", "Garden"} {
+		if err := client.SavePreset(ctx, name); err != nil {
+			t.Fatalf("SavePreset(%q) failed: %v", name, err)
+		}
+	}
+	if err := client.DeletePreset(ctx, "1"); err != nil {
+		t.Fatalf("DeletePreset failed: %v", err)
+	}
+
+	presets, err := client.GetPresets(ctx)
+	if err != nil {
+		t.Fatalf("GetPresets failed: %v", err)
+	}
+	expected := tapo.PresetData{ID: []string{"2"}, Name: []string{"Garden"}}
+	if !reflect.DeepEqual(*presets, expected) {
+		t.Errorf("Expected presets %+v, got %+v", expected, *presets)
+	}
+
+	if err := client.GotoPreset(ctx, "1"); err == nil {
+		t.Error("Expected an error moving to a deleted preset")
+	}
+	if err := client.GotoPreset(ctx, "2"); err != nil {
+		t.Errorf("GotoPreset failed: %v", err)
+	}
+}
+
+func TestCamera_CruiseBlocksMotor(t *testing.T) {
+	client := newClient(t, emulator.New())
+	ctx := context.Background()
+
+	if err := client.SavePreset(ctx, "Door"); err != nil {
+		t.Fatalf("SavePreset failed: %v", err)
+	}
+	if err := client.StartCruise(ctx); err != nil {
+		t.Fatalf("StartCruise failed: %v", err)
+	}
+
+	var tapoErr *tapo.TapoError
+	if err := client.GotoPreset(ctx, "1"); !errors.As(err, &tapoErr) || tapoErr.Code != tapo.ErrorCodeCruiseInProgress {
+		t.Errorf("Expected TapoError %d, got %v", tapo.ErrorCodeCruiseInProgress, err)
+	}
+	if err := client.MoveMotorStep(ctx, 90); !errors.As(err, &tapoErr) || tapoErr.Code != tapo.ErrorCodeCruiseInProgress {
+		t.Errorf("Expected TapoError %d, got %v", tapo.ErrorCodeCruiseInProgress, err)
+	}
+
+	if err := client.StopCruise(ctx); err != nil {
+		t.Fatalf("StopCruise failed: %v", err)
+	}
+	if err := client.GotoPreset(ctx, "1"); err != nil {
+		t.Errorf("GotoPreset after StopCruise failed: %v", err)
+	}
+}
+
+func TestCamera_Faults(t *testing.T) {
+	tests := []struct {
+		name   string
+		fault  emulator.Fault
+		code   int
+		logins int
+	}{
+		{"expired session is replayed", emulator.Fault{Code: emulator.CodeSessionExpired}, 0, 2},
+		{"suspension", emulator.Fault{Code: emulator.CodeSuspended, SecLeft: 30}, tapo.ErrorCodeRateLimited, 1},
+		{"method error", emulator.Fault{Method: "getLedStatus", Code: emulator.CodeGeneral}, tapo.ErrorCodeGeneral, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cam := emulator.New()
+			cam.Inject(tt.fault)
+			client := newClient(t, cam)
+
+			_, err := client.GetLEDStatus(context.Background())
+
+			var tapoErr *tapo.TapoError
+			switch {
+			case tt.code == 0 && err != nil:
+				t.Errorf("Expected success, got %v", err)
+			case tt.code != 0 && (!errors.As(err, &tapoErr) || tapoErr.Code != tt.code):
+				t.Errorf("Expected TapoError %d, got %v", tt.code, err)
+			}
+			if tt.fault.SecLeft > 0 && (tapoErr == nil || tapoErr.SecLeft != tt.fault.SecLeft) {
+				t.Errorf("Expected SecLeft %d, got %+v", tt.fault.SecLeft, tapoErr)
+			}
+			if cam.Logins() != tt.logins {
+				t.Errorf("Expected %d logins, got %d", tt.logins, cam.Logins())
+			}
+		})
+	}
+}
+
+func TestCamera_RejectsWrongPassword(t *testing.T) {
+	client := newClient(t, emulator.New(emulator.WithCredentials("admin", "other")))
+
+	if _, err := client.GetLEDStatus(context.Background()); err == nil {
+		t.Error("Expected login with the wrong password to fail")
+	}
+}
@@ -0,0 +1,52 @@
+package emulator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Server serves a Camera over TLS on a local port
+type Server struct {
+	*httptest.Server
+	*Camera
+
+	mu    sync.Mutex
+	conns int
+}
+
+// NewServer starts serving cam over TLS with a self-signed certificate.
+// Close the server when done.
+func NewServer(cam *Camera) *Server {
+	s := &Server{Camera: cam}
+
+	s.Server = httptest.NewUnstartedServer(cam)
+	s.Server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+		}
+	}
+	s.StartTLS()
+
+	return s
+}
+
+// DialContext connects to the server regardless of the requested address.
+// Use it as the DialContext of a client transport to reach the emulator
+// under any camera host name.
+func (s *Server) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, network, s.Listener.Addr().String())
+}
+
+// ConnCount returns the number of TCP connections the server accepted
+func (s *Server) ConnCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conns
+}
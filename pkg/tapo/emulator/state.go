@@ -0,0 +1,446 @@
+package emulator
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// settings maps section -> name -> fields, mirroring the nesting of the
+// camera's get and set requests, e.g. led -> config -> enabled
+type settings map[string]map[string]map[string]interface{}
+
+// preset is a saved PTZ position
+type preset struct {
+	id   string
+	name string
+}
+
+// state is the emulated configuration of a camera
+type state struct {
+	settings     settings
+	presets      []preset
+	nextPresetID int
+	cruising     bool
+	alarming     bool
+}
+
+// Methods answered from a settings section
+var (
+	getMethods = map[string]string{
+		"getDeviceInfo":            "device_info",
+		"getLensMaskConfig":        "lens_mask",
+		"getMediaEncrypt":          "cet",
+		"getDetectionConfig":       "motion_detection",
+		"getPersonDetectionConfig": "people_detection",
+		"getLastAlarmInfo":         "msg_alarm",
+		"getLdc":                   "image",
+		"getLedStatus":             "led",
+		"getRecordPlan":            "record_plan",
+		"getCloudConfig":           "cloud_config",
+	}
+	setMethods = map[string]string{
+		"setLensMaskConfig":        "lens_mask",
+		"setMediaEncrypt":          "cet",
+		"setDetectionConfig":       "motion_detection",
+		"setPersonDetectionConfig": "people_detection",
+		"setLdc":                   "image",
+		"setLedStatus":             "led",
+	}
+)
+
+// newState returns the factory settings of the emulated camera
+func newState() *state {
+	schedule := `["0000-2400:2"]`
+
+	return &state{
+		nextPresetID: 1,
+		settings: settings{
+			"device_info": {
+				"basic_info": {
+					"device_type":  "SMART.IPCAMERA",
+					"device_model": "C200",
+					"device_name":  "C200 2.0",
+					"device_info":  "C200 2.0 IPC",
+					"hw_version":   "2.0",
+					"sw_version":   "1.3.6 Build 230221 Rel.52426n",
+					"device_alias": "Emulated Camera",
+					"features":     "3",
+					"barcode":      "",
+					"mac":          "AA-BB-CC-DD-EE-FF",
+					"dev_id":       "EMULATOR0000000000000000000000000000000",
+					"oem_id":       "EMULATOR0000000000000000000000000",
+					"hw_desc":      "00000000000000000000000000000000",
+				},
+			},
+			"lens_mask":        {"lens_mask_info": {"enabled": "off"}},
+			"cet":              {"media_encrypt": {"enabled": "on"}},
+			"motion_detection": {"motion_det": {"enabled": "on", "digital_sensitivity": "50"}},
+			"people_detection": {"detection": {"enabled": "on", "sensitivity": "50"}},
+			"msg_alarm": {
+				"chn1_msg_alarm_info": {
+					"enabled":    "off",
+					"alarm_type": "0",
+					"light_type": "0",
+					"alarm_mode": []interface{}{"sound", "light"},
+				},
+			},
+			"image": {
+				"common": {"inf_type": "auto"},
+				"switch": {"flip_type": "off"},
+			},
+			"led": {"config": {"enabled": "on"}},
+			"audio_config": {
+				"speaker":    {"volume": "50"},
+				"microphone": {"volume": "50", "mute": "off"},
+			},
+			"record_plan": {
+				"chn1_channel": {
+					"enabled":   "on",
+					"monday":    schedule,
+					"tuesday":   schedule,
+					"wednesday": schedule,
+					"thursday":  schedule,
+					"friday":    schedule,
+					"saturday":  schedule,
+					"sunday":    schedule,
+				},
+			},
+			"motor": {
+				"capability": {
+					"absolute_move_supported": "1",
+					"calibrate_supported":     "1",
+					"limit_supported":         "1",
+					"preset_supported":        "1",
+				},
+			},
+			"function": {
+				"module_spec": {
+					"ptz":           "1",
+					"lens_mask":     "1",
+					"led":           "1",
+					"audio":         "speaker,microphone",
+					"sd_card":       "1",
+					"alarm":         "1",
+					"ai_detection":  "1",
+					"media_encrypt": "1",
+				},
+			},
+			"cloud_config": {
+				"upgrade_info": {"status": "0", "version": "", "release_log": ""},
+			},
+		},
+	}
+}
+
+// call executes a multipleRequest method and returns its result and error code
+func (s *state) call(method string, params json.RawMessage) (map[string]interface{}, int) {
+	if section, ok := getMethods[method]; ok {
+		return s.get(section, params)
+	}
+	if section, ok := setMethods[method]; ok {
+		return nil, s.set(section, params)
+	}
+
+	switch method {
+	case "getClockStatus":
+		now := time.Now()
+		return map[string]interface{}{
+			"system": map[string]interface{}{
+				"clock_status": map[string]interface{}{
+					"seconds_from_1970": now.Unix(),
+					"local_time":        now.Format("2006-01-02 15:04:05"),
+				},
+			},
+		}, 0
+
+	case "getPresetConfig":
+		ids := make([]string, 0, len(s.presets))
+		names := make([]string, 0, len(s.presets))
+		for _, p := range s.presets {
+			ids = append(ids, p.id)
+			names = append(names, p.name)
+		}
+		return map[string]interface{}{
+			"preset": map[string]interface{}{
+				"preset": map[string]interface{}{"id": ids, "name": names},
+			},
+		}, 0
+
+	case "addMotorPostion":
+		var p struct {
+			Preset struct {
+				SetPreset struct {
+					Name string `json:"name"`
+				} `json:"set_preset"`
+			} `json:"preset"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil || p.Preset.SetPreset.Name == "" {
+			return nil, CodeGeneral
+		}
+		id := strconv.Itoa(s.nextPresetID)
+		s.nextPresetID++
+		s.presets = append(s.presets, preset{id: id, name: p.Preset.SetPreset.Name})
+		return map[string]interface{}{"id": id}, 0
+
+	case "motorMoveToPreset":
+		var p struct {
+			Preset struct {
+				GotoPreset struct {
+					ID string `json:"id"`
+				} `json:"goto_preset"`
+			} `json:"preset"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, CodeGeneral
+		}
+		if s.presetIndex(p.Preset.GotoPreset.ID) < 0 {
+			return nil, CodePresetNotFound
+		}
+		if s.cruising {
+			return nil, CodeCruiseInProgress
+		}
+		return nil, 0
+
+	case "deletePreset":
+		var p struct {
+			Preset struct {
+				RemovePreset struct {
+					ID []string `json:"id"`
+				} `json:"remove_preset"`
+			} `json:"preset"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, CodeGeneral
+		}
+		for _, id := range p.Preset.RemovePreset.ID {
+			i := s.presetIndex(id)
+			if i < 0 {
+				return nil, CodePresetNotFound
+			}
+			s.presets = append(s.presets[:i], s.presets[i+1:]...)
+		}
+		return nil, 0
+
+	case "getSdCardStatus":
+		return map[string]interface{}{
+			"harddisk_manage": map[string]interface{}{
+				"hd_info": []interface{}{
+					map[string]interface{}{
+						"hd_info_1": map[string]interface{}{
+							"disk_name":          "1",
+							"type":               "local",
+							"status":             "normal",
+							"detect_status":      "normal",
+							"total_space":        "59.5GB",
+							"free_space":         "42.1GB",
+							"percent":            "100",
+							"loop_record_status": "1",
+							"write_protect":      "0",
+							"rw_attr":            "rw",
+						},
+					},
+				},
+			},
+		}, 0
+
+	case "formatSdCard", "rebootDevice", "checkFirmwareVersionByCloud":
+		return nil, 0
+	}
+
+	return nil, CodeMethodNotExist
+}
+
+// direct executes a direct get, set or do request
+func (s *state) direct(method string, req map[string]json.RawMessage) (map[string]interface{}, int) {
+	switch method {
+	case "get":
+		result := make(map[string]interface{})
+		for section, params := range req {
+			if section == "method" {
+				continue
+			}
+			data, code := s.get(section, params)
+			if code != 0 {
+				return nil, code
+			}
+			result[section] = data[section]
+		}
+		return result, 0
+
+	case "set":
+		for section, params := range req {
+			if section == "method" {
+				continue
+			}
+			wrapped, _ := json.Marshal(map[string]json.RawMessage{section: params})
+			if code := s.set(section, wrapped); code != 0 {
+				return nil, code
+			}
+		}
+		return map[string]interface{}{}, 0
+
+	case "do":
+		return map[string]interface{}{}, s.do(req)
+	}
+
+	return nil, CodeGeneral
+}
+
+// do executes a direct action
+func (s *state) do(req map[string]json.RawMessage) int {
+	if raw, ok := req["motor"]; ok {
+		var motor map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &motor); err != nil {
+			return CodeGeneral
+		}
+		switch {
+		case motor["cruise_stop"] != nil:
+			s.cruising = false
+		case motor["cruise"] != nil:
+			s.cruising = true
+		case s.cruising:
+			return CodeCruiseInProgress
+		case motor["move"] != nil, motor["movestep"] != nil, motor["manual_cali"] != nil:
+		default:
+			return CodeGeneral
+		}
+		return 0
+	}
+
+	if raw, ok := req["msg_alarm"]; ok {
+		var alarm struct {
+			Manual struct {
+				Action string `json:"action"`
+			} `json:"manual_msg_alarm"`
+		}
+		if err := json.Unmarshal(raw, &alarm); err != nil {
+			return CodeGeneral
+		}
+		switch alarm.Manual.Action {
+		case "start":
+			s.alarming = true
+		case "stop":
+			s.alarming = false
+		default:
+			return CodeGeneral
+		}
+		return 0
+	}
+
+	if _, ok := req["cloud_config"]; ok {
+		return 0
+	}
+
+	return CodeGeneral
+}
+
+// get answers a get request for section, e.g. {"led": {"name": ["config"]}}
+func (s *state) get(section string, params json.RawMessage) (map[string]interface{}, int) {
+	stored, ok := s.settings[section]
+	if !ok {
+		return nil, CodeParamNotExist
+	}
+
+	var p map[string]json.RawMessage
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, CodeGeneral
+		}
+	}
+
+	// The query is either nested under the section or given directly
+	query := p["name"]
+	if inner, ok := p[section]; ok {
+		var q map[string]json.RawMessage
+		if err := json.Unmarshal(inner, &q); err != nil {
+			return nil, CodeGeneral
+		}
+		query = q["name"]
+	}
+
+	// Without a name query the whole section is returned
+	var names []string
+	if query == nil {
+		for name := range stored {
+			names = append(names, name)
+		}
+	} else if names, ok = nameList(query); !ok {
+		return nil, CodeGeneral
+	}
+
+	data := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		fields, ok := stored[name]
+		if !ok {
+			return nil, CodeParamNotExist
+		}
+		data[name] = copyFields(fields)
+	}
+
+	return map[string]interface{}{section: data}, 0
+}
+
+// set merges the fields of a set request into section,
+// e.g. {"led": {"config": {"enabled": "off"}}}
+func (s *state) set(section string, params json.RawMessage) int {
+	stored, ok := s.settings[section]
+	if !ok {
+		return CodeParamNotExist
+	}
+
+	// A request without settings changes nothing
+	var p map[string]map[string]map[string]interface{}
+	if len(params) == 0 || string(params) == "null" {
+		return 0
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return CodeGeneral
+	}
+
+	for name, fields := range p[section] {
+		current, ok := stored[name]
+		if !ok {
+			return CodeParamNotExist
+		}
+		for key, value := range fields {
+			current[key] = value
+		}
+	}
+
+	return 0
+}
+
+// presetIndex returns the index of the preset with id, or -1
+func (s *state) presetIndex(id string) int {
+	for i, p := range s.presets {
+		if p.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nameList decodes a "name" query given as a string or a list of strings
+func nameList(raw json.RawMessage) ([]string, bool) {
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil && len(names) > 0 {
+		return names, true
+	}
+
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil && name != "" {
+		return []string{name}, true
+	}
+
+	return nil, false
+}
+
+// copyFields returns a shallow copy of fields so responses do not alias state
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Error codes reported by cameras
+const (
+	ErrorCodeSuccess           = 0
+	ErrorCodeGeneral           = -1
+	ErrorCodeSetParamNotExist  = -40101
+	ErrorCodeMethodNotExist    = -40105
+	ErrorCodeParamNotExist     = -40106
+	ErrorCodeBadCredentials    = -40209
+	ErrorCodeUnsupported       = -40210
+	ErrorCodeInvalidToken      = -40401
+	ErrorCodeRateLimited       = -40404
+	ErrorCodeInvalidAuth       = -40411
+	ErrorCodeLoginRequired     = -40413
+	ErrorCodePresetNotFound    = -64302
+	ErrorCodeCruiseInProgress  = -64303
+	ErrorCodeMotorRangeLimit   = -64304
+	ErrorCodePresetDeleted     = -64321
+	ErrorCodePrivacyModeOn     = -64324
+	ErrorCodeUserNotAuthorized = -71103
+)
+
+// Error kinds, matched with errors.Is. A *TapoError matches the kind of its
+// code, or ErrLockedOut and ErrRateLimited when it reports a login lockout;
+// a *TransportError matches ErrTransport, and ErrTimeout when the camera did
+// not answer in time.
+var (
+	ErrSessionExpired = errors.New("camera session expired")
+	ErrAuthentication = errors.New("camera rejected the credentials")
+	ErrRateLimited    = errors.New("camera temporarily suspended requests")
+	ErrLockedOut      = errors.New("camera locked out logins after failed attempts")
+	ErrPermission     = errors.New("camera user is not authorized")
+	ErrUnsupported    = errors.New("camera does not support the request")
+	ErrInvalidParams  = errors.New("camera rejected the request parameters")
+	ErrNotFound       = errors.New("camera resource not found")
+	ErrConflict       = errors.New("camera cannot do this in its current state")
+	ErrTransport      = errors.New("camera unreachable")
+	ErrTimeout        = errors.New("camera did not answer in time")
+)
+
+// knownError describes a known error code
+type knownError struct {
+	message string
+	kind    error
+}
+
+// knownErrors is the catalogue of error codes cameras are known to report
+var knownErrors = map[int]knownError{
+	ErrorCodeSuccess:           {"Success", nil},
+	ErrorCodeGeneral:           {"General error", nil},
+	ErrorCodeSetParamNotExist:  {"Parameter to set does not exist", ErrInvalidParams},
+	ErrorCodeMethodNotExist:    {"Method does not exist", ErrUnsupported},
+	ErrorCodeParamNotExist:     {"Parameter to get or do does not exist", ErrInvalidParams},
+	ErrorCodeBadCredentials:    {"Invalid login credentials", ErrAuthentication},
+	ErrorCodeUnsupported:       {"Function not supported", ErrUnsupported},
+	ErrorCodeInvalidToken:      {"Invalid or expired token", ErrSessionExpired},
+	ErrorCodeRateLimited:       {"Rate limited - temporary suspension", ErrRateLimited},
+	ErrorCodeInvalidAuth:       {"Invalid authentication data", ErrAuthentication},
+	ErrorCodeLoginRequired:     {"Login required", ErrAuthentication},
+	ErrorCodePresetNotFound:    {"Preset not found", ErrNotFound},
+	ErrorCodeCruiseInProgress:  {"Cruise in progress - stop cruise first", ErrConflict},
+	ErrorCodeMotorRangeLimit:   {"Maximum pan/tilt range reached", ErrInvalidParams},
+	ErrorCodePresetDeleted:     {"Preset was deleted", ErrNotFound},
+	ErrorCodePrivacyModeOn:     {"Privacy mode is on - disable it first", ErrConflict},
+	ErrorCodeUserNotAuthorized: {"User is not authorized", ErrPermission},
+}
+
+// TapoError represents a Tapo API error
+type TapoError struct {
+	Code    int
+	Message string
+
+	// Details reported by the camera alongside the error code
+	ErrMsg  string                 // err_msg text, if any
+	SecLeft int                    // seconds until a -40404 suspension lapses
+	Result  map[string]interface{} // raw error result body
+
+	// LockedOut is set when a login failed because the camera locks out
+	// logins after repeated failures; the error code is that of the failed
+	// login, and the lockout code and SecLeft are nested in result.data
+	LockedOut bool
+}
+
+func (e *TapoError) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the error kind of e's code, so callers can
+// use errors.Is(err, ErrNotFound) instead of comparing codes. A lockout
+// matches ErrLockedOut and ErrRateLimited instead of the kind of its code.
+func (e *TapoError) Is(target error) bool {
+	if e.LockedOut {
+		return target == ErrLockedOut || target == ErrRateLimited
+	}
+	kind := knownErrors[e.Code].kind
+	return kind != nil && kind == target
+}
+
+// Suspended reports whether the camera refuses requests for SecLeft
+// seconds, after rate limiting or a login lockout
+func (e *TapoError) Suspended() bool {
+	return e.SecLeft > 0 && (e.Code == ErrorCodeRateLimited || e.LockedOut)
+}
+
+// RetryAfter returns how long to wait before the camera accepts requests
+// again, or zero when the camera did not report a suspension
+func (e *TapoError) RetryAfter() time.Duration {
+	return time.Duration(e.SecLeft) * time.Second
+}
+
+// ErrorResponse is the body returned with a non-zero error_code
+type ErrorResponse struct {
+	ErrorCode int                    `json:"error_code"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+}
+
+// NewTapoError creates a new TapoError
+func NewTapoError(code int, message string) *TapoError {
+	return &TapoError{Code: code, Message: message}
+}
+
+// ErrorMessage returns a human-readable error message for error codes
+func ErrorMessage(code int) string {
+	if known, ok := knownErrors[code]; ok {
+		return known.message
+	}
+	return "Unknown error"
+}
+
+// TransportError reports a failure to exchange a request with the camera,
+// as opposed to an error code the camera answered with
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying network error
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrTransport, and ErrTimeout when the request timed out
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport || target == ErrTimeout && e.Timeout()
+}
+
+// Timeout reports whether the camera did not answer in time
+func (e *TransportError) Timeout() bool {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,78 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTapoError_Is(t *testing.T) {
+	tests := []struct {
+		code int
+		kind error
+	}{
+		{ErrorCodeInvalidToken, ErrSessionExpired},
+		{ErrorCodeInvalidAuth, ErrAuthentication},
+		{ErrorCodeBadCredentials, ErrAuthentication},
+		{ErrorCodeRateLimited, ErrRateLimited},
+		{ErrorCodeUserNotAuthorized, ErrPermission},
+		{ErrorCodeMethodNotExist, ErrUnsupported},
+		{ErrorCodeUnsupported, ErrUnsupported},
+		{ErrorCodeParamNotExist, ErrInvalidParams},
+		{ErrorCodePresetNotFound, ErrNotFound},
+		{ErrorCodePresetDeleted, ErrNotFound},
+		{ErrorCodeCruiseInProgress, ErrConflict},
+		{ErrorCodePrivacyModeOn, ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(ErrorMessage(tt.code), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", NewTapoError(tt.code, ErrorMessage(tt.code)))
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("Expected code %d to match %v", tt.code, tt.kind)
+			}
+			if errors.Is(err, ErrTransport) {
+				t.Errorf("Camera error %d should not match ErrTransport", tt.code)
+			}
+		})
+	}
+
+	if errors.Is(NewTapoError(ErrorCodeGeneral, ""), ErrNotFound) {
+		t.Error("General error should not match any kind")
+	}
+	if ErrorMessage(-99999) != "Unknown error" {
+		t.Errorf("Expected Unknown error, got %q", ErrorMessage(-99999))
+	}
+}
+
+func TestTransportError_Is(t *testing.T) {
+	refused := &TransportError{Op: "request failed", Err: errors.New("connection refused")}
+	if !errors.Is(refused, ErrTransport) || errors.Is(refused, ErrTimeout) {
+		t.Errorf("Expected ErrTransport without ErrTimeout for %v", refused)
+	}
+
+	timeout := &TransportError{Op: "request failed", Err: context.DeadlineExceeded}
+	if !errors.Is(timeout, ErrTransport) || !errors.Is(timeout, ErrTimeout) {
+		t.Errorf("Expected ErrTransport and ErrTimeout for %v", timeout)
+	}
+}
+
+func TestClient_TransportErrors(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	client := cam.client()
+	ctx := context.Background()
+
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+
+	cam.server.Close()
+	client.CloseIdleConnections()
+
+	_, err := client.GetLEDStatus(ctx)
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || !errors.Is(err, ErrTransport) {
+		t.Errorf("Expected a TransportError, got %v", err)
+	}
+}
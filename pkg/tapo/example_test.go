@@ -0,0 +1,92 @@
+package tapo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/tapo"
+)
+
+func ExampleClient_GetDeviceInfo() {
+	client := tapo.NewClient("192.168.1.100", "admin", "password")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := client.GetDeviceInfo(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(info.DeviceModel, info.SwVersion)
+}
+
+func ExampleClient_ExecuteBatchContext() {
+	client := tapo.NewClient("192.168.1.100", "admin", "password")
+
+	results, err := client.ExecuteBatchContext(context.Background(), []tapo.SingleRequest{
+		{Method: "getLedStatus", Params: map[string]interface{}{
+			"led": map[string]interface{}{"name": []string{"config"}},
+		}},
+		{Method: "motorMoveToPreset", Params: tapo.GotoPresetRequest{
+			Preset: tapo.GotoPreset{GotoPreset: tapo.GotoPresetID{ID: "1"}},
+		}},
+	})
+	if err != nil {
+		// The whole batch failed, e.g. the camera is unreachable
+		log.Fatal(err)
+	}
+
+	// Each method succeeds or fails on its own
+	var led tapo.LEDConfig
+	if err := results[0].Decode(&led); err != nil {
+		log.Println("getLedStatus:", err)
+	}
+	if results[1].Err != nil {
+		log.Println("motorMoveToPreset:", results[1].Err)
+	}
+}
+
+func ExampleExecuteInto() {
+	client := tapo.NewClient("192.168.1.100", "admin", "password")
+
+	config, err := tapo.ExecuteInto[tapo.LensMaskConfig](context.Background(), client, "getLensMaskConfig", map[string]interface{}{
+		"lens_mask": map[string]interface{}{"name": []string{"lens_mask_info"}},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("privacy mode:", config.LensMask.LensMaskInfo.Enabled)
+}
+
+func ExampleTapoError() {
+	client := tapo.NewClient("192.168.1.100", "admin", "password")
+
+	err := client.GotoPreset(context.Background(), "1")
+
+	var tapoErr *tapo.TapoError
+	switch {
+	case errors.As(err, &tapoErr) && tapoErr.Code == tapo.ErrorCodeRateLimited:
+		fmt.Println("camera suspended logins, retry in", tapoErr.RetryAfter())
+	case errors.As(err, &tapoErr) && tapoErr.Code == tapo.ErrorCodeCruiseInProgress:
+		fmt.Println("stop the cruise first")
+	case err != nil:
+		log.Fatal(err)
+	}
+}
+
+func ExamplePool() {
+	pool := tapo.NewPool(5*time.Minute, tapo.WithTimeout(5*time.Second))
+	defer pool.Close()
+
+	// Callers asking for the same camera and user share one session
+	client := pool.Get("192.168.1.100", "admin", "password")
+
+	if err := client.SetLEDStatus(context.Background(), false); err != nil {
+		log.Fatal(err)
+	}
+}
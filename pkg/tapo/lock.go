@@ -0,0 +1,32 @@
+package tapo
+
+import (
+	"context"
+	"sync"
+)
+
+// ctxMutex is a mutex whose acquisition can be abandoned when a context
+// ends. The zero value is unlocked and ready to use.
+type ctxMutex struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// Lock acquires the mutex or returns ctx.Err() if ctx ends first
+func (m *ctxMutex) Lock(ctx context.Context) error {
+	m.once.Do(func() {
+		m.ch = make(chan struct{}, 1)
+	})
+
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock releases the mutex
+func (m *ctxMutex) Unlock() {
+	<-m.ch
+}
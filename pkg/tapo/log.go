@@ -0,0 +1,56 @@
+package tapo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger logs the calls, logins and suspensions of the client to l:
+// calls and logins at debug level, expired sessions at info level, failed
+// logins and suspensions at warn level. Records are logged with the context
+// of the call, so a handler can add request-scoped attributes. Passwords
+// are never logged, but transport errors may include the camera URL with
+// its session token (stok=...), which the handler should redact.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// log writes a record about the camera to the logger of the client
+func (c *Client) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.logger == nil || !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs = append([]slog.Attr{slog.String("host", c.Host)}, attrs...)
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logCall logs a command sent to the camera, see Observer.ObserveCall
+func (c *Client) logCall(ctx context.Context, method string, elapsed time.Duration, err error) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.Float64("elapsed_ms", milliseconds(elapsed)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	c.log(ctx, slog.LevelDebug, "Camera call", attrs...)
+}
+
+// logLogin logs a login attempt, see Observer.ObserveLogin
+func (c *Client) logLogin(ctx context.Context, protocol string, err error) {
+	if err != nil {
+		c.log(ctx, slog.LevelWarn, "Camera login failed",
+			slog.String("protocol", protocol), slog.Any("error", err))
+		return
+	}
+	c.log(ctx, slog.LevelDebug, "Logged in to camera", slog.String("protocol", protocol))
+}
+
+// milliseconds returns d in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
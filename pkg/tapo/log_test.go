@@ -0,0 +1,58 @@
+package tapo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestClient_Logger(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := cam.client(WithLogger(logger))
+	ctx := context.Background()
+
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+	if err := client.GotoPreset(ctx, "9"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="Logged in to camera" host=camera.test protocol=secure`,
+		`level=DEBUG msg="Camera call" host=camera.test method=getLedStatus elapsed_ms=`,
+		`msg="Camera call" host=camera.test method=motorMoveToPreset elapsed_ms=`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in log:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, cam.password) {
+		t.Errorf("Expected the password to be left out of the log:\n%s", out)
+	}
+}
+
+func TestClient_LoggerLevel(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	cam.password = "wrong"
+	var buf bytes.Buffer
+	client := cam.client(WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	if _, err := client.GetLEDStatus(context.Background()); err == nil {
+		t.Fatal("Expected the login to fail")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `level=WARN msg="Camera login failed" host=camera.test protocol=secure error=`) {
+		t.Errorf("Expected the failed login to be logged, got:\n%s", out)
+	}
+	if strings.Contains(out, "Camera call") {
+		t.Errorf("Expected debug records to be left out at info level, got:\n%s", out)
+	}
+}
@@ -1,14 +1,67 @@
 package tapo
 
-import "time"
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
 
-// Client represents a Tapo camera client
+// Client represents a Tapo camera client. Host is a host name or IP
+// address, optionally with a port. A Client is safe for concurrent use;
+// Host, Username, Password and Timeout must not be changed after the first
+// request.
 type Client struct {
 	Host     string
 	Username string
 	Password string
 
-	// Session data
+	// mu guards the session and suspension state below
+	mu sync.Mutex
+	session
+
+	// suspendedUntil blocks requests while the camera rate-limits us (-40404)
+	suspendedUntil time.Time
+
+	// authLock serializes logins so concurrent callers share one re-authentication
+	authLock ctxMutex
+	// seqLock serializes secure requests so Seq headers reach the camera in order
+	seqLock ctxMutex
+
+	// HTTP client timeout
+	Timeout time.Duration
+
+	// Where the camera API is reached
+	endpoint endpointOptions
+
+	// Shared HTTP client, built on first use from transportOpts
+	transportOpts transportOptions
+	httpOnce      sync.Once
+	httpClient    *http.Client
+
+	// transport overrides the default HTTP transport (used by tests)
+	transport http.RoundTripper
+
+	// commands sends decrypted commands; nil uses the camera connection
+	commands CommandTransport
+
+	// tracer receives the protocol trace; nil disables tracing
+	tracer Tracer
+
+	// observer receives call and login measurements; may be nil
+	observer Observer
+
+	// logger receives call, login and suspension records; nil disables logging
+	logger *slog.Logger
+
+	// onLogin is called after each login attempt; the pool uses it to
+	// cache a client only once its credentials have been accepted
+	onLogin func(err error)
+}
+
+// session holds the state negotiated during login
+type session struct {
 	stok       string
 	seq        int
 	lsk        []byte // AES key (16 bytes)
@@ -17,9 +70,6 @@ type Client struct {
 	nonce      string
 	hashedPass string
 	isSecure   bool
-
-	// HTTP client timeout
-	Timeout time.Duration
 }
 
 // LoginRequest represents the login API request
@@ -99,6 +149,24 @@ type APIResponse struct {
 	Result    map[string]interface{} `json:"result,omitempty"`
 }
 
+// rawResponse is an API response whose result is decoded by the caller
+type rawResponse struct {
+	ErrorCode int             `json:"error_code"`
+	Result    json.RawMessage `json:"result,omitempty"`
+}
+
+// MultipleResponse is the result of a multipleRequest call
+type MultipleResponse struct {
+	Responses []MethodResponse `json:"responses"`
+}
+
+// MethodResponse is the result of one method in a multipleRequest call
+type MethodResponse struct {
+	Method    string          `json:"method"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	ErrorCode int             `json:"error_code"`
+}
+
 // SecureResponse contains encrypted response data
 type SecureResponse struct {
 	ErrorCode int          `json:"error_code"`
@@ -164,6 +232,11 @@ type PresetData struct {
 	Name []string `json:"name,omitempty"`
 }
 
+// PresetsResponse is the result of getPresetConfig
+type PresetsResponse struct {
+	Preset PresetConfig `json:"preset"`
+}
+
 // GotoPresetRequest for going to a preset
 type GotoPresetRequest struct {
 	Preset GotoPreset `json:"preset"`
@@ -227,6 +300,11 @@ type DeviceInfo struct {
 	BasicInfo BasicInfo `json:"basic_info"`
 }
 
+// DeviceInfoResponse is the result of getDeviceInfo
+type DeviceInfoResponse struct {
+	DeviceInfo DeviceInfo `json:"device_info"`
+}
+
 // BasicInfo contains basic device information
 type BasicInfo struct {
 	DeviceType  string `json:"device_type"`
@@ -244,6 +322,22 @@ type BasicInfo struct {
 	HwDesc      string `json:"hw_desc"`
 }
 
+// ClockStatusResponse is the result of getClockStatus
+type ClockStatusResponse struct {
+	System ClockSystem `json:"system"`
+}
+
+// ClockSystem contains the clock status
+type ClockSystem struct {
+	ClockStatus ClockStatus `json:"clock_status"`
+}
+
+// ClockStatus contains the device time
+type ClockStatus struct {
+	SecondsFrom1970 int64  `json:"seconds_from_1970"`
+	LocalTime       string `json:"local_time"`
+}
+
 // ==== Privacy Models ====
 
 // LensMaskConfig represents lens mask settings
@@ -261,6 +355,21 @@ type LensMaskEnabled struct {
 	Enabled string `json:"enabled"`
 }
 
+// MediaEncryptConfig represents media encryption settings
+type MediaEncryptConfig struct {
+	Cet MediaEncryptInfo `json:"cet"`
+}
+
+// MediaEncryptInfo contains media encryption data
+type MediaEncryptInfo struct {
+	MediaEncrypt MediaEncryptEnabled `json:"media_encrypt"`
+}
+
+// MediaEncryptEnabled contains enabled flag
+type MediaEncryptEnabled struct {
+	Enabled string `json:"enabled"`
+}
+
 // ==== Detection Models ====
 
 // MotionDetectionConfig represents motion detection settings
@@ -396,7 +505,21 @@ type RecordPlanConfig struct {
 
 // RecordPlanInfo contains recording plan data
 type RecordPlanInfo struct {
-	Name []string `json:"name,omitempty"`
+	Name        []string            `json:"name,omitempty"`
+	Chn1Channel *RecordPlanSchedule `json:"chn1_channel,omitempty"`
+}
+
+// RecordPlanSchedule contains the weekly recording schedule of a channel.
+// Each day holds the camera's schedule string, e.g. ["0000-2400:2"].
+type RecordPlanSchedule struct {
+	Enabled   string `json:"enabled"`
+	Monday    string `json:"monday,omitempty"`
+	Tuesday   string `json:"tuesday,omitempty"`
+	Wednesday string `json:"wednesday,omitempty"`
+	Thursday  string `json:"thursday,omitempty"`
+	Friday    string `json:"friday,omitempty"`
+	Saturday  string `json:"saturday,omitempty"`
+	Sunday    string `json:"sunday,omitempty"`
 }
 
 // SDCardConfig for SD card operations
@@ -410,6 +533,30 @@ type HarddiskInfo struct {
 	FormatHD string   `json:"format_hd,omitempty"`
 }
 
+// SDCardStatusResponse is the result of getSdCardStatus
+type SDCardStatusResponse struct {
+	HarddiskManage SDCardTable `json:"harddisk_manage"`
+}
+
+// SDCardTable contains one entry per disk, keyed "hd_info_<n>"
+type SDCardTable struct {
+	HDInfo []map[string]SDCardInfo `json:"hd_info"`
+}
+
+// SDCardInfo contains the status of an SD card
+type SDCardInfo struct {
+	DiskName         string `json:"disk_name"`
+	Type             string `json:"type,omitempty"`
+	Status           string `json:"status"`
+	DetectStatus     string `json:"detect_status,omitempty"`
+	TotalSpace       string `json:"total_space"`
+	FreeSpace        string `json:"free_space"`
+	Percent          string `json:"percent,omitempty"`
+	LoopRecordStatus string `json:"loop_record_status,omitempty"`
+	WriteProtect     string `json:"write_protect,omitempty"`
+	RwAttr           string `json:"rw_attr,omitempty"`
+}
+
 // ==== System Models ====
 
 // SystemConfig for system operations
@@ -434,52 +581,3 @@ type FirmwareAction struct {
 	FWDownload     string   `json:"fw_download,omitempty"`
 	Name           []string `json:"name,omitempty"`
 }
-
-// ==== Error Codes ====
-
-const (
-	ErrorCodeSuccess          = 0
-	ErrorCodeInvalidToken     = -40401
-	ErrorCodeRateLimited      = -40404
-	ErrorCodeInvalidAuth      = -40411
-	ErrorCodeLoginRequired    = -40413
-	ErrorCodeCruiseInProgress = -64303
-	ErrorCodeGeneral          = -1
-)
-
-// TapoError represents a Tapo API error
-type TapoError struct {
-	Code    int
-	Message string
-}
-
-func (e *TapoError) Error() string {
-	return e.Message
-}
-
-// NewTapoError creates a new TapoError
-func NewTapoError(code int, message string) *TapoError {
-	return &TapoError{Code: code, Message: message}
-}
-
-// ErrorMessage returns a human-readable error message for error codes
-func ErrorMessage(code int) string {
-	switch code {
-	case ErrorCodeSuccess:
-		return "Success"
-	case ErrorCodeInvalidToken:
-		return "Invalid or expired token"
-	case ErrorCodeRateLimited:
-		return "Rate limited - temporary suspension"
-	case ErrorCodeInvalidAuth:
-		return "Invalid authentication data"
-	case ErrorCodeLoginRequired:
-		return "Login required"
-	case ErrorCodeCruiseInProgress:
-		return "Cruise in progress - stop cruise first"
-	case ErrorCodeGeneral:
-		return "General error"
-	default:
-		return "Unknown error"
-	}
-}
@@ -0,0 +1,98 @@
+package tapo
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Login protocols reported to an Observer
+const (
+	ProtocolSecure = "secure"
+	ProtocolLegacy = "legacy"
+)
+
+// Observer receives measurements of the exchanges of a client with its
+// camera, e.g. to export metrics. Its methods are called synchronously and
+// concurrently, so they must be quick and safe for concurrent use.
+type Observer interface {
+	// ObserveCall reports a command sent to the camera. method is the
+	// camera method, multipleRequest for a batch of several methods, or
+	// the method and section of a direct request such as do.motor.
+	// err is the error of the call, or the first error the camera
+	// reported for one of its methods; elapsed includes any login.
+	ObserveCall(host, method string, elapsed time.Duration, err error)
+
+	// ObserveLogin reports a login attempt. protocol is ProtocolSecure or
+	// ProtocolLegacy, or empty when the camera could not be probed.
+	ObserveLogin(host, protocol string, err error)
+}
+
+// WithObserver reports the calls and logins of the client to o
+func WithObserver(o Observer) Option {
+	return func(c *Client) {
+		c.observer = o
+	}
+}
+
+// observeCall reports the outcome of sending payload to the observer and
+// the logger
+func (c *Client) observeCall(ctx context.Context, payload interface{}, result []byte, elapsed time.Duration, err error) {
+	logged := c.logger != nil && c.logger.Enabled(ctx, slog.LevelDebug)
+	if c.observer == nil && !logged {
+		return
+	}
+
+	method := "unknown"
+	switch p := payload.(type) {
+	case MultipleRequest:
+		method = p.Method
+		if len(p.Params.Requests) == 1 {
+			method = p.Params.Requests[0].Method
+		}
+		if err == nil {
+			results, _ := splitResponses(result, p.Params.Requests)
+			for _, r := range results {
+				if r.Err != nil {
+					err = r.Err
+					break
+				}
+			}
+		}
+	case map[string]interface{}:
+		if m, ok := p["method"].(string); ok {
+			method = m
+			for _, section := range sortedKeys(p) {
+				if section != "method" {
+					method += "." + section
+					break
+				}
+			}
+		}
+	}
+
+	if c.observer != nil {
+		c.observer.ObserveCall(c.Host, method, elapsed, err)
+	}
+	if logged {
+		c.logCall(ctx, method, elapsed, err)
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// observeLogin reports a login attempt to the observer
+func (c *Client) observeLogin(protocol string, err error) {
+	if c.observer != nil {
+		c.observer.ObserveLogin(c.Host, protocol, err)
+	}
+}
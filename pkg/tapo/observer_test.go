@@ -0,0 +1,87 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// observerRecorder collects the measurements reported to an Observer
+type observerRecorder struct {
+	mu     sync.Mutex
+	calls  []string
+	logins []string
+}
+
+func (r *observerRecorder) ObserveCall(host, method string, elapsed time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code := 0
+	var tapoErr *TapoError
+	if errors.As(err, &tapoErr) {
+		code = tapoErr.Code
+	}
+	r.calls = append(r.calls, fmt.Sprintf("%s %s %d", host, method, code))
+}
+
+func (r *observerRecorder) ObserveLogin(host, protocol string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logins = append(r.logins, fmt.Sprintf("%s %s %v", host, protocol, err == nil))
+}
+
+func TestClient_Observer(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		cam := newFakeCamera(t, secure)
+		rec := &observerRecorder{}
+		client := cam.client(WithObserver(rec))
+		ctx := context.Background()
+
+		if _, err := client.GetLEDStatus(ctx); err != nil {
+			t.Fatalf("GetLEDStatus failed: %v", err)
+		}
+		if err := client.MoveMotor(ctx, "10", "0"); err != nil {
+			t.Fatalf("MoveMotor failed: %v", err)
+		}
+		if err := client.GotoPreset(ctx, "9"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+
+		protocol := ProtocolLegacy
+		if secure {
+			protocol = ProtocolSecure
+		}
+		if got := strings.Join(rec.logins, "|"); got != "camera.test "+protocol+" true" {
+			t.Errorf("Expected one %s login, got %q", protocol, got)
+		}
+
+		want := "camera.test getLedStatus 0|camera.test do.motor 0|camera.test motorMoveToPreset -64302"
+		if got := strings.Join(rec.calls, "|"); got != want {
+			t.Errorf("Expected calls %q, got %q", want, got)
+		}
+	}
+}
+
+func TestClient_ObserverFailedLogin(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	cam.password = "wrong"
+	rec := &observerRecorder{}
+	client := cam.client(WithObserver(rec))
+
+	if _, err := client.GetLEDStatus(context.Background()); err == nil {
+		t.Fatal("Expected the login to fail")
+	}
+
+	if got := strings.Join(rec.logins, "|"); got != "camera.test secure false" {
+		t.Errorf("Expected a failed secure login, got %q", got)
+	}
+	if len(rec.calls) != 1 || !strings.HasPrefix(rec.calls[0], "camera.test getLedStatus ") {
+		t.Errorf("Expected the failed call, got %v", rec.calls)
+	}
+}
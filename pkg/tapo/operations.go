@@ -0,0 +1,463 @@
+package tapo
+
+import (
+	"context"
+	"strconv"
+)
+
+// GetDeviceInfo returns the basic device information
+func (c *Client) GetDeviceInfo(ctx context.Context) (*BasicInfo, error) {
+	resp, err := ExecuteInto[DeviceInfoResponse](ctx, c, "getDeviceInfo", DeviceInfoRequest{
+		DeviceInfo: DeviceInfoParams{Name: []string{"basic_info"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.DeviceInfo.BasicInfo, nil
+}
+
+// GetClockStatus returns the device time
+func (c *Client) GetClockStatus(ctx context.Context) (*ClockStatus, error) {
+	resp, err := ExecuteInto[ClockStatusResponse](ctx, c, "getClockStatus", map[string]interface{}{
+		"system": map[string]string{"name": "clock_status"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.System.ClockStatus, nil
+}
+
+// GetLensMask returns the privacy mode (lens mask) state
+func (c *Client) GetLensMask(ctx context.Context) (*LensMaskEnabled, error) {
+	resp, err := ExecuteInto[LensMaskConfig](ctx, c, "getLensMaskConfig", map[string]interface{}{
+		"lens_mask": map[string]interface{}{"name": []string{"lens_mask_info"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.LensMask.LensMaskInfo, nil
+}
+
+// GetMediaEncrypt returns the media encryption state
+func (c *Client) GetMediaEncrypt(ctx context.Context) (*MediaEncryptEnabled, error) {
+	resp, err := ExecuteInto[MediaEncryptConfig](ctx, c, "getMediaEncrypt", map[string]interface{}{
+		"cet": map[string]interface{}{"name": []string{"media_encrypt"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Cet.MediaEncrypt, nil
+}
+
+// GetMotionDetection returns the motion detection settings
+func (c *Client) GetMotionDetection(ctx context.Context) (*MotionDetSettings, error) {
+	resp, err := ExecuteInto[MotionDetectionConfig](ctx, c, "getDetectionConfig", map[string]interface{}{
+		"motion_detection": map[string]interface{}{"name": []string{"motion_det"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.MotionDetection.MotionDet, nil
+}
+
+// GetPersonDetection returns the person detection settings
+func (c *Client) GetPersonDetection(ctx context.Context) (*PersonDetSettings, error) {
+	resp, err := ExecuteInto[PersonDetectionConfig](ctx, c, "getPersonDetectionConfig", map[string]interface{}{
+		"people_detection": map[string]interface{}{"name": []string{"detection"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.PeopleDetection.Detection, nil
+}
+
+// GetAlarmInfo returns the channel 1 alarm settings
+func (c *Client) GetAlarmInfo(ctx context.Context) (*Chn1AlarmSettings, error) {
+	resp, err := ExecuteInto[AlarmConfig](ctx, c, "getLastAlarmInfo", map[string]interface{}{
+		"msg_alarm": map[string]interface{}{"name": []string{"chn1_msg_alarm_info"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.MsgAlarm.Chn1AlarmInfo == nil {
+		return &Chn1AlarmSettings{}, nil
+	}
+	return resp.MsgAlarm.Chn1AlarmInfo, nil
+}
+
+// GetImageSettings returns the common and switch (flip) image settings
+func (c *Client) GetImageSettings(ctx context.Context) (*ImageSettings, error) {
+	resp, err := ExecuteInto[ImageConfig](ctx, c, "getLdc", map[string]interface{}{
+		"image": map[string]interface{}{"name": []string{"common", "switch"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Image, nil
+}
+
+// GetLEDStatus returns the status LED state
+func (c *Client) GetLEDStatus(ctx context.Context) (*LEDEnabled, error) {
+	resp, err := ExecuteInto[LEDConfig](ctx, c, "getLedStatus", map[string]interface{}{
+		"led": map[string]interface{}{"name": []string{"config"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.LED.Config, nil
+}
+
+// GetPresets returns the saved preset IDs and names
+func (c *Client) GetPresets(ctx context.Context) (*PresetData, error) {
+	resp, err := ExecuteInto[PresetsResponse](ctx, c, "getPresetConfig", map[string]interface{}{
+		"preset": map[string]interface{}{"name": []string{"preset"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Preset.Preset, nil
+}
+
+// GetRecordPlan returns the recording schedule of channel 1
+func (c *Client) GetRecordPlan(ctx context.Context) (*RecordPlanSchedule, error) {
+	resp, err := ExecuteInto[RecordPlanConfig](ctx, c, "getRecordPlan", map[string]interface{}{
+		"record_plan": map[string]interface{}{"name": []string{"chn1_channel"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.RecordPlan.Chn1Channel == nil {
+		return &RecordPlanSchedule{}, nil
+	}
+	return resp.RecordPlan.Chn1Channel, nil
+}
+
+// GetSDCardStatus returns the status of each SD card in camera order; a
+// slot without a card reports the status "offline"
+func (c *Client) GetSDCardStatus(ctx context.Context) ([]SDCardInfo, error) {
+	resp, err := ExecuteInto[SDCardStatusResponse](ctx, c, "getSdCardStatus", map[string]interface{}{
+		"harddisk_manage": map[string]interface{}{"table": []string{"hd_info"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]SDCardInfo, 0, len(resp.HarddiskManage.HDInfo))
+	for _, entry := range resp.HarddiskManage.HDInfo {
+		for _, card := range entry {
+			cards = append(cards, card)
+		}
+	}
+	return cards, nil
+}
+
+// onOff converts a flag to the camera's "on"/"off" representation
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// call runs a multipleRequest method whose result carries no data
+func (c *Client) call(ctx context.Context, method string, params interface{}) error {
+	results, err := c.ExecuteBatchContext(ctx, []SingleRequest{{Method: method, Params: params}})
+	if err != nil {
+		return err
+	}
+	return results[0].Err
+}
+
+// do sends a direct request whose response carries no data
+func (c *Client) do(ctx context.Context, payload interface{}) error {
+	_, err := c.execute(ctx, payload)
+	return err
+}
+
+// ==== PTZ ====
+
+// MoveMotor moves the camera to the x/y coordinates
+func (c *Client) MoveMotor(ctx context.Context, x, y string) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"motor": MotorMove{
+			Move: &MoveCoords{XCoord: x, YCoord: y},
+		},
+	})
+}
+
+// MoveMotorStep moves the camera one step towards direction, in degrees:
+// 0 = right, 90 = up, 180 = left, 270 = down
+func (c *Client) MoveMotorStep(ctx context.Context, direction int) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"motor": MotorMove{
+			Step: &MoveStep{Direction: strconv.Itoa(direction)},
+		},
+	})
+}
+
+// CalibrateMotor starts motor calibration
+func (c *Client) CalibrateMotor(ctx context.Context) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"motor": map[string]interface{}{
+			"manual_cali": "",
+		},
+	})
+}
+
+// GetMotorCapability returns the motor capability info as reported by the camera
+func (c *Client) GetMotorCapability(ctx context.Context) (map[string]interface{}, error) {
+	return c.getDirect(ctx, "motor", map[string]interface{}{
+		"name": []string{"capability"},
+	})
+}
+
+// StartCruise starts cruise (patrol) mode
+func (c *Client) StartCruise(ctx context.Context) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"motor": CruiseMotor{
+			Cruise: &CruiseCoord{Coord: "0"},
+		},
+	})
+}
+
+// StopCruise stops cruise (patrol) mode
+func (c *Client) StopCruise(ctx context.Context) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"motor": CruiseMotor{
+			CruiseStop: &struct{}{},
+		},
+	})
+}
+
+// ==== Presets ====
+
+// SavePreset saves the current position as a preset called name
+func (c *Client) SavePreset(ctx context.Context, name string) error {
+	// The API method name really is misspelled
+	return c.call(ctx, "addMotorPostion", SetPresetRequest{
+		Preset: SetPreset{SetPreset: SetPresetData{Name: name, SavePTZ: "1"}},
+	})
+}
+
+// GotoPreset moves the camera to the preset with id
+func (c *Client) GotoPreset(ctx context.Context, id string) error {
+	return c.call(ctx, "motorMoveToPreset", GotoPresetRequest{
+		Preset: GotoPreset{GotoPreset: GotoPresetID{ID: id}},
+	})
+}
+
+// DeletePreset removes the preset with id
+func (c *Client) DeletePreset(ctx context.Context, id string) error {
+	return c.call(ctx, "deletePreset", DeletePresetRequest{
+		Preset: DeletePreset{RemovePreset: RemovePresetData{ID: []string{id}}},
+	})
+}
+
+// ==== Device ====
+
+// GetModuleSpec returns the module specifications as reported by the camera
+func (c *Client) GetModuleSpec(ctx context.Context) (map[string]interface{}, error) {
+	return c.getDirect(ctx, "function", map[string]interface{}{
+		"name": []string{"module_spec"},
+	})
+}
+
+// getDirect sends a direct get request for section and returns the
+// sections the camera answers with
+func (c *Client) getDirect(ctx context.Context, section string, params interface{}) (map[string]interface{}, error) {
+	result, err := c.ExecuteDirectContext(ctx, map[string]interface{}{
+		"method": "get",
+		section:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delete(result, "error_code")
+	return result, nil
+}
+
+// ==== Privacy ====
+
+// SetLensMask enables or disables privacy mode (lens mask)
+func (c *Client) SetLensMask(ctx context.Context, enabled bool) error {
+	return c.call(ctx, "setLensMaskConfig", LensMaskConfig{
+		LensMask: LensMaskInfo{LensMaskInfo: LensMaskEnabled{Enabled: onOff(enabled)}},
+	})
+}
+
+// SetMediaEncrypt enables or disables media encryption
+func (c *Client) SetMediaEncrypt(ctx context.Context, enabled bool) error {
+	return c.call(ctx, "setMediaEncrypt", MediaEncryptConfig{
+		Cet: MediaEncryptInfo{MediaEncrypt: MediaEncryptEnabled{Enabled: onOff(enabled)}},
+	})
+}
+
+// ==== Detection ====
+
+// SetMotionDetection enables or disables motion detection. A sensitivity of
+// zero keeps the current sensitivity.
+func (c *Client) SetMotionDetection(ctx context.Context, enabled bool, sensitivity int) error {
+	settings := MotionDetSettings{Enabled: onOff(enabled)}
+	if sensitivity > 0 {
+		settings.DigitalSensitivity = strconv.Itoa(sensitivity)
+	}
+
+	return c.call(ctx, "setDetectionConfig", MotionDetectionConfig{
+		MotionDetection: MotionDetInfo{MotionDet: settings},
+	})
+}
+
+// SetPersonDetection enables or disables person detection. A sensitivity of
+// zero keeps the current sensitivity.
+func (c *Client) SetPersonDetection(ctx context.Context, enabled bool, sensitivity int) error {
+	settings := PersonDetSettings{Enabled: onOff(enabled)}
+	if sensitivity > 0 {
+		settings.Sensitivity = strconv.Itoa(sensitivity)
+	}
+
+	return c.call(ctx, "setPersonDetectionConfig", PersonDetectionConfig{
+		PeopleDetection: PersonDetInfo{Detection: settings},
+	})
+}
+
+// ==== Alarm ====
+
+// SetAlarm updates the channel 1 alarm settings
+func (c *Client) SetAlarm(ctx context.Context, settings Chn1AlarmSettings) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "set",
+		"msg_alarm": AlarmInfo{
+			Chn1AlarmInfo: &settings,
+		},
+	})
+}
+
+// StartManualAlarm starts the siren and light alarm
+func (c *Client) StartManualAlarm(ctx context.Context) error {
+	return c.manualAlarm(ctx, "start")
+}
+
+// StopManualAlarm stops a manually started alarm
+func (c *Client) StopManualAlarm(ctx context.Context) error {
+	return c.manualAlarm(ctx, "stop")
+}
+
+// manualAlarm sends a manual alarm action
+func (c *Client) manualAlarm(ctx context.Context, action string) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"msg_alarm": AlarmInfo{
+			ManualAlarm: &ManualAlarmAction{Action: action},
+		},
+	})
+}
+
+// ==== Image ====
+
+// SetFlip sets the image flip mode, e.g. "off" or "center"
+func (c *Client) SetFlip(ctx context.Context, flipType string) error {
+	return c.call(ctx, "setLdc", ImageConfig{
+		Image: ImageSettings{Switch: &SwitchSettings{FlipType: flipType}},
+	})
+}
+
+// SetNightMode sets the day/night mode: "auto", "on" (night) or "off" (day)
+func (c *Client) SetNightMode(ctx context.Context, mode string) error {
+	return c.call(ctx, "setLdc", ImageConfig{
+		Image: ImageSettings{Common: &CommonImageSettings{InfType: mode}},
+	})
+}
+
+// ==== LED ====
+
+// SetLEDStatus enables or disables the status LED
+func (c *Client) SetLEDStatus(ctx context.Context, enabled bool) error {
+	return c.call(ctx, "setLedStatus", LEDConfig{
+		LED: LEDSettings{Config: LEDEnabled{Enabled: onOff(enabled)}},
+	})
+}
+
+// ==== Audio ====
+
+// GetAudioConfig returns the speaker and microphone settings
+func (c *Client) GetAudioConfig(ctx context.Context) (*AudioSettings, error) {
+	resp, err := ExecuteDirectInto[AudioConfig](ctx, c, map[string]interface{}{
+		"method": "get",
+		"audio_config": AudioSettings{
+			Name: []string{"microphone", "speaker"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.AudioConfig, nil
+}
+
+// SetSpeakerVolume sets the speaker volume (0-100)
+func (c *Client) SetSpeakerVolume(ctx context.Context, volume int) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "set",
+		"audio_config": AudioSettings{
+			Speaker: &SpeakerSettings{Volume: strconv.Itoa(volume)},
+		},
+	})
+}
+
+// SetMicrophone updates the microphone settings; empty fields are unchanged
+func (c *Client) SetMicrophone(ctx context.Context, settings MicrophoneSettings) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "set",
+		"audio_config": AudioSettings{
+			Microphone: &settings,
+		},
+	})
+}
+
+// ==== Recording ====
+
+// FormatSDCard formats the SD card, erasing all recordings
+func (c *Client) FormatSDCard(ctx context.Context) error {
+	return c.call(ctx, "formatSdCard", SDCardConfig{
+		HarddiskManage: HarddiskInfo{FormatHD: "1"},
+	})
+}
+
+// ==== System ====
+
+// Reboot restarts the camera
+func (c *Client) Reboot(ctx context.Context) error {
+	return c.call(ctx, "rebootDevice", SystemConfig{
+		System: SystemAction{Reboot: "null"},
+	})
+}
+
+// CheckFirmwareUpdate asks the camera to check for new firmware and returns
+// the check result followed by the upgrade info
+func (c *Client) CheckFirmwareUpdate(ctx context.Context) ([]BatchResult, error) {
+	return c.ExecuteBatchContext(ctx, []SingleRequest{
+		{
+			Method: "checkFirmwareVersionByCloud",
+			Params: FirmwareConfig{CloudConfig: FirmwareAction{CheckFWVersion: "null"}},
+		},
+		{
+			Method: "getCloudConfig",
+			Params: FirmwareConfig{CloudConfig: FirmwareAction{Name: []string{"upgrade_info"}}},
+		},
+	})
+}
+
+// StartFirmwareUpgrade starts downloading and installing new firmware
+func (c *Client) StartFirmwareUpgrade(ctx context.Context) error {
+	return c.do(ctx, map[string]interface{}{
+		"method": "do",
+		"cloud_config": FirmwareAction{
+			FWDownload: "null",
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Operations(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	client := cam.client()
+	ctx := context.Background()
+
+	tests := []struct {
+		method string
+		call   func() error
+	}{
+		{"setLedStatus", func() error { return client.SetLEDStatus(ctx, true) }},
+		{"setLensMaskConfig", func() error { return client.SetLensMask(ctx, false) }},
+		{"addMotorPostion", func() error { return client.SavePreset(ctx, "Door") }},
+		{"motorMoveToPreset", func() error { return client.GotoPreset(ctx, "1") }},
+		{"do", func() error { return client.MoveMotorStep(ctx, 90) }},
+		{"set", func() error { return client.SetSpeakerVolume(ctx, 50) }},
+	}
+
+	var expected []string
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Errorf("%s failed: %v", tt.method, err)
+		}
+		expected = append(expected, tt.method)
+	}
+
+	if methods := cam.receivedMethods(); !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Expected methods %v, got %v", expected, methods)
+	}
+}
+
+func TestClient_OperationMethodError(t *testing.T) {
+	cam := newFakeCamera(t, false)
+	cam.setMethodError("motorMoveToPreset", ErrorCodeCruiseInProgress)
+
+	err := cam.client().GotoPreset(context.Background(), "1")
+
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || tapoErr.Code != ErrorCodeCruiseInProgress {
+		t.Errorf("Expected TapoError %d, got %v", ErrorCodeCruiseInProgress, err)
+	}
+}
+
+func TestResponseData(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"result", `{"error_code":0,"result":{"led":{}}}`, `{"led":{}}`},
+		{"direct get", `{"error_code":0,"motor":{}}`, `{"error_code":0,"motor":{}}`},
+	}
+
+	for _, tt := range tests {
+		var resp rawResponse
+		if err := json.Unmarshal([]byte(tt.body), &resp); err != nil {
+			t.Fatalf("%s: unmarshal failed: %v", tt.name, err)
+		}
+
+		if got := string(responseData([]byte(tt.body), resp)); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package tapo
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Default transport settings used by NewClient
+const (
+	DefaultTimeout         = 10 * time.Second
+	DefaultMaxIdleConns    = 2
+	DefaultIdleConnTimeout = 30 * time.Second
+	DefaultTLSSessionCache = 8
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// transportOptions holds the settings of the shared HTTP transport
+type transportOptions struct {
+	keepAlive       bool
+	maxIdleConns    int
+	idleConnTimeout time.Duration
+	tlsSessionCache int
+	pins            PinStore
+	proxy           *url.URL
+	dial            DialFunc
+	tune            []func(*http.Transport)
+}
+
+// defaultTransportOptions returns the transport settings used by NewClient
+func defaultTransportOptions() transportOptions {
+	return transportOptions{
+		keepAlive:       true,
+		maxIdleConns:    DefaultMaxIdleConns,
+		idleConnTimeout: DefaultIdleConnTimeout,
+		tlsSessionCache: DefaultTLSSessionCache,
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request to the camera
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.Timeout = timeout
+	}
+}
+
+// WithKeepAlive enables or disables connection reuse. Disable it for
+// firmware that drops persistent connections; requests then carry
+// "Connection: close" as the official app does.
+func WithKeepAlive(enabled bool) Option {
+	return func(c *Client) {
+		c.transportOpts.keepAlive = enabled
+	}
+}
+
+// WithMaxIdleConns sets how many idle connections are kept to the camera
+func WithMaxIdleConns(n int) Option {
+	return func(c *Client) {
+		c.transportOpts.maxIdleConns = n
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection is kept open
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.transportOpts.idleConnTimeout = timeout
+	}
+}
+
+// WithTLSSessionCache sets the number of TLS sessions cached for resumption.
+// Zero disables session resumption.
+func WithTLSSessionCache(size int) Option {
+	return func(c *Client) {
+		c.transportOpts.tlsSessionCache = size
+	}
+}
+
+// WithTransportFunc registers a function that can adjust the HTTP transport
+// after the other options have been applied
+func WithTransportFunc(fn func(*http.Transport)) Option {
+	return func(c *Client) {
+		c.transportOpts.tune = append(c.transportOpts.tune, fn)
+	}
+}
+
+// newTransport builds the HTTP transport shared by all requests to host
+func newTransport(opts transportOptions, host string) *http.Transport {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // Tapo cameras use self-signed certs
+	}
+	if opts.tlsSessionCache > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(opts.tlsSessionCache)
+	}
+	if opts.pins != nil {
+		// Self-signed certificates cannot be chain-verified, so trust the
+		// first one seen and require it afterwards
+		tlsConfig.VerifyConnection = pinVerifier(opts.pins, host)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		DisableKeepAlives:   !opts.keepAlive,
+		MaxIdleConns:        opts.maxIdleConns,
+		MaxIdleConnsPerHost: opts.maxIdleConns,
+		IdleConnTimeout:     opts.idleConnTimeout,
+		TLSHandshakeTimeout: DefaultTimeout,
+	}
+
+	if opts.proxy != nil {
+		transport.Proxy = http.ProxyURL(opts.proxy)
+	}
+	if opts.dial != nil {
+		transport.DialContext = opts.dial
+	}
+
+	for _, tune := range opts.tune {
+		tune(transport)
+	}
+
+	return transport
+}
@@ -0,0 +1,190 @@
+package tapo
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCertificateMismatch is matched by errors.Is when a camera presents a
+// certificate other than the one pinned on first use
+var ErrCertificateMismatch = errors.New("camera certificate does not match pinned fingerprint")
+
+// CertificateMismatchError reports a camera certificate that differs from its pin
+type CertificateMismatchError struct {
+	Host     string
+	Expected string
+	Got      string
+}
+
+func (e *CertificateMismatchError) Error() string {
+	return fmt.Sprintf("certificate for %s does not match pinned fingerprint (expected %s, got %s)", e.Host, e.Expected, e.Got)
+}
+
+// Unwrap allows errors.Is(err, ErrCertificateMismatch)
+func (e *CertificateMismatchError) Unwrap() error {
+	return ErrCertificateMismatch
+}
+
+// Pin is the certificate fingerprint trusted for a camera
+type Pin struct {
+	Host        string    `json:"host"`
+	Fingerprint string    `json:"fingerprint"`
+	PinnedAt    time.Time `json:"pinned_at"`
+}
+
+// PinStore persists the certificate pins of cameras
+type PinStore interface {
+	Get(host string) (Pin, bool)
+	Set(pin Pin) error
+	Delete(host string) error
+	List() []Pin
+}
+
+// WithPinStore enables trust-on-first-use certificate pinning: the first
+// certificate seen for the camera is recorded in store and later
+// connections must present the same certificate
+func WithPinStore(store PinStore) Option {
+	return func(c *Client) {
+		c.transportOpts.pins = store
+	}
+}
+
+// CertificateFingerprint returns the hex SHA-256 fingerprint of cert
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// pinVerifier returns a tls.Config.VerifyConnection callback enforcing the
+// pin of host, recording the certificate when no pin exists yet
+func pinVerifier(store PinStore, host string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("camera %s presented no certificate", host)
+		}
+
+		fingerprint := CertificateFingerprint(cs.PeerCertificates[0])
+
+		pin, ok := store.Get(host)
+		if !ok {
+			if err := store.Set(Pin{Host: host, Fingerprint: fingerprint, PinnedAt: time.Now().UTC()}); err != nil {
+				return fmt.Errorf("failed to pin certificate: %w", err)
+			}
+			return nil
+		}
+
+		if pin.Fingerprint != fingerprint {
+			return &CertificateMismatchError{Host: host, Expected: pin.Fingerprint, Got: fingerprint}
+		}
+
+		return nil
+	}
+}
+
+// FilePinStore is a PinStore persisted as a JSON file
+type FilePinStore struct {
+	mu   sync.Mutex
+	path string
+	pins map[string]Pin
+}
+
+// NewFilePinStore opens the pin file at path, creating it on first write
+func NewFilePinStore(path string) (*FilePinStore, error) {
+	s := &FilePinStore{
+		path: path,
+		pins: make(map[string]Pin),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pin file: %w", err)
+	}
+
+	var pins []Pin
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pin file: %w", err)
+	}
+	for _, pin := range pins {
+		s.pins[pin.Host] = pin
+	}
+
+	return s, nil
+}
+
+// Get returns the pin of host
+func (s *FilePinStore) Get(host string) (Pin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pin, ok := s.pins[host]
+	return pin, ok
+}
+
+// Set stores pin and persists the file
+func (s *FilePinStore) Set(pin Pin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pins[pin.Host] = pin
+	return s.save()
+}
+
+// Delete removes the pin of host so the next certificate is trusted again
+func (s *FilePinStore) Delete(host string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pins, host)
+	return s.save()
+}
+
+// List returns all pins sorted by host
+func (s *FilePinStore) List() []Pin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted()
+}
+
+// sorted returns the pins ordered by host; the caller must hold s.mu
+func (s *FilePinStore) sorted() []Pin {
+	pins := make([]Pin, 0, len(s.pins))
+	for _, pin := range s.pins {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Host < pins[j].Host
+	})
+	return pins
+}
+
+// save atomically writes the pins to disk; the caller must hold s.mu
+func (s *FilePinStore) save() error {
+	data, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create pin directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write pin file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,106 @@
+package tapo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilePinStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.json")
+
+	store, err := NewFilePinStore(path)
+	if err != nil {
+		t.Fatalf("NewFilePinStore failed: %v", err)
+	}
+
+	if _, ok := store.Get("192.168.1.100"); ok {
+		t.Error("New store should have no pins")
+	}
+
+	pin := Pin{Host: "192.168.1.100", Fingerprint: "abc", PinnedAt: time.Now().UTC()}
+	if err := store.Set(pin); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Set(Pin{Host: "192.168.1.101", Fingerprint: "def"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	reloaded, err := NewFilePinStore(path)
+	if err != nil {
+		t.Fatalf("Reloading pin file failed: %v", err)
+	}
+
+	got, ok := reloaded.Get("192.168.1.100")
+	if !ok || got.Fingerprint != "abc" {
+		t.Errorf("Expected persisted pin abc, got %+v", got)
+	}
+	if pins := reloaded.List(); len(pins) != 2 || pins[0].Host != "192.168.1.100" {
+		t.Errorf("Expected 2 pins sorted by host, got %+v", pins)
+	}
+
+	if err := reloaded.Delete("192.168.1.100"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := reloaded.Get("192.168.1.100"); ok {
+		t.Error("Pin should be removed after Delete")
+	}
+}
+
+func TestClient_PinsCertificateOnFirstUse(t *testing.T) {
+	camera := newFakeCamera(t, true)
+
+	store, err := NewFilePinStore(filepath.Join(t.TempDir(), "pins.json"))
+	if err != nil {
+		t.Fatalf("NewFilePinStore failed: %v", err)
+	}
+
+	client := camera.client(WithPinStore(store))
+	if _, err := client.Execute("getLedStatus", nil); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	pin, ok := store.Get("camera.test")
+	if !ok {
+		t.Fatal("Expected certificate to be pinned on first use")
+	}
+	if expected := CertificateFingerprint(camera.server.Certificate()); pin.Fingerprint != expected {
+		t.Errorf("Expected fingerprint %s, got %s", expected, pin.Fingerprint)
+	}
+
+	// A fresh client must accept the pinned certificate
+	if _, err := camera.client(WithPinStore(store)).Execute("getLedStatus", nil); err != nil {
+		t.Errorf("Pinned certificate should be accepted, got %v", err)
+	}
+}
+
+func TestClient_RejectsMismatchedCertificate(t *testing.T) {
+	camera := newFakeCamera(t, true)
+
+	store, err := NewFilePinStore(filepath.Join(t.TempDir(), "pins.json"))
+	if err != nil {
+		t.Fatalf("NewFilePinStore failed: %v", err)
+	}
+	if err := store.Set(Pin{Host: "camera.test", Fingerprint: "0000"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	client := camera.client(WithPinStore(store))
+	_, err = client.Execute("getLedStatus", nil)
+
+	if !errors.Is(err, ErrCertificateMismatch) {
+		t.Fatalf("Expected ErrCertificateMismatch, got %v", err)
+	}
+
+	var mismatch *CertificateMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected *CertificateMismatchError, got %T", err)
+	}
+	if mismatch.Expected != "0000" || mismatch.Host != "camera.test" {
+		t.Errorf("Unexpected mismatch details: %+v", mismatch)
+	}
+	if camera.loginCount() != 0 {
+		t.Error("Credentials must not be sent to a camera with a mismatched certificate")
+	}
+}
@@ -0,0 +1,210 @@
+package tapo
+
+import (
+	"sync"
+	"time"
+)
+
+// Pool caches authenticated clients keyed by camera host and username so
+// that repeated API calls reuse the camera session instead of logging in
+// again on every request
+type Pool struct {
+	mu          sync.Mutex
+	clients     map[string]*pooledClient
+	idleTimeout time.Duration
+	opts        []Option
+	hostOpts    func(host string) []Option
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// pooledClient is a cached client together with its bookkeeping data
+type pooledClient struct {
+	client   *Client
+	password string
+	lastUsed time.Time
+}
+
+// NewPool creates a session pool that evicts clients unused for idleTimeout.
+// A zero or negative idleTimeout keeps sessions until they are evicted
+// explicitly. opts are applied to every client the pool creates.
+func NewPool(idleTimeout time.Duration, opts ...Option) *Pool {
+	p := &Pool{
+		clients:     make(map[string]*pooledClient),
+		idleTimeout: idleTimeout,
+		opts:        opts,
+		stop:        make(chan struct{}),
+	}
+
+	if idleTimeout > 0 {
+		go p.janitor()
+	}
+
+	return p
+}
+
+// SetHostOptions registers a function returning extra options for the
+// clients of host, such as a proxy for cameras on a remote site. They are
+// applied after the pool options. Cached sessions are dropped so the next
+// request uses the new options.
+func (p *Pool) SetHostOptions(fn func(host string) []Option) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.hostOpts = fn
+	for key, entry := range p.clients {
+		entry.client.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+}
+
+// poolKey builds the cache key for a camera session
+func poolKey(host, username string) string {
+	return host + "|" + username
+}
+
+// Get returns the cached client for host and username, creating one when
+// none exists. A session is never shared with a caller holding another
+// password: such a caller gets a new client, which replaces the cached one
+// only once it has logged in, so a wrong password cannot evict a working
+// session.
+func (p *Pool) Get(host, username, password string) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := poolKey(host, username)
+	now := time.Now()
+
+	entry, ok := p.clients[key]
+	if ok && entry.password == password {
+		entry.lastUsed = now
+		return entry.client
+	}
+
+	opts := p.opts
+	if p.hostOpts != nil {
+		opts = append(opts[:len(opts):len(opts)], p.hostOpts(host)...)
+	}
+
+	client := NewClient(host, username, password, opts...)
+	if !ok {
+		p.clients[key] = &pooledClient{
+			client:   client,
+			password: password,
+			lastUsed: now,
+		}
+		return client
+	}
+
+	client.onLogin = func(err error) {
+		if err != nil {
+			client.CloseIdleConnections()
+			return
+		}
+		p.replace(key, client, password)
+	}
+
+	return client
+}
+
+// replace caches client, which has just logged in, in place of the session
+// held for key
+func (p *Pool) replace(key string, client *Client, password string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.clients[key]; ok {
+		if entry.client == client {
+			return
+		}
+		entry.client.CloseIdleConnections()
+	}
+	client.onLogin = nil
+	p.clients[key] = &pooledClient{
+		client:   client,
+		password: password,
+		lastUsed: time.Now(),
+	}
+}
+
+// Evict removes the cached session for host and username
+func (p *Pool) Evict(host, username string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := poolKey(host, username)
+	if entry, ok := p.clients[key]; ok {
+		entry.client.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+}
+
+// EvictHost removes every cached session for host
+func (p *Pool) EvictHost(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, entry := range p.clients {
+		if entry.client.Host == host {
+			entry.client.CloseIdleConnections()
+			delete(p.clients, key)
+		}
+	}
+}
+
+// Len returns the number of cached sessions
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.clients)
+}
+
+// Close stops the background eviction and drops all cached sessions
+func (p *Pool) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, entry := range p.clients {
+		entry.client.CloseIdleConnections()
+	}
+	p.clients = make(map[string]*pooledClient)
+}
+
+// janitor periodically evicts idle sessions until the pool is closed
+func (p *Pool) janitor() {
+	interval := p.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			p.evictIdle(now)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// evictIdle removes sessions that have not been used since now - idleTimeout
+func (p *Pool) evictIdle(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, entry := range p.clients {
+		if now.Sub(entry.lastUsed) > p.idleTimeout {
+			entry.client.CloseIdleConnections()
+			delete(p.clients, key)
+		}
+	}
+}
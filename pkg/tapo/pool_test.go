@@ -0,0 +1,121 @@
+package tapo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPool_GetReusesClient(t *testing.T) {
+	pool := NewPool(0)
+	defer pool.Close()
+
+	first := pool.Get("192.168.1.100", "admin", "password")
+	second := pool.Get("192.168.1.100", "admin", "password")
+
+	if first != second {
+		t.Error("Expected the same client for the same host and username")
+	}
+	if pool.Len() != 1 {
+		t.Errorf("Expected 1 pooled session, got %d", pool.Len())
+	}
+}
+
+func TestPool_GetSeparatesCameras(t *testing.T) {
+	pool := NewPool(0)
+	defer pool.Close()
+
+	first := pool.Get("192.168.1.100", "admin", "password")
+	second := pool.Get("192.168.1.101", "admin", "password")
+	third := pool.Get("192.168.1.100", "operator", "password")
+
+	if first == second || first == third {
+		t.Error("Expected distinct clients per host and username")
+	}
+	if pool.Len() != 3 {
+		t.Errorf("Expected 3 pooled sessions, got %d", pool.Len())
+	}
+}
+
+func TestPool_GetReplacesOnPasswordChange(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	pool := NewPool(0, cam.dialOption())
+	defer pool.Close()
+
+	first := pool.Get("camera.test", cam.username, "old")
+	first.stok = "cached_token"
+
+	second := pool.Get("camera.test", cam.username, cam.password)
+	if first == second {
+		t.Error("Expected a new client when the password changes")
+	}
+	if second.IsAuthenticated() {
+		t.Error("Replacement client should not inherit the cached session")
+	}
+	if pool.Get("camera.test", cam.username, "old") != first {
+		t.Error("Expected the cached client to be kept until the new password logs in")
+	}
+
+	if err := second.AuthenticateContext(context.Background()); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if pool.Get("camera.test", cam.username, cam.password) != second {
+		t.Error("Expected the client to be cached once logged in")
+	}
+	if pool.Len() != 1 {
+		t.Errorf("Expected 1 pooled session, got %d", pool.Len())
+	}
+}
+
+func TestPool_GetKeepsSessionOnWrongPassword(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	pool := NewPool(0, cam.dialOption())
+	defer pool.Close()
+
+	first := pool.Get("camera.test", cam.username, cam.password)
+	if err := first.AuthenticateContext(context.Background()); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	wrong := pool.Get("camera.test", cam.username, "wrong")
+	if err := wrong.AuthenticateContext(context.Background()); err == nil {
+		t.Fatal("Expected the wrong password to be rejected")
+	}
+
+	if got := pool.Get("camera.test", cam.username, cam.password); got != first || !got.IsAuthenticated() {
+		t.Error("Expected a wrong password to leave the cached session in place")
+	}
+}
+
+func TestPool_Evict(t *testing.T) {
+	pool := NewPool(0)
+	defer pool.Close()
+
+	first := pool.Get("192.168.1.100", "admin", "password")
+	pool.Evict("192.168.1.100", "admin")
+
+	if pool.Len() != 0 {
+		t.Errorf("Expected empty pool after Evict, got %d", pool.Len())
+	}
+	if pool.Get("192.168.1.100", "admin", "password") == first {
+		t.Error("Expected a new client after Evict")
+	}
+}
+
+func TestPool_evictIdle(t *testing.T) {
+	pool := NewPool(time.Minute)
+	defer pool.Close()
+
+	pool.Get("192.168.1.100", "admin", "password")
+	pool.Get("192.168.1.101", "admin", "password")
+
+	pool.evictIdle(time.Now().Add(30 * time.Second))
+	if pool.Len() != 2 {
+		t.Errorf("Expected sessions to survive before the idle timeout, got %d", pool.Len())
+	}
+
+	pool.evictIdle(time.Now().Add(2 * time.Minute))
+	if pool.Len() != 0 {
+		t.Errorf("Expected idle sessions to be evicted, got %d", pool.Len())
+	}
+}
@@ -0,0 +1,55 @@
+package tapo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// DialFunc opens a network connection, as net.Dialer.DialContext does
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// WithProxy routes connections to the camera through a proxy. Supported
+// schemes are "http" and "https" (HTTP CONNECT) and "socks5" and "socks5h"
+// (host names are resolved by the proxy). Credentials in the URL are sent
+// to the proxy.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *Client) {
+		c.transportOpts.proxy = proxyURL
+	}
+}
+
+// WithDialer sets the function used to open connections, to the proxy when
+// one is configured and to the camera otherwise
+func WithDialer(dial DialFunc) Option {
+	return func(c *Client) {
+		c.transportOpts.dial = dial
+	}
+}
+
+// ParseProxyURL parses and validates a proxy URL for WithProxy
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// The parse error quotes the URL, which may hold credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q in %s", u.Scheme, u.Redacted())
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %s has no host", u.Redacted())
+	}
+
+	return u, nil
+}
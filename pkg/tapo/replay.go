@@ -0,0 +1,231 @@
+package tapo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// CommandTransport exchanges decrypted commands with a camera. The request
+// is the JSON command and the response the camera's JSON answer, including
+// its error_code. The default transport logs in, encrypts and sends the
+// command to the camera.
+type CommandTransport interface {
+	RoundTrip(ctx context.Context, request []byte) ([]byte, error)
+}
+
+// CommandTransportFunc adapts a function to CommandTransport
+type CommandTransportFunc func(ctx context.Context, request []byte) ([]byte, error)
+
+// RoundTrip calls f(ctx, request)
+func (f CommandTransportFunc) RoundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	return f(ctx, request)
+}
+
+// WithCommandTransport wraps the command transport, e.g. to record
+// exchanges with a Recorder or to replay them with a Replayer. Wrappers are
+// applied in order, so the last one sees requests first.
+func WithCommandTransport(wrap func(next CommandTransport) CommandTransport) Option {
+	return func(c *Client) {
+		c.commands = wrap(c.getCommandTransport())
+	}
+}
+
+// getCommandTransport returns the transport commands are sent with
+func (c *Client) getCommandTransport() CommandTransport {
+	if c.commands == nil {
+		return CommandTransportFunc(c.roundTrip)
+	}
+	return c.commands
+}
+
+// Exchange is a decrypted request and the camera's response to it
+type Exchange struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Fixture is a recorded sequence of exchanges with a camera
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// LoadFixture reads a fixture written by Recorder.Save
+func LoadFixture(path string) (Fixture, error) {
+	var f Fixture
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// Recorder captures the exchanges of the clients it wraps. Secrets such as
+// passwords, nonces and session tokens are redacted before recording.
+type Recorder struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Wrap returns a transport recording every successful exchange with next;
+// pass it to WithCommandTransport
+func (r *Recorder) Wrap(next CommandTransport) CommandTransport {
+	return CommandTransportFunc(func(ctx context.Context, request []byte) ([]byte, error) {
+		response, err := next.RoundTrip(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.exchanges = append(r.exchanges, Exchange{
+			Request:  redactJSON(request),
+			Response: redactJSON(response),
+		})
+		r.mu.Unlock()
+
+		return response, nil
+	})
+}
+
+// Fixture returns the exchanges recorded so far
+func (r *Recorder) Fixture() Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Fixture{Exchanges: append([]Exchange{}, r.exchanges...)}
+}
+
+// Save writes the recorded exchanges to path as indented JSON
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Replayer answers requests from a fixture instead of a camera. Requests
+// must arrive in the recorded order and match the recorded requests.
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+}
+
+// NewReplayer creates a replayer serving the exchanges of f
+func NewReplayer(f Fixture) *Replayer {
+	return &Replayer{exchanges: f.Exchanges}
+}
+
+// Wrap returns the replayer itself so no request reaches the camera; pass
+// it to WithCommandTransport
+func (r *Replayer) Wrap(CommandTransport) CommandTransport {
+	return r
+}
+
+// RoundTrip answers request with the next recorded response
+func (r *Replayer) RoundTrip(_ context.Context, request []byte) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.exchanges) {
+		return nil, fmt.Errorf("replay: unexpected request %s after %d recorded exchanges", request, len(r.exchanges))
+	}
+
+	exchange := r.exchanges[r.next]
+	if !jsonEqual(redactJSON(request), exchange.Request) {
+		return nil, fmt.Errorf("replay: request %d is %s, recorded %s", r.next+1, request, exchange.Request)
+	}
+	r.next++
+
+	return exchange.Response, nil
+}
+
+// Remaining returns the number of recorded exchanges not replayed yet
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.exchanges) - r.next
+}
+
+// redactedKeys are protocol secrets never written to fixtures or logs
+var redactedKeys = map[string]bool{
+	"password":       true,
+	"passwd":         true,
+	"digest_passwd":  true,
+	"cnonce":         true,
+	"nonce":          true,
+	"device_confirm": true,
+	"stok":           true,
+	"lsk":            true,
+	"ivb":            true,
+}
+
+// redactedValue replaces the value of a redacted key
+const redactedValue = "REDACTED"
+
+// redactJSON returns data with the values of redactedKeys replaced. Data
+// that is not JSON is returned unchanged.
+func redactJSON(data []byte) json.RawMessage {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return data
+	}
+
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+// redactValue walks a decoded JSON value replacing redacted keys
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redactedKeys[key] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+// jsonEqual reports whether a and b hold the same JSON value
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
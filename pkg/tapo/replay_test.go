@@ -0,0 +1,96 @@
+package tapo
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_Replay(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	rec := NewRecorder()
+	ctx := context.Background()
+
+	client := cam.client(WithCommandTransport(rec.Wrap))
+	if err := client.SetLEDStatus(ctx, false); err != nil {
+		t.Fatalf("SetLEDStatus failed: %v", err)
+	}
+	recorded, err := client.GetLEDStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "led.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if len(fixture.Exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, got %d", len(fixture.Exchanges))
+	}
+
+	// The replaying client has no route to a camera
+	replayer := NewReplayer(fixture)
+	replay := NewClient("unreachable.test", "admin", "password", WithCommandTransport(replayer.Wrap))
+
+	if err := replay.SetLEDStatus(ctx, false); err != nil {
+		t.Fatalf("Replayed SetLEDStatus failed: %v", err)
+	}
+	replayed, err := replay.GetLEDStatus(ctx)
+	if err != nil {
+		t.Fatalf("Replayed GetLEDStatus failed: %v", err)
+	}
+	if *replayed != *recorded {
+		t.Errorf("Expected replayed %+v, got %+v", *recorded, *replayed)
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("Expected all exchanges replayed, %d remaining", replayer.Remaining())
+	}
+	if cam.loginCount() != 1 {
+		t.Errorf("Replay should not log in, got %d logins", cam.loginCount())
+	}
+}
+
+func TestReplayer_RejectsUnexpectedRequest(t *testing.T) {
+	replayer := NewReplayer(Fixture{Exchanges: []Exchange{{
+		Request:  []byte(`{"method":"multipleRequest","params":{"requests":[{"method":"getLedStatus"}]}}`),
+		Response: []byte(`{"error_code":0}`),
+	}}})
+	client := NewClient("unreachable.test", "admin", "password", WithCommandTransport(replayer.Wrap))
+	ctx := context.Background()
+
+	if err := client.SetLEDStatus(ctx, true); err == nil || !strings.Contains(err.Error(), "replay") {
+		t.Errorf("Expected a replay mismatch, got %v", err)
+	}
+	if _, err := client.Execute("getLedStatus", nil); err == nil {
+		t.Error("Expected an error for a response without the method result")
+	}
+	if _, err := client.Execute("getLedStatus", nil); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Errorf("Expected an exhausted replay error, got %v", err)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"nested secrets", `{"params":{"username":"admin","password":"secret","cnonce":"ABC"}}`, `{"params":{"cnonce":"REDACTED","password":"REDACTED","username":"admin"}}`},
+		{"arrays", `{"list":[{"stok":"abc"},{"nonce":"def"}]}`, `{"list":[{"stok":"REDACTED"},{"nonce":"REDACTED"}]}`},
+		{"numbers kept", `{"error_code":-40401,"seq":12345678901}`, `{"error_code":-40401,"seq":12345678901}`},
+		{"not json", `not json`, `not json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactJSON([]byte(tt.input))); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package tapo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/budhilaw/gotapo-api/pkg/crypto"
+)
+
+// defaultHeaders returns the default HTTP headers for Tapo API requests
+func (c *Client) defaultHeaders() map[string]string {
+	headers := map[string]string{
+		"Referer":         fmt.Sprintf("%s://%s", c.endpoint.scheme, c.hostHeader()),
+		"Accept":          "application/json",
+		"Accept-Encoding": "gzip, deflate",
+		"User-Agent":      "Tapo CameraClient Android",
+		"requestByApp":    "true",
+		"Content-Type":    "application/json; charset=UTF-8",
+	}
+
+	if !c.transportOpts.keepAlive {
+		headers["Connection"] = "close"
+	}
+
+	return headers
+}
+
+// newRequest builds a POST request to url carrying the headers of the
+// official app
+func (c *Client) newRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range c.defaultHeaders() {
+		req.Header.Set(key, value)
+	}
+	req.Host = c.hostHeader()
+
+	return req, nil
+}
+
+// makeRawRequest makes a raw HTTP POST request to the camera during the
+// login phase
+func (c *Client) makeRawRequest(ctx context.Context, phase string, payload interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	c.trace(ctx, TraceEvent{Phase: phase, Message: "request", Payload: jsonData})
+	start := time.Now()
+
+	req, err := c.newRequest(ctx, c.getBaseURL()+"/", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.getHTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &TransportError{Op: "request failed", Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Op: "failed to read response", Err: err}
+	}
+
+	c.trace(ctx, TraceEvent{Phase: phase, Message: "response", Payload: body, Elapsed: time.Since(start)})
+
+	return body, nil
+}
+
+// Execute sends a command to the camera
+func (c *Client) Execute(method string, params interface{}) (map[string]interface{}, error) {
+	return c.ExecuteContext(context.Background(), method, params)
+}
+
+// ExecuteContext sends a command to the camera, aborting when ctx is
+// cancelled or its deadline passes
+func (c *Client) ExecuteContext(ctx context.Context, method string, params interface{}) (map[string]interface{}, error) {
+	requests := []SingleRequest{{Method: method, Params: params}}
+
+	raw, err := c.execute(ctx, newMultipleRequest(requests))
+	if err != nil {
+		return nil, err
+	}
+
+	// Surface the error_code reported for the method itself
+	results, err := splitResponses(raw, requests)
+	if err != nil {
+		return nil, err
+	}
+	if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+
+	return decodeMap(raw, nil)
+}
+
+// newMultipleRequest wraps requests in a multipleRequest
+func newMultipleRequest(requests []SingleRequest) MultipleRequest {
+	return MultipleRequest{
+		Method: "multipleRequest",
+		Params: MultipleReqParams{
+			Requests: requests,
+		},
+	}
+}
+
+// ExecuteDirect sends a direct command without multipleRequest wrapper
+func (c *Client) ExecuteDirect(payload interface{}) (map[string]interface{}, error) {
+	return c.ExecuteDirectContext(context.Background(), payload)
+}
+
+// ExecuteDirectContext sends a direct command without multipleRequest
+// wrapper, aborting when ctx is cancelled or its deadline passes
+func (c *Client) ExecuteDirectContext(ctx context.Context, payload interface{}) (map[string]interface{}, error) {
+	return decodeMap(c.execute(ctx, payload))
+}
+
+// execute sends payload over the command transport and decodes the
+// camera's answer, recording any suspension it reports
+func (c *Client) execute(ctx context.Context, payload interface{}) (json.RawMessage, error) {
+	start := time.Now()
+	result, err := c.exchange(ctx, payload)
+	c.observeCall(ctx, payload, result, time.Since(start), err)
+
+	return result, err
+}
+
+// exchange sends payload and parses the response for execute
+func (c *Client) exchange(ctx context.Context, payload interface{}) (json.RawMessage, error) {
+	if err := c.checkSuspended(); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.getCommandTransport().RoundTrip(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseResponse(body)
+	c.recordSuspension(ctx, err)
+
+	return result, err
+}
+
+// parseResponse returns the data of a decrypted response, or a *TapoError
+// for the error_code it reports
+func parseResponse(body []byte) (json.RawMessage, error) {
+	var apiResp rawResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if apiResp.ErrorCode != 0 {
+		if apiResp.ErrorCode == ErrorCodeInvalidToken {
+			return nil, responseError(apiResp.ErrorCode, body, "session expired - please re-authenticate")
+		}
+		return nil, responseError(apiResp.ErrorCode, body, ErrorMessage(apiResp.ErrorCode))
+	}
+
+	return responseData(body, apiResp), nil
+}
+
+// responseCode returns the error_code of a response, or zero if it has none
+func responseCode(body []byte) int {
+	var apiResp rawResponse
+	_ = json.Unmarshal(body, &apiResp)
+	return apiResp.ErrorCode
+}
+
+// roundTrip is the default command transport: it logs in first if needed
+// and sends request to the camera. When the camera reports an expired
+// session the client re-authenticates and replays the request once with a
+// fresh seq and Tapo_tag.
+func (c *Client) roundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	if err := c.ensureAuthenticated(ctx); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	body, err := c.send(ctx, request)
+	if err != nil || responseCode(body) != ErrorCodeInvalidToken {
+		return body, err
+	}
+
+	c.trace(ctx, TraceEvent{Phase: "auth", Message: "session expired, logging in again"})
+	c.log(ctx, slog.LevelInfo, "Camera session expired, logging in again")
+
+	if err := c.ensureAuthenticated(ctx); err != nil {
+		return nil, fmt.Errorf("re-authentication failed: %w", err)
+	}
+
+	return c.send(ctx, request)
+}
+
+// send dispatches request over the negotiated connection type and returns
+// the decrypted response. An expired session is dropped so the next caller
+// logs in again.
+func (c *Client) send(ctx context.Context, request []byte) ([]byte, error) {
+	var body []byte
+	var stok string
+	var err error
+
+	c.mu.Lock()
+	isSecure := c.isSecure
+	c.mu.Unlock()
+
+	if isSecure {
+		body, stok, err = c.executeSecure(ctx, request)
+	} else {
+		body, stok, err = c.executePlain(ctx, request)
+	}
+
+	if err == nil && responseCode(body) == ErrorCodeInvalidToken {
+		c.invalidateSession(stok)
+	}
+
+	return body, err
+}
+
+// invalidateSession drops the session identified by stok unless another
+// caller already replaced it
+func (c *Client) invalidateSession(stok string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stok == stok {
+		c.stok = ""
+	}
+}
+
+// sessionExpiredResponse is answered without contacting the camera when the
+// session was dropped by a concurrent request
+var sessionExpiredResponse = []byte(`{"error_code":-40401}`)
+
+// executePlain sends an unencrypted request and returns the session token
+// it was sent with
+func (c *Client) executePlain(ctx context.Context, request []byte) ([]byte, string, error) {
+	c.mu.Lock()
+	stok := c.stok
+	c.mu.Unlock()
+
+	if stok == "" {
+		return sessionExpiredResponse, stok, nil
+	}
+
+	body, err := c.doPlain(ctx, stok, request)
+	return body, stok, err
+}
+
+// doPlain performs an unencrypted request with the given session token
+func (c *Client) doPlain(ctx context.Context, stok string, request []byte) ([]byte, error) {
+	c.trace(ctx, TraceEvent{Phase: "request", Payload: request})
+	start := time.Now()
+
+	req, err := c.newRequest(ctx, c.getRequestURL(stok), request)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.getHTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &TransportError{Op: "request failed", Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Op: "failed to read response", Err: err}
+	}
+
+	c.trace(ctx, TraceEvent{Phase: "response", Payload: body, Elapsed: time.Since(start)})
+
+	return body, nil
+}
+
+// executeSecure sends an encrypted request and returns the session token it
+// was sent with. Secure requests are serialized: the camera rejects Seq
+// values that arrive out of order, so the lock is held from seq allocation
+// until the response is read.
+func (c *Client) executeSecure(ctx context.Context, request []byte) ([]byte, string, error) {
+	if err := c.seqLock.Lock(ctx); err != nil {
+		return nil, "", err
+	}
+	defer c.seqLock.Unlock()
+
+	sess, ok := c.nextSecureSession()
+	if !ok {
+		return sessionExpiredResponse, sess.stok, nil
+	}
+
+	body, err := c.doSecure(ctx, sess, request)
+	return body, sess.stok, err
+}
+
+// nextSecureSession returns a snapshot of the session carrying the next
+// sequence number; the caller must hold c.seqLock
+func (c *Client) nextSecureSession() (session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sess := c.session
+	if sess.stok == "" {
+		return sess, false
+	}
+
+	c.seq++
+	return sess, true
+}
+
+// doSecure performs an encrypted request using the session snapshot sess
+// and returns the decrypted response. Errors the camera reports outside the
+// encrypted envelope are returned as they are.
+func (c *Client) doSecure(ctx context.Context, sess session, request []byte) ([]byte, error) {
+	c.trace(ctx, TraceEvent{Phase: "request", Seq: sess.seq, Payload: request})
+	start := time.Now()
+
+	// Encrypt the payload
+	encrypted, err := crypto.AESEncrypt(request, sess.lsk, sess.ivb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt request: %w", err)
+	}
+
+	// Create secure request
+	secureReq := SecureRequest{
+		Method: "securePassthrough",
+		Params: SecureParams{
+			Request: base64.StdEncoding.EncodeToString(encrypted),
+		},
+	}
+
+	secureJSON, err := json.Marshal(secureReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secure request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, c.getRequestURL(sess.stok), secureJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add secure headers
+	req.Header.Set("Seq", strconv.Itoa(sess.seq))
+	req.Header.Set("Tapo_tag", sess.calculateTag(string(secureJSON)))
+
+	client := c.getHTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &TransportError{Op: "request failed", Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Op: "failed to read response", Err: err}
+	}
+
+	var secureResp SecureResponse
+	if err := json.Unmarshal(body, &secureResp); err != nil {
+		return nil, fmt.Errorf("failed to parse secure response: %w", err)
+	}
+
+	if secureResp.ErrorCode != 0 {
+		c.trace(ctx, TraceEvent{Phase: "response", Seq: sess.seq, Payload: body, Elapsed: time.Since(start)})
+		return body, nil
+	}
+
+	// Decrypt response
+	encryptedResp, err := base64.StdEncoding.DecodeString(secureResp.Result.Response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	decrypted, err := crypto.AESDecrypt(encryptedResp, sess.lsk, sess.ivb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt response: %w", err)
+	}
+
+	c.trace(ctx, TraceEvent{Phase: "response", Seq: sess.seq, Payload: decrypted, Elapsed: time.Since(start)})
+
+	return decrypted, nil
+}
+
+// responseData returns the data of a successful response: its result, or
+// for direct get requests, which answer without one, the whole body
+func responseData(body []byte, resp rawResponse) json.RawMessage {
+	if len(resp.Result) > 0 {
+		return resp.Result
+	}
+	return body
+}
+
+// calculateTag calculates the Tapo_tag header value
+func (s *session) calculateTag(requestJSON string) string {
+	tag1 := crypto.SHA256Hash(s.hashedPass + s.cnonce)
+	tag := crypto.SHA256Hash(tag1 + requestJSON + strconv.Itoa(s.seq))
+	return tag
+}
@@ -0,0 +1,81 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// responseError builds a TapoError from an error response body, keeping the
+// err_msg and sec_left details the camera reports alongside the code
+func responseError(code int, body []byte, message string) *TapoError {
+	tapoErr := NewTapoError(code, message)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Result == nil {
+		return tapoErr
+	}
+
+	tapoErr.Result = resp.Result
+	tapoErr.ErrMsg, _ = resp.Result["err_msg"].(string)
+
+	// sec_left is reported either directly in result or nested in result.data
+	if secLeft, ok := intField(resp.Result, "sec_left"); ok {
+		tapoErr.SecLeft = secLeft
+	} else if data, ok := resp.Result["data"].(map[string]interface{}); ok {
+		tapoErr.SecLeft, _ = intField(data, "sec_left")
+
+		// A login lockout is answered with the code of the failed login
+		// and the suspension code nested in result.data
+		if nested, _ := intField(data, "code"); nested == ErrorCodeRateLimited && code != ErrorCodeRateLimited {
+			tapoErr.LockedOut = true
+			tapoErr.Message = "Too many failed logins - camera locked out logins"
+		}
+	}
+
+	return tapoErr
+}
+
+// intField reads an integer from a decoded JSON object
+func intField(m map[string]interface{}, key string) (int, bool) {
+	value, ok := m[key].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// checkSuspended fails fast while a camera-reported suspension lasts so we
+// do not extend it by retrying
+func (c *Client) checkSuspended() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remaining := time.Until(c.suspendedUntil)
+	if remaining <= 0 {
+		return nil
+	}
+
+	tapoErr := NewTapoError(ErrorCodeRateLimited, ErrorMessage(ErrorCodeRateLimited))
+	tapoErr.SecLeft = int(math.Ceil(remaining.Seconds()))
+	return tapoErr
+}
+
+// recordSuspension remembers the suspension reported with a -40404 error or
+// a login lockout
+func (c *Client) recordSuspension(ctx context.Context, err error) {
+	var tapoErr *TapoError
+	if !errors.As(err, &tapoErr) || !tapoErr.Suspended() {
+		return
+	}
+
+	c.mu.Lock()
+	c.suspendedUntil = time.Now().Add(tapoErr.RetryAfter())
+	c.mu.Unlock()
+
+	c.log(ctx, slog.LevelWarn, "Camera suspended requests",
+		slog.Int("retry_after_seconds", int(math.Ceil(tapoErr.RetryAfter().Seconds()))))
+}
@@ -0,0 +1,96 @@
+package tapo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TraceEvent is a step of the protocol exchange with a camera
+type TraceEvent struct {
+	Host string
+
+	// Phase is the protocol step: "login.detect", "login.nonce",
+	// "login.digest" or "login.legacy" for the handshakes, "auth" for
+	// session changes, and "request" or "response" for commands
+	Phase string
+
+	// Seq is the Seq header of a secure command, zero otherwise
+	Seq int
+
+	// Message describes a session change
+	Message string
+
+	// Payload is the plaintext JSON exchanged, with secrets redacted
+	Payload json.RawMessage
+
+	// Elapsed is the time a response took
+	Elapsed time.Duration
+
+	Err error
+}
+
+// String formats the event as a single log line
+func (e TraceEvent) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", e.Host, e.Phase)
+	if e.Seq != 0 {
+		fmt.Fprintf(&b, " seq=%d", e.Seq)
+	}
+	if e.Elapsed > 0 {
+		fmt.Fprintf(&b, " elapsed=%s", e.Elapsed.Round(time.Millisecond))
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, " %s", e.Message)
+	}
+	if len(e.Payload) > 0 {
+		fmt.Fprintf(&b, " %s", e.Payload)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, " error=%q", e.Err.Error())
+	}
+	return b.String()
+}
+
+// Tracer receives the protocol trace of a client. Passwords, digests,
+// nonces, session tokens and keys are redacted before a Tracer sees them.
+type Tracer func(TraceEvent)
+
+// WithTracer traces every exchange of the client with the camera
+func WithTracer(t Tracer) Option {
+	return func(c *Client) {
+		c.tracer = t
+	}
+}
+
+// tracerKey is the context key of a per-request Tracer
+type tracerKey struct{}
+
+// ContextWithTracer returns a context tracing the camera exchanges made
+// with it, in addition to any tracer of the client
+func ContextWithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+// trace reports ev to the tracers of ctx and the client, redacting its
+// payload. It does nothing when tracing is off.
+func (c *Client) trace(ctx context.Context, ev TraceEvent) {
+	ctxTracer, _ := ctx.Value(tracerKey{}).(Tracer)
+	if ctxTracer == nil && c.tracer == nil {
+		return
+	}
+
+	ev.Host = c.Host
+	if len(ev.Payload) > 0 {
+		ev.Payload = redactJSON(ev.Payload)
+	}
+
+	if c.tracer != nil {
+		c.tracer(ev)
+	}
+	if ctxTracer != nil {
+		ctxTracer(ev)
+	}
+}
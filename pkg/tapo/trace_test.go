@@ -0,0 +1,127 @@
+package tapo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// traceRecorder collects trace events
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+func (r *traceRecorder) trace(ev TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, ev)
+}
+
+func (r *traceRecorder) phases() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	phases := make([]string, 0, len(r.events))
+	for _, ev := range r.events {
+		phases = append(phases, ev.Phase)
+	}
+	return phases
+}
+
+func TestClient_Trace(t *testing.T) {
+	tests := []struct {
+		name   string
+		secure bool
+		phases string
+	}{
+		{"secure", true, "login.detect login.detect login.nonce login.nonce login.digest login.digest auth request response"},
+		{"legacy", false, "login.detect login.detect login.legacy login.legacy auth request response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cam := newFakeCamera(t, tt.secure)
+			rec := &traceRecorder{}
+			client := cam.client(WithTracer(rec.trace))
+
+			if _, err := client.GetLEDStatus(context.Background()); err != nil {
+				t.Fatalf("GetLEDStatus failed: %v", err)
+			}
+
+			if phases := strings.Join(rec.phases(), " "); phases != tt.phases {
+				t.Errorf("Expected phases %q, got %q", tt.phases, phases)
+			}
+
+			// Secrets never reach the tracer
+			secrets := []string{client.GetSessionToken(), client.cnonce, client.nonce, client.hashedPass}
+			for _, ev := range rec.events {
+				line := ev.String()
+				for _, secret := range secrets {
+					if secret != "" && strings.Contains(line, secret) {
+						t.Errorf("Trace leaks secret %q: %s", secret, line)
+					}
+				}
+			}
+
+			last := rec.events[len(rec.events)-1]
+			if !strings.Contains(string(last.Payload), `"enabled":"on"`) || last.Elapsed <= 0 {
+				t.Errorf("Expected the decrypted response with its timing, got %s", last)
+			}
+			if tt.secure && last.Seq == 0 {
+				t.Errorf("Expected the Seq of the secure request, got %s", last)
+			}
+		})
+	}
+}
+
+func TestClient_TraceContext(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	client := cam.client()
+	ctx := context.Background()
+
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+
+	// Only requests made with the tracing context are traced
+	rec := &traceRecorder{}
+	if _, err := client.GetLEDStatus(ContextWithTracer(ctx, rec.trace)); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+
+	if phases := strings.Join(rec.phases(), " "); phases != "request response" {
+		t.Errorf("Expected one traced exchange, got %q", phases)
+	}
+}
+
+func TestClient_TraceReauthentication(t *testing.T) {
+	cam := newFakeCamera(t, true)
+	rec := &traceRecorder{}
+	client := cam.client(WithTracer(rec.trace))
+	ctx := context.Background()
+
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+	cam.expireSession(1)
+	if _, err := client.GetLEDStatus(ctx); err != nil {
+		t.Fatalf("GetLEDStatus failed: %v", err)
+	}
+
+	var messages []string
+	for _, ev := range rec.events {
+		if ev.Phase == "auth" {
+			messages = append(messages, ev.Message)
+		}
+	}
+	expected := "logged in with secure authentication|session expired, logging in again|logged in with secure authentication"
+	if got := strings.Join(messages, "|"); got != expected {
+		t.Errorf("Expected auth transitions %q, got %q", expected, got)
+	}
+}
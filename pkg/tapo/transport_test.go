@@ -0,0 +1,141 @@
+package tapo
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewClient_Options(t *testing.T) {
+	tuned := false
+	client := NewClient("192.168.1.100", "admin", "password",
+		WithTimeout(3*time.Second),
+		WithKeepAlive(false),
+		WithMaxIdleConns(5),
+		WithIdleConnTimeout(time.Minute),
+		WithTLSSessionCache(0),
+		WithTransportFunc(func(*http.Transport) { tuned = true }),
+	)
+
+	if client.Timeout != 3*time.Second {
+		t.Errorf("Expected timeout 3s, got %v", client.Timeout)
+	}
+
+	transport, ok := client.getHTTPClient().Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.getHTTPClient().Transport)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("Expected keep-alives to be disabled")
+	}
+	if transport.MaxIdleConnsPerHost != 5 {
+		t.Errorf("Expected 5 idle connections per host, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute {
+		t.Errorf("Expected idle timeout 1m, got %v", transport.IdleConnTimeout)
+	}
+	if transport.TLSClientConfig.ClientSessionCache != nil {
+		t.Error("Expected TLS session cache to be disabled")
+	}
+	if !tuned {
+		t.Error("Expected transport func to be applied")
+	}
+	if client.getHTTPClient() != client.getHTTPClient() {
+		t.Error("Expected the HTTP client to be shared across requests")
+	}
+}
+
+func TestClient_defaultHeadersConnection(t *testing.T) {
+	keepAlive := NewClient("192.168.1.100", "admin", "password")
+	if _, ok := keepAlive.defaultHeaders()["Connection"]; ok {
+		t.Error("Keep-alive client should not send Connection: close")
+	}
+
+	closing := NewClient("192.168.1.100", "admin", "password", WithKeepAlive(false))
+	if closing.defaultHeaders()["Connection"] != "close" {
+		t.Error("Client without keep-alive should send Connection: close")
+	}
+}
+
+func TestClient_ReusesConnections(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive bool
+		conns     int
+	}{
+		// Secure login takes 3 requests, followed by 5 commands
+		{"keep-alive", true, 1},
+		{"no keep-alive", false, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			camera := newFakeCamera(t, true)
+			client := camera.client(WithKeepAlive(tt.keepAlive))
+
+			for i := 0; i < 5; i++ {
+				if _, err := client.Execute("getLedStatus", nil); err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+			}
+
+			if camera.connCount() != tt.conns {
+				t.Errorf("Expected %d connections, got %d", tt.conns, camera.connCount())
+			}
+		})
+	}
+}
+
+// perRequestTransport reproduces the former behaviour of building a new
+// transport, and so a new TCP+TLS connection, for every request
+type perRequestTransport struct {
+	camera *fakeCamera
+}
+
+func (p perRequestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		DialContext:     p.camera.dial,
+	}
+	defer transport.CloseIdleConnections()
+
+	return transport.RoundTrip(req)
+}
+
+func BenchmarkClient_Execute(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		client func(*fakeCamera) *Client
+	}{
+		{"per-request transport", func(f *fakeCamera) *Client {
+			client := f.client(WithKeepAlive(false))
+			client.transport = perRequestTransport{camera: f}
+			return client
+		}},
+		{"shared transport without keep-alive", func(f *fakeCamera) *Client {
+			return f.client(WithKeepAlive(false))
+		}},
+		{"shared keep-alive transport", func(f *fakeCamera) *Client {
+			return f.client()
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			camera := newFakeCamera(b, true)
+			client := bm.client(camera)
+
+			if err := client.Authenticate(); err != nil {
+				b.Fatalf("Authenticate failed: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.Execute("getLedStatus", nil); err != nil {
+					b.Fatalf("Execute failed: %v", err)
+				}
+			}
+		})
+	}
+}